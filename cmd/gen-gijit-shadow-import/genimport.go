@@ -4,6 +4,7 @@ genimport: create a map from string (pkg.FuncName) -> function pointer
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,18 +15,25 @@ import (
 
 var defaultPreludePath = "src/github.com/gijit/gi/pkg/compiler/shadow"
 
+// where the shadows written under defaultPreludePath
+// are imported from.
+const shadowImportPrefix = "github.com/gijit/gi/pkg/compiler/shadow"
+
 var defaultPreludePathParts []string
 
 func init() {
 	defaultPreludePathParts = strings.Split(defaultPreludePath, "/")
 }
 
+var bundle = flag.String("bundle", "", "also write a main package to this file, that registers the shadowed packages at startup and runs the gi REPL. Build it to get a gi that can import them.")
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "supply package name to shadow as only argument.\n")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Fprintf(os.Stderr, "supply the package(s) to shadow as arguments.\n")
 		os.Exit(1)
 	}
-	pkg := os.Args[1]
+	pkgs := flag.Args()
 
 	odir := "."
 	dir := os.Getenv("GOINTERP_PRELUDE_DIR")
@@ -48,14 +56,26 @@ func main() {
 		odir = filepath.Join(append([]string{gopath}, defaultPreludePathParts...)...)
 	}
 
-	odir += string(os.PathSeparator) + pkg
-	os.MkdirAll(odir, 0777)
-	fmt.Printf("writing to odir '%s'\n", odir)
 	cwd, err := os.Getwd()
 	panicOn(err)
 
-	err = compiler.GenShadowImport(pkg, cwd, pkg, odir)
-	panicOn(err)
+	for _, pkg := range pkgs {
+		pkgDir := odir + string(os.PathSeparator) + pkg
+		os.MkdirAll(pkgDir, 0777)
+		fmt.Printf("writing to odir '%s'\n", pkgDir)
+
+		err = compiler.GenShadowImport(pkg, cwd, pkg, pkgDir)
+		panicOn(err)
+	}
+
+	if *bundle != "" {
+		f, err := os.Create(*bundle)
+		panicOn(err)
+		err = compiler.GenShadowBundle(f, pkgs, shadowImportPrefix)
+		panicOn(err)
+		panicOn(f.Close())
+		fmt.Printf("wrote bundle main to '%s'\n", *bundle)
+	}
 }
 
 func panicOn(err error) {
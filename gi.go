@@ -0,0 +1,35 @@
+/*
+Package gi embeds the gijit Go interpreter in
+your own program, so that Go can serve as its
+scripting language:
+
+	in, err := gi.NewInterpreter(nil)
+	if err != nil {
+		...
+	}
+	defer in.Close()
+	in.Set("limit", 10)
+	in.Eval(`func fib(n int) int { if n < 2 { return n }; return fib(n-1) + fib(n-2) }`)
+	vals, err := in.Eval(`fib(limit)`) // vals[0].Interface() == 55
+
+The prelude directory is found as the gi command
+finds it; set GOINTERP_PRELUDE_DIR, or the
+PreludePath in the config, to override.
+*/
+package gi
+
+import (
+	"github.com/gijit/gi/pkg/compiler"
+)
+
+// Interpreter compiles and runs Go source in an
+// embedded LuaJIT vm. See compiler.Interpreter.
+type Interpreter = compiler.Interpreter
+
+// Config holds the options for NewInterpreter.
+type Config = compiler.GIConfig
+
+// NewInterpreter starts an Interpreter. cfg may be nil.
+func NewInterpreter(cfg *Config) (*Interpreter, error) {
+	return compiler.NewInterpreter(cfg)
+}
@@ -0,0 +1,80 @@
+// Package lua lets Go code typed at the gijit REPL
+// reach plain Lua values: the globals, and libraries
+// loaded with :do or require. It plays the part that
+// github.com/gopherjs/gopherjs/js plays for GopherJS.
+//
+// The translator compiles calls on *Object directly
+// into Lua table operations, so none of the bodies
+// below ever run. Outside of gijit they do nothing
+// useful; the package only exists to be type checked.
+//
+// Values handed to Lua arrive as the nearest plain
+// Lua value: Go integers become Lua numbers. Other
+// Go values pass as they are.
+//
+//	import "github.com/gijit/gi/lua"
+//
+//	str := lua.Global.Get("string")
+//	s := str.Call("rep", "ab", 3).String() // "ababab"
+package lua
+
+// Object is a Lua value: a table, function, string,
+// number, boolean, userdata, or nil. A Lua nil is a
+// nil *Object.
+type Object struct{ object *Object }
+
+// Get returns o[key].
+func (o *Object) Get(key string) *Object { return o.object.Get(key) }
+
+// Set does o[key] = value.
+func (o *Object) Set(key string, value interface{}) { o.object.Set(key, value) }
+
+// Delete does o[key] = nil.
+func (o *Object) Delete(key string) { o.object.Delete(key) }
+
+// Len returns #o.
+func (o *Object) Len() int { return o.object.Len() }
+
+// Index returns o[i]. Lua sequences start at 1.
+func (o *Object) Index(i int) *Object { return o.object.Index(i) }
+
+// SetIndex does o[i] = value.
+func (o *Object) SetIndex(i int, value interface{}) { o.object.SetIndex(i, value) }
+
+// Call calls the function o[name] with args, as
+// o.name(args...) would in Lua, and returns its
+// first result. For a method, o:name(args...),
+// pass o itself as the first argument.
+func (o *Object) Call(name string, args ...interface{}) *Object {
+	return o.object.Call(name, args...)
+}
+
+// Invoke calls o, which must be a function, and
+// returns its first result.
+func (o *Object) Invoke(args ...interface{}) *Object { return o.object.Invoke(args...) }
+
+// Bool is Lua's truth: false for nil and false,
+// true for everything else, including 0.
+func (o *Object) Bool() bool { return o.object.Bool() }
+
+// String returns tostring(o), or the string itself.
+func (o *Object) String() string { return o.object.String() }
+
+// Int returns o converted to a number, then
+// truncated to an int; 0 if it is not a number.
+func (o *Object) Int() int { return o.object.Int() }
+
+// Int64 is Int for an int64.
+func (o *Object) Int64() int64 { return o.object.Int64() }
+
+// Float returns o converted to a number; 0 if it
+// is not a number.
+func (o *Object) Float() float64 { return o.object.Float() }
+
+// Interface returns o as a Go value: nil, bool,
+// float64 or string, for those Lua types. Tables,
+// functions and the rest come back as they are.
+func (o *Object) Interface() interface{} { return o.object.Interface() }
+
+// Global is Lua's global table, _G.
+var Global *Object
@@ -0,0 +1,231 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	goparser "go/parser"
+	gotoken "go/token"
+	"io"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/parser"
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test660ReplValuesSatisfyNativeGoInterfaces(t *testing.T) {
+
+	cv.Convey(`a struct defined at the REPL, with the right methods, can be passed to native Go code wanting a fmt.Stringer, an io.Reader, a sort.Interface, an error, or an interface{} that fmt will print with String()`, t, func() {
+
+		path := "example.com/adapt/native"
+		tpkg := types.NewPackage(path, "native")
+		str := types.Typ[types.String]
+		nt := types.Typ[types.Int]
+		errt := types.Universe.Lookup("error").Type()
+		v := func(name string, t types.Type) *types.Var { return types.NewVar(token.NoPos, tpkg, name, t) }
+		tuple := func(vars ...*types.Var) *types.Tuple { return types.NewTuple(vars...) }
+		method := func(name string, params, results *types.Tuple) *types.Func {
+			return types.NewFunc(token.NoPos, tpkg, name, types.NewSignature(nil, params, results, false))
+		}
+		iface := func(methods ...*types.Func) *types.Interface {
+			return types.NewInterface(methods, nil).Complete()
+		}
+		fn := func(name string, param types.Type) {
+			sig := types.NewSignature(nil, tuple(v("x", param)), tuple(v("", str)), false)
+			tpkg.Scope().Insert(types.NewFunc(token.NoPos, tpkg, name, sig))
+		}
+		fn("Describe", iface(method("String", nil, tuple(v("", str)))))
+		fn("Slurp", iface(method("Read",
+			tuple(v("p", types.NewSlice(types.Typ[types.Byte]))),
+			tuple(v("n", nt), v("err", errt)))))
+		fn("SortAll", iface(
+			method("Len", nil, tuple(v("", nt))),
+			method("Less", tuple(v("i", nt), v("j", nt)), tuple(v("", types.Typ[types.Bool]))),
+			method("Swap", tuple(v("i", nt), v("j", nt)), nil)))
+		fn("Explain", errt)
+		fn("Sprint", types.NewInterface(nil, nil).Complete())
+		tpkg.MarkComplete()
+
+		RegisterShadowPackage(path, map[string]interface{}{
+			"Describe": func(s fmt.Stringer) string { return "<" + s.String() + ">" },
+			"Slurp": func(r io.Reader) string {
+				var got []byte
+				buf := make([]byte, 3)
+				for {
+					n, err := r.Read(buf)
+					got = append(got, buf[:n]...)
+					if err != nil {
+						return string(got) + " then " + err.Error()
+					}
+				}
+			},
+			"SortAll": func(x sort.Interface) string {
+				sort.Sort(x)
+				return fmt.Sprintf("sorted %v", x.Len())
+			},
+			"Explain": func(e error) string { return "failed: " + e.Error() },
+			"Sprint":  func(x interface{}) string { return fmt.Sprint(x) },
+		}, tpkg)
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		translation := inc.Tr([]byte(`
+import "example.com/adapt/native"
+
+type pt struct { Name string }
+func (p *pt) String() string { return "pt " + p.Name }
+
+type rdr struct {
+	data []byte
+	pos int
+}
+func (r *rdr) Read(p []byte) (n int, err error) {
+	for n < len(p) && r.pos < len(r.data) {
+		p[n] = r.data[r.pos]
+		n++
+		r.pos++
+	}
+	if r.pos == len(r.data) {
+		err = &oops{why: "eof"}
+	}
+	return
+}
+
+type oops struct { why string }
+func (o *oops) Error() string { return "oops: " + o.why }
+
+type byVal struct { s []int }
+func (b *byVal) Len() int { return len(b.s) }
+func (b *byVal) Less(i, j int) bool { return b.s[i] < b.s[j] }
+func (b *byVal) Swap(i, j int) { b.s[i], b.s[j] = b.s[j], b.s[i] }
+
+d := native.Describe(&pt{Name: "a"})
+r := native.Slurp(&rdr{data: []byte{104, 101, 108, 108, 111, 33, 33}})
+bv := &byVal{s: []int{5, 3, 9, 1}}
+so := native.SortAll(bv)
+first := bv.s[0]
+last := bv.s[3]
+e := native.Explain(&oops{why: "disk"})
+s1 := native.Sprint(&pt{Name: "b"})
+s2 := native.Sprint(&oops{why: "net"})
+
+type wrapped struct { *pt }
+d2 := native.Describe(&wrapped{&pt{Name: "c"}})
+`))
+		pp("translation='%s'", string(translation))
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustString(vm, "d", "<pt a>")
+		LuaMustString(vm, "r", "hello!! then oops: eof")
+		LuaMustString(vm, "so", "sorted 4")
+		LuaMustInt64(vm, "first", 1)
+		LuaMustInt64(vm, "last", 9)
+		LuaMustString(vm, "e", "failed: oops: disk")
+		LuaMustString(vm, "s1", "pt b")
+		LuaMustString(vm, "s2", "oops: net")
+
+		// String is promoted from the embedded *pt.
+		LuaMustString(vm, "d2", "<pt c>")
+	})
+}
+
+func Test661GenShadowWritesAdapters(t *testing.T) {
+
+	cv.Convey(`gen-gijit-shadow-import writes, for each exported interface, an adapter that calls the methods of a REPL value, and registers it with luar`, t, func() {
+
+		src := `package shapes
+
+type Shape interface {
+	Area() float64
+	Scale(by ...float64)
+	Name() (string, error)
+}
+
+func Total(s []Shape) float64 { return 0 }
+`
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "shapes.go", src, 0)
+		panicOn(err)
+		pkg, _, err := (&types.Config{Sizes: sizes64}).Check(nil, nil, "example.com/shapes", fset, []*ast.File{f}, nil, nil)
+		panicOn(err)
+
+		var buf bytes.Buffer
+		panicOn(genShadow(&buf, pkg, "example.com/shapes", "shapes"))
+		gen := buf.String()
+		pp("gen='%s'", gen)
+
+		_, err = goparser.ParseFile(gotoken.NewFileSet(), "shapes.genimp.go", gen, 0)
+		cv.So(err, cv.ShouldBeNil)
+		cv.So(gen, cv.ShouldContainSubstring, `luar.RegisterAdapter(reflect.TypeOf((*shapes.Shape)(nil)).Elem(), GijitShadow_NewAdapter_Shape)`)
+		cv.So(gen, cv.ShouldContainSubstring, `func (a *GijitShadow_Adapter_Shape) Area() (r0 float64) {`)
+		cv.So(gen, cv.ShouldContainSubstring, `func (a *GijitShadow_Adapter_Shape) Scale(p0 ...float64) {`)
+		cv.So(gen, cv.ShouldContainSubstring, `a.obj.CallMethod("Name", []interface{}{&r0, &r1})`)
+	})
+}
+
+func Test662AdaptersLetGoOfReplValues(t *testing.T) {
+
+	cv.Convey(`once native Go is done with an adapter for a REPL value, the Lua registry lets go of the value too, so printing a value in a loop does not pin it over and over`, t, func() {
+
+		path := "example.com/adapt/printer"
+		tpkg := types.NewPackage(path, "printer")
+		sig := types.NewSignature(nil,
+			types.NewTuple(types.NewVar(token.NoPos, tpkg, "x", types.NewInterface(nil, nil).Complete())),
+			types.NewTuple(types.NewVar(token.NoPos, tpkg, "", types.Typ[types.String])), false)
+		tpkg.Scope().Insert(types.NewFunc(token.NoPos, tpkg, "Sprint", sig))
+		tpkg.MarkComplete()
+		RegisterShadowPackage(path, map[string]interface{}{
+			"Sprint": func(x interface{}) string { return fmt.Sprint(x) },
+		}, tpkg)
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		LuaRunAndReport(vm, string(inc.Tr([]byte(`
+import "example.com/adapt/printer"
+
+type pt struct { Name string }
+func (p *pt) String() string { return "pt " + p.Name }
+
+p := &pt{Name: "a"}
+s := ""
+for i := 0; i < 200; i++ {
+	s = printer.Sprint(p)
+}
+`))))
+		LuaMustString(vm, "s", "pt a")
+
+		pinned := func() int {
+			panicOn(vm.DoString(`
+local n = 0
+for _, v in pairs(debug.getregistry()) do
+   if rawequal(v, p) then
+      n = n + 1
+   end
+end
+return n`))
+			defer vm.Pop(1)
+			return vm.ToInteger(-1)
+		}
+		cv.So(pinned(), cv.ShouldBeGreaterThan, 0)
+
+		// the finalizers run on a goroutine of their own;
+		// the next adapter frees what they gave up.
+		for i := 0; i < 10 && pinned() > 1; i++ {
+			runtime.GC()
+			time.Sleep(10 * time.Millisecond)
+			LuaRunAndReport(vm, string(inc.Tr([]byte(`s = printer.Sprint(p)`))))
+		}
+		cv.So(pinned(), cv.ShouldBeLessThanOrEqualTo, 1)
+	})
+}
@@ -3,6 +3,7 @@ package analysis
 import (
 	"github.com/gijit/gi/pkg/ast"
 	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
 )
 
 func HasBreak(n ast.Node) bool {
@@ -30,3 +31,43 @@ func (v *hasBreakVisitor) Visit(node ast.Node) (w ast.Visitor) {
 	}
 	return v
 }
+
+// HasContinue reports whether body holds a continue
+// of the loop it belongs to: an unlabeled continue
+// outside any nested loop, or a continue naming label.
+func HasContinue(body ast.Node, label *types.Label, info *types.Info) bool {
+	found := false
+	ast.Walk(&hasContinueVisitor{label: label, info: info, found: &found}, body)
+	return found
+}
+
+type hasContinueVisitor struct {
+	label  *types.Label
+	info   *types.Info
+	nested bool
+	found  *bool
+}
+
+func (v *hasContinueVisitor) Visit(node ast.Node) (w ast.Visitor) {
+	if *v.found {
+		return nil
+	}
+	switch n := node.(type) {
+	case *ast.BranchStmt:
+		if n.Tok == token.CONTINUE {
+			if n.Label == nil {
+				*v.found = !v.nested
+			} else if v.label != nil {
+				*v.found = v.info.Uses[n.Label] == v.label
+			}
+		}
+		return nil
+	case *ast.ForStmt, *ast.RangeStmt:
+		if !v.nested {
+			return &hasContinueVisitor{label: v.label, info: v.info, nested: true, found: v.found}
+		}
+	case *ast.FuncLit:
+		return nil
+	}
+	return v
+}
@@ -120,9 +120,8 @@ func (c *FuncInfo) Visit(node ast.Node) ast.Visitor {
 	case *ast.BranchStmt:
 		switch n.Tok {
 		case token.GOTO:
-			for _, n2 := range c.analyzeStack {
-				c.Flattened[n2] = true
-			}
+			// jea: LuaJIT has goto, so unlike GopherJS we
+			// need not flatten the enclosing statements.
 			c.GotoLabel[c.p.Uses[n.Label].(*types.Label)] = true
 		case token.CONTINUE:
 			if n.Label != nil {
@@ -197,28 +196,18 @@ func (c *FuncInfo) Visit(node ast.Node) ast.Visitor {
 				c.markBlocking(c.analyzeStack)
 			}
 		}
-	case *ast.SendStmt:
-		c.markBlocking(c.analyzeStack)
+	// jea: channel sends, receives, range over a channel and
+	// select can all block, but our goroutines are LuaJIT
+	// coroutines (see goroutines.lua) that simply yield, so
+	// unlike GopherJS we never need to flatten their
+	// enclosing functions into resumable state machines.
 	case *ast.UnaryExpr:
 		switch n.Op {
 		case token.AND:
 			if id, ok := astutil.RemoveParens(n.X).(*ast.Ident); ok {
 				c.p.HasPointer[c.p.Uses[id].(*types.Var)] = true
 			}
-		case token.ARROW:
-			c.markBlocking(c.analyzeStack)
-		}
-	case *ast.RangeStmt:
-		if _, ok := c.p.TypeOf(n.X).Underlying().(*types.Chan); ok {
-			c.markBlocking(c.analyzeStack)
-		}
-	case *ast.SelectStmt:
-		for _, s := range n.Body.List {
-			if s.(*ast.CommClause).Comm == nil { // default clause
-				return c
-			}
 		}
-		c.markBlocking(c.analyzeStack)
 	case *ast.CommClause:
 		switch comm := n.Comm.(type) {
 		case *ast.SendStmt:
@@ -248,9 +237,11 @@ func (c *FuncInfo) Visit(node ast.Node) ast.Visitor {
 	return c
 }
 
+// jea: Blocking still makes calls keep their order
+// of evaluation, but as goroutines are coroutines, we
+// never mark anything as Flattened.
 func (c *FuncInfo) markBlocking(stack []ast.Node) {
 	for _, n := range stack {
 		c.Blocking[n] = true
-		c.Flattened[n] = true
 	}
 }
@@ -11,6 +11,15 @@ _giPrivateArrayMt = {
       local len = props.len
       --print("newindex called for key", k, " len at start is ", len)
       local raw = rawget(t, _giPrivateRaw)
+      if props.goaddr ~= nil then
+         -- elements in Go memory; see slice.lua.
+         k = tonumber(k)
+         if k < 0 or k >= len then
+            error("out of bounds access " .. tostring(k))
+         end
+         raw[k] = v
+         return
+      end
       if raw[k] == nil then
          if  v ~= nil then
             -- new value
@@ -37,6 +46,13 @@ _giPrivateArrayMt = {
       -- __pairs works. It would be a problem for hash
       -- tables that want to store the key 'raw'.
       -- if k == 'raw' then return t[_giPrivateRaw] end
+      local props = rawget(t, _giPrivateArrayProps)
+      if props.goaddr ~= nil then
+         k = tonumber(k)
+         if k < 0 or k >= props.len then
+            error("out of bounds access " .. tostring(k))
+         end
+      end
       return rawget(t, _giPrivateRaw)[k]
    end,
 
@@ -54,6 +70,12 @@ _giPrivateArrayMt = {
          quo = '"'
       end
       
+      if props.goaddr ~= nil then
+         for i = 0, len-1 do
+            s = s .. "["..tostring(i).."]" .. "= " .. quo..tostring(raw[i])..quo .. ", "
+         end
+         return s .. "}"
+      end
       for i, _ in pairs(raw) do
          s = s .. "["..tostring(i).."]" .. "= " .. quo..tostring(raw[i])..quo .. ", "
       end
@@ -81,6 +103,19 @@ _giPrivateArrayMt = {
          if v then return k,v end
       end
 
+      local props = rawget(t, _giPrivateArrayProps)
+      if props.goaddr ~= nil then
+         -- elements in Go memory go in order, from 0.
+         local raw = rawget(t, _giPrivateRaw)
+         local len = props.len
+         return function(t, k)
+            k = k + 1
+            if k < len then
+               return k, raw[k]
+            end
+         end, t, -1
+      end
+
       -- Return an iterator function, the table, starting point
       return stateless_iter, t, nil
    end,
@@ -104,18 +139,31 @@ function __gi_NewArray(x, typeKind, len, zeroVal)
    end
 
    local proxy = {}
-   proxy[_giPrivateRaw] = x
+   local props = {len=len, typeKind=typeKind}
 
-   -- zero any tail that is not set
-   if zeroVal ~= nil then
+   local kind = __gi_goBackedKind(typeKind)
+   if kind ~= nil then
+      -- numeric elements live in Go memory, which starts
+      -- out zeroed; see slice.lua.
+      local raw, goaddr = __gi_goAlloc(kind, len)
+      for i = 0, len-1 do
+         local v = x[i]
+         if v ~= nil then
+            raw[i] = v
+         end
+      end
+      x = raw
+      props.goaddr = goaddr
+   elseif zeroVal ~= nil then
+      -- zero any tail that is not set
       for i =0,len-1 do
          if x[i] == nil then
             x[i] = zeroVal
          end
       end
    end
+   proxy[_giPrivateRaw] = x
    
-   local props = {len=len, typeKind=typeKind}
    proxy[_giPrivateArrayProps] = props
 
    --print("upon init, len is ",proxy[_giPrivateArrayProps]["len"])
@@ -192,6 +240,15 @@ function _gi_UnpackArrayRaw(t)
       return unpack(t) 
    end
 
+   local props = rawget(t, _giPrivateArrayProps)
+   if props.goaddr ~= nil then
+      local vals = {}
+      for i = 0, props.len-1 do
+         vals[i+1] = raw[i]
+      end
+      return unpack(vals, 1, props.len)
+   end
+
    if #raw == 0 then
       return nil
    end
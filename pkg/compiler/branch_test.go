@@ -0,0 +1,201 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test590LabeledBreakAndContinue(t *testing.T) {
+
+	cv.Convey(`labeled break and continue leave nested loops, and plain continue runs the loop's post statement, all by LuaJIT goto`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+func pairsBelow(n int) int {
+	s := 0
+outer:
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if j > i {
+				continue outer
+			}
+			if i*j > 6 {
+				break outer
+			}
+			if j == 1 {
+				continue
+			}
+			k := i + j
+			s += k
+		}
+	}
+	return s
+}
+`)
+		panicOn(err)
+
+		vals, err := in.Eval(`pairsBelow(5)`)
+		panicOn(err)
+		// (0,0) (1,0) (2,0) (2,2) (3,0) (3,2), then 3*3 > 6.
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 15)
+	})
+}
+
+func Test591BreakLeavesSwitchAndSelectOnly(t *testing.T) {
+
+	cv.Convey(`break in a switch or select leaves just that statement, continue inside them continues the enclosing loop, and fallthrough runs the next case`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+n := 0
+ch := make(chan int, 1)
+ch <- 5
+for i := 0; i < 6; i++ {
+	switch {
+	case i == 1:
+		continue
+	case i == 2:
+		n += 100
+		break
+	case i == 3:
+		n += 1000
+		fallthrough
+	case i == 4:
+		n += 10000
+	}
+	select {
+	case v := <-ch:
+		if v == 5 {
+			break
+		}
+		n += 7
+	default:
+	}
+	n++
+}
+`)
+		panicOn(err)
+
+		vals, err := in.Eval(`n`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 21105)
+	})
+}
+
+func Test592GotoAndRangeContinue(t *testing.T) {
+
+	cv.Convey(`goto jumps back and forward to labels, and continue works in range loops over slices, maps and strings`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+func collatz(n int) int {
+	steps := 0
+again:
+	if n == 1 {
+		goto done
+	}
+	if n%2 == 0 {
+		n /= 2
+	} else {
+		n = 3*n + 1
+	}
+	steps++
+	goto again
+done:
+	return steps
+}
+skipped := 0
+for _, x := range []int{1, 2, 3, 4} {
+	if x%2 == 0 {
+		continue
+	}
+	skipped += x
+}
+for k := range map[string]int{"a": 1, "b": 2} {
+	if k == "a" {
+		continue
+	}
+	skipped += 10
+}
+for i := range "hey" {
+	if i == 0 {
+		continue
+	}
+	skipped += 100
+}
+`)
+		panicOn(err)
+
+		vals, err := in.Eval(`collatz(27)`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 111)
+
+		vals, err = in.Eval(`skipped`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 214)
+	})
+}
+
+func Test593LabelsAtTopLevel(t *testing.T) {
+
+	cv.Convey(`labels, labeled break and continue, and goto work in statements typed at the top level, not just in funcs`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`n := 0`)
+		panicOn(err)
+		_, err = in.Eval(`loop: for i := 0; i < 3; i++ { if i == 1 { break loop }; n++ }`)
+		panicOn(err)
+
+		_, err = in.Eval(`k := 0`)
+		panicOn(err)
+		_, err = in.Eval(`L: for k < 3 { k++; if k == 2 { break L } }`)
+		panicOn(err)
+
+		_, err = in.Eval(`
+m := 0
+outer:
+for a := 0; a < 3; a++ {
+	for b := 0; b < 3; b++ {
+		if b == 1 {
+			continue outer
+		}
+		if a == 2 {
+			break outer
+		}
+		m++
+	}
+}
+j := 0
+again:
+j++
+if j < 5 {
+	goto again
+}
+goto done
+j = 100
+done:
+k++
+`)
+		panicOn(err)
+
+		for name, want := range map[string]int{"n": 1, "k": 3, "m": 2, "j": 5} {
+			vals, err := in.Eval(name)
+			panicOn(err)
+			cv.So(vals[0].Interface(), cv.ShouldEqual, want)
+		}
+
+		// as in a func, a label must be used.
+		_, err = in.Eval(`U: for k < 3 { k++ }`)
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldContainSubstring, "label U declared but not used")
+	})
+}
@@ -0,0 +1,201 @@
+package compiler
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/parser"
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+	cv "github.com/glycerine/goconvey/convey"
+	"github.com/glycerine/luar"
+)
+
+// cbProblem is the native side of cb.Problem, shaped
+// like gonum's optimize.Problem.
+type cbProblem struct {
+	Func func(x []float64) float64
+	Grad func(grad, x []float64)
+}
+
+func Test670ReplFuncsPassAsTypedGoFuncs(t *testing.T) {
+
+	cv.Convey(`a func literal from the REPL can be handed to native Go wanting a func type, directly or in a struct field, with its arguments and results converted between int64 cdata, floats, and Go's sized types`, t, func() {
+
+		path := "example.com/callbacks/cb"
+		src := `package cb
+
+type Problem struct {
+	Func func(x []float64) float64
+	Grad func(grad, x []float64)
+}
+
+func Apply(f func(int) int, x int) int { return 0 }
+func SortedIndex(n int, less func(i, j int) bool) []int { return nil }
+func Map(mapping func(rune) rune, s string) string { return "" }
+func Midpoint(f func(float64) float64, a, b float64, n int) float64 { return 0 }
+func Byte(f func() byte) int { return 0 }
+func Divmod(f func(a, b int) (int, int), a, b int) int { return 0 }
+func Evaluate(p Problem, x []float64) (float64, float64, float64) { return 0, 0, 0 }
+func HasGrad(p Problem) bool { return false }
+`
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "cb.go", src, 0)
+		panicOn(err)
+		tpkg, _, err := (&types.Config{Sizes: sizes64}).Check(nil, nil, path, fset, []*ast.File{f}, nil, nil)
+		panicOn(err)
+
+		RegisterShadowPackage(path, map[string]interface{}{
+			"Apply": func(f func(int) int, x int) int { return f(x) + 1 },
+			"SortedIndex": func(n int, less func(i, j int) bool) []int {
+				idx := make([]int, n)
+				for i := range idx {
+					idx[i] = i
+				}
+				sort.Slice(idx, func(i, j int) bool { return less(idx[i], idx[j]) })
+				return idx
+			},
+			"Map": strings.Map,
+			"Midpoint": func(f func(float64) float64, a, b float64, n int) float64 {
+				h := (b - a) / float64(n)
+				sum := 0.0
+				for i := 0; i < n; i++ {
+					sum += f(a + h*(float64(i)+0.5))
+				}
+				return sum * h
+			},
+			"Byte":   func(f func() byte) int { return int(f()) },
+			"Divmod": func(f func(a, b int) (int, int), a, b int) int { q, r := f(a, b); return q*100 + r },
+			"Evaluate": func(p cbProblem, x []float64) (float64, float64, float64) {
+				grad := make([]float64, len(x))
+				p.Grad(grad, x)
+				return p.Func(x), grad[0], grad[1]
+			},
+			"HasGrad": func(p cbProblem) bool { return p.Grad != nil },
+			"Problem": func() *cbProblem { return &cbProblem{} },
+		}, tpkg)
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		translation := inc.Tr([]byte(`
+import "example.com/callbacks/cb"
+
+a := cb.Apply(func(i int) int { return i * 10 }, 4)
+
+ages := []int{40, 7, 22}
+idx := cb.SortedIndex(len(ages), func(i, j int) bool { return ages[i] < ages[j] })
+youngest := idx[0]
+oldest := idx[2]
+
+rot := cb.Map(func(r rune) rune { return r + 1 }, "HAL")
+area := cb.Midpoint(func(x float64) float64 { return 2 * x }, 0, 3, 10)
+b := cb.Byte(func() byte { return 250 })
+dm := cb.Divmod(func(a, b int) (int, int) { return a / b, a % b }, 17, 5)
+
+p := cb.Problem{
+	Func: func(x []float64) float64 { return x[0]*x[0] + 3*x[1] },
+	Grad: func(grad, x []float64) {
+		grad[0] = 2 * x[0]
+		grad[1] = 3
+	},
+}
+fx, g0, g1 := cb.Evaluate(p, []float64{2, 1})
+withGrad := cb.HasGrad(p)
+noGrad := cb.HasGrad(cb.Problem{Func: p.Func})
+`))
+		pp("translation='%s'", string(translation))
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustInt64(vm, "a", 41)
+		LuaMustInt64(vm, "youngest", 1)
+		LuaMustInt64(vm, "oldest", 0)
+		LuaMustString(vm, "rot", "IBM")
+		LuaMustFloat64(vm, "area", 9)
+		LuaMustInt64(vm, "b", 250)
+		LuaMustInt64(vm, "dm", 302)
+		LuaMustFloat64(vm, "fx", 7)
+		LuaMustFloat64(vm, "g0", 4)
+		LuaMustFloat64(vm, "g1", 3)
+		LuaMustBool(vm, "withGrad", true)
+		LuaMustBool(vm, "noGrad", false)
+	})
+}
+
+func Test671ReplFuncsStayOnTheirGoroutine(t *testing.T) {
+
+	cv.Convey(`a REPL func handed to native Go panics if called from another goroutine, rather than run Lua concurrently; and once Go is done with it, the Lua registry lets go of it`, t, func() {
+
+		path := "example.com/callbacks/later"
+		src := `package later
+
+func Apply(f func(int) int, x int) int { return 0 }
+func Elsewhere(f func(int) int) string { return "" }
+`
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "later.go", src, 0)
+		panicOn(err)
+		tpkg, _, err := (&types.Config{Sizes: sizes64}).Check(nil, nil, path, fset, []*ast.File{f}, nil, nil)
+		panicOn(err)
+
+		RegisterShadowPackage(path, map[string]interface{}{
+			"Apply": func(f func(int) int, x int) int { return f(x) },
+			"Elsewhere": func(f func(int) int) string {
+				done := make(chan string)
+				go func() {
+					defer func() {
+						done <- fmt.Sprint(recover())
+					}()
+					f(1)
+				}()
+				return <-done
+			},
+		}, tpkg)
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		LuaRunAndReport(vm, string(inc.Tr([]byte(`
+import "example.com/callbacks/later"
+
+calls := 0
+f := func(i int) int { calls++; return i }
+sum := 0
+for i := 0; i < 200; i++ {
+	sum += later.Apply(f, i)
+}
+msg := later.Elsewhere(f)
+`))))
+		LuaMustInt64(vm, "sum", 19900)
+		LuaMustInt64(vm, "calls", 200)
+		LuaMustString(vm, "msg", luar.ErrOtherGoroutine.Error())
+
+		pinned := func() int {
+			panicOn(vm.DoString(`
+local n = 0
+for _, v in pairs(debug.getregistry()) do
+   if rawequal(v, f) then
+      n = n + 1
+   end
+end
+return n`))
+			defer vm.Pop(1)
+			return vm.ToInteger(-1)
+		}
+		for i := 0; i < 10 && pinned() > 1; i++ {
+			runtime.GC()
+			time.Sleep(10 * time.Millisecond)
+			LuaRunAndReport(vm, string(inc.Tr([]byte(`sum = later.Apply(f, 1)`))))
+		}
+		cv.So(pinned(), cv.ShouldBeLessThanOrEqualTo, 1)
+	})
+}
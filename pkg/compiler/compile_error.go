@@ -0,0 +1,138 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/gijit/gi/pkg/scanner"
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+)
+
+// A CompileError reports the syntax and type errors
+// that stopped a snippet of Go from being translated.
+// Line and column numbers in Errors count from the
+// start of Src, the snippet as entered, rather than
+// from the start of the session; or, after a //line
+// comment such as :source adds, from the start of
+// the file named there.
+type CompileError struct {
+	Src    []byte
+	Errors []types.Error
+
+	// line 1 of the translated source may be longer
+	// than line 1 of Src; see prependAns.
+	colShift int
+}
+
+// Error lists each error as line:column: message,
+// one per line.
+func (e *CompileError) Error() string {
+	var msgs []string
+	for i, te := range e.Errors {
+		msgs = append(msgs, e.message(i, te))
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e *CompileError) message(i int, te types.Error) string {
+	pos := e.Position(i)
+	switch {
+	case !pos.IsValid():
+		return te.Msg
+	case pos.Filename != "":
+		return fmt.Sprintf("%s:%d:%d: %s", pos.Filename, pos.Line, pos.Column, te.Msg)
+	}
+	return fmt.Sprintf("%d:%d: %s", pos.Line, pos.Column, te.Msg)
+}
+
+// Position gives the location of the i-th error,
+// relative to Src. It is not valid when the error
+// has no position, as for a failed import.
+func (e *CompileError) Position(i int) (pos token.Position) {
+	pos, _ = e.positions(i)
+	return
+}
+
+// positions gives the reported position of the i-th
+// error, and the one within Src.
+func (e *CompileError) positions(i int) (pos, raw token.Position) {
+	te := e.Errors[i]
+	if te.Fset == nil || !te.Pos.IsValid() {
+		return
+	}
+	pos = te.Fset.Position(te.Pos)
+	raw = te.Fset.PositionFor(te.Pos, false)
+	if raw.Line == 1 {
+		pos.Column -= e.colShift
+		raw.Column -= e.colShift
+		if pos.Column < 1 {
+			pos.Column, raw.Column = 1, 1
+		}
+	}
+	return
+}
+
+// Caret shows each error beneath the line of Src it
+// refers to, with a caret under the offending column:
+//
+//	1:10: cannot convert 1 (untyped int constant) to string
+//	    b := "x" + 1
+//	             ^
+func (e *CompileError) Caret() string {
+	lines := strings.Split(string(e.Src), "\n")
+	var buf bytes.Buffer
+	for i, te := range e.Errors {
+		fmt.Fprintf(&buf, "%s\n", e.message(i, te))
+		_, raw := e.positions(i)
+		if !raw.IsValid() || raw.Line > len(lines) {
+			continue
+		}
+		line := lines[raw.Line-1]
+		fmt.Fprintf(&buf, "    %s\n    ", line)
+		// keep tabs, so the caret lines up.
+		for j := 0; j < raw.Column-1 && j < len(line); j++ {
+			if line[j] == '\t' {
+				buf.WriteByte('\t')
+			} else {
+				buf.WriteByte(' ')
+			}
+		}
+		buf.WriteString("^\n")
+	}
+	return buf.String()
+}
+
+// newCompileError gathers the errors in err, which
+// came from parsing or type checking src in fset.
+func newCompileError(src []byte, fset *token.FileSet, err error) *CompileError {
+	ce := &CompileError{Src: src}
+	var add func(err error)
+	add = func(err error) {
+		switch x := err.(type) {
+		case types.Error:
+			ce.Errors = append(ce.Errors, x)
+		case ErrorList:
+			for _, e := range x {
+				add(e)
+			}
+		case scanner.ErrorList:
+			for _, e := range x {
+				add(e)
+			}
+		case *scanner.Error:
+			// the parser reports a token.Position; find
+			// the file it belongs to, the most recent one.
+			te := types.Error{Fset: fset, Msg: x.Msg}
+			if f := fset.File(token.Pos(fset.Base() - 1)); f != nil && x.Pos.Offset <= f.Size() {
+				te.Pos = f.Pos(x.Pos.Offset)
+			}
+			ce.Errors = append(ce.Errors, te)
+		default:
+			ce.Errors = append(ce.Errors, types.Error{Msg: err.Error()})
+		}
+	}
+	add(err)
+	return ce
+}
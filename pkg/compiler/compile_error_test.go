@@ -0,0 +1,121 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test540TypeErrorsAreCompileErrors(t *testing.T) {
+
+	cv.Convey(`a type error comes back as a *CompileError, positioned relative to the snippet rather than to the session`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		_, err = translateAndCatchPanic(inc, []byte("a := 1\nb := 2\nc := 3\n"))
+		panicOn(err)
+
+		src := "d := 4\n\tb := \"x\" + 1\n"
+		_, err = translateAndCatchPanic(inc, []byte(src))
+		cv.So(err, cv.ShouldNotBeNil)
+		ce, ok := err.(*CompileError)
+		cv.So(ok, cv.ShouldBeTrue)
+		cv.So(len(ce.Errors), cv.ShouldEqual, 1)
+		pos := ce.Position(0)
+		cv.So(pos.Line, cv.ShouldEqual, 2)
+		cv.So(pos.Column, cv.ShouldEqual, 7)
+		cv.So(ce.Error(), cv.ShouldStartWith, "2:7: ")
+		cv.So(ce.Caret(), cv.ShouldEndWith, "\n    \tb := \"x\" + 1\n    \t     ^\n")
+
+		// the type checker is still usable afterwards.
+		_, err = translateAndCatchPanic(inc, []byte("e := a + c"))
+		panicOn(err)
+	})
+}
+
+func Test541SyntaxErrorsAndCalculatorLinesAreCompileErrors(t *testing.T) {
+
+	cv.Convey(`syntax errors are *CompileErrors too, and the columns of a '=' calculator line refer to what was typed`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		_, err = translateAndCatchPanic(inc, []byte("x := (1 + \n"))
+		ce, ok := err.(*CompileError)
+		cv.So(ok, cv.ShouldBeTrue)
+		cv.So(ce.Position(0).Line, cv.ShouldBeGreaterThanOrEqualTo, 1)
+
+		_, err = translateAndCatchPanic(inc, []byte(`= 1 + "s"`))
+		ce, ok = err.(*CompileError)
+		cv.So(ok, cv.ShouldBeTrue)
+		cv.So(ce.Position(0).Line, cv.ShouldEqual, 1)
+		cv.So(ce.Position(0).Column, cv.ShouldEqual, 3)
+	})
+}
+
+func Test542CompileErrorListsEveryTypeError(t *testing.T) {
+
+	cv.Convey(`every type error in a snippet is reported, and none of the snippet's declarations stay behind`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		_, err = translateAndCatchPanic(inc, []byte("a := 1\n"))
+		panicOn(err)
+
+		src := "z := 2\nb := a + \"x\"\nc := undefinedThing\na = \"s\"\n"
+		_, err = translateAndCatchPanic(inc, []byte(src))
+		ce, ok := err.(*CompileError)
+		cv.So(ok, cv.ShouldBeTrue)
+		cv.So(len(ce.Errors), cv.ShouldEqual, 3)
+		cv.So(ce.Position(0).Line, cv.ShouldEqual, 2)
+		cv.So(ce.Position(1).Line, cv.ShouldEqual, 3)
+		cv.So(ce.Position(2).Line, cv.ShouldEqual, 4)
+
+		_, err = translateAndCatchPanic(inc, []byte("w := z"))
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldContainSubstring, "undeclared name: z")
+
+		_, err = translateAndCatchPanic(inc, []byte("a2 := a + 1"))
+		panicOn(err)
+	})
+}
+
+func Test543FailedChunkTakesBackItsMethods(t *testing.T) {
+
+	cv.Convey(`a method declared in a snippet with a type error is taken back off its type, so the type does not satisfy interfaces with it`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) error {
+			translation, err := translateAndCatchPanic(inc, []byte(src))
+			if err == nil {
+				LuaRunAndReport(vm, string(translation))
+			}
+			return err
+		}
+		panicOn(run(`type P struct{}`))
+
+		err = run(`func (P) M() int { return 1 }; var zz int = "x"`)
+		cv.So(err, cv.ShouldNotBeNil)
+
+		err = run(`var i interface{ M() int } = P{}`)
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldContainSubstring, "missing method M")
+
+		// declared again, in a snippet that checks, M is kept.
+		panicOn(run(`func (P) M() int { return 1 }`))
+		panicOn(run(`var i interface{ M() int } = P{}; n := i.M()`))
+		LuaMustInt64(vm, "n", 1)
+	})
+}
@@ -20,6 +20,7 @@ import (
 var sizes64 = &types.StdSizes{WordSize: 8, MaxAlign: 8}
 var reservedKeywords = make(map[string]bool)
 var predeclared = make(map[string]bool)
+var luaKeywords = make(map[string]bool)
 
 func init() {
 	// javascript reserved words
@@ -36,6 +37,7 @@ func init() {
 	// lua reserved words
 	for _, w := range []string{"and", "break", "do", "else", "elseif", "", "end", "false", "for", "function", "if", "in", "local", "nil", "not", "or", "repeat", "return", "then", "true", "until", "while"} {
 		reservedKeywords[w] = true
+		luaKeywords[w] = true
 	}
 }
 
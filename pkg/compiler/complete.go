@@ -0,0 +1,298 @@
+package compiler
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/scanner"
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+)
+
+// tab completion at the gi> prompt.
+
+// replCommands are the colon commands that tab
+// completes; see the :help text in repl_luajit.go.
+var replCommands = []string{
+	":?", ":ast", ":bench", ":clear", ":do", ":export", ":g", ":go",
+	":h", ":help", ":info", ":ls", ":noast", ":package",
+	":prelude", ":print", ":profile", ":q", ":r", ":raw", ":reload", ":reset",
+	":rm", ":source", ":timeout", ":type", ":v", ":vv",
+}
+
+// Complete gives the tab completions for the word
+// before the cursor at pos in line, in the form that
+// liner.WordCompleter wants: each completion replaces
+// the word, between head and tail.
+//
+// pending is the input already entered on earlier
+// lines of an incomplete, multi-line, entry. The
+// identifiers it declares have yet to reach the type
+// checker, so they are offered by name alone.
+//
+// After `pkg.`, we offer the exported members of the
+// imported package; after `x.`, the fields and
+// methods of the type of x; otherwise the identifiers
+// in scope, and the Go keywords.
+func (tr *IncrState) Complete(pending, line string, pos int) (head string, completions []string, tail string) {
+	rs := []rune(line)
+	if pos < 0 || pos > len(rs) {
+		pos = len(rs)
+	}
+	before := string(rs[:pos])
+	tail = string(rs[pos:])
+
+	if pending == "" && strings.HasPrefix(before, ":") {
+		sp := strings.IndexByte(before, ' ')
+		if sp < 0 {
+			return "", matchPrefix(replCommands, before), tail
+		}
+		switch strings.TrimSpace(before[:sp]) {
+		case ":type", ":info":
+			// these take Go; complete it below.
+		default:
+			return before, nil, tail
+		}
+	}
+	if inLiteral(before) {
+		return before, nil, tail
+	}
+
+	start := wordStart(before)
+	word := before[start:]
+	dot := strings.LastIndexByte(word, '.')
+	if dot < 0 {
+		head = before[:start]
+		return head, matchPrefix(tr.scopeNames(pending+"\n"+head), word), tail
+	}
+	recv, partial := word[:dot], word[dot+1:]
+	head = before[:start+dot+1]
+	return head, matchPrefix(tr.memberNames(recv), partial), tail
+}
+
+// scopeNames lists the identifiers visible at the
+// top level of the current package, the Go keywords,
+// and the identifiers in the pending source src.
+func (tr *IncrState) scopeNames(src string) []string {
+	var names []string
+	if pk := tr.CurPkg; pk != nil && pk.Arch != nil {
+		for _, nm := range pk.Arch.Pkg.Scope().Names() {
+			if !strings.HasPrefix(nm, "__") {
+				names = append(names, nm)
+			}
+		}
+	}
+	names = append(names, types.Universe.Names()...)
+	for tok := token.BREAK; tok <= token.VAR; tok++ {
+		if tok.IsKeyword() {
+			names = append(names, tok.String())
+		}
+	}
+	return append(names, sourceIdents(src)...)
+}
+
+// memberNames lists what may follow `recv.`: the
+// exported members of an imported package, or else
+// the fields and methods of the type of the
+// expression recv.
+func (tr *IncrState) memberNames(recv string) []string {
+	pk := tr.CurPkg
+	if pk == nil || pk.Arch == nil {
+		return nil
+	}
+	pkg := pk.Arch.Pkg
+	if pn, ok := pkg.Scope().Lookup(recv).(*types.PkgName); ok {
+		return packageMembers(pn.Imported())
+	}
+
+	tv, err := types.Eval(pk.fileSet, pkg, token.NoPos, recv)
+	if err != nil || tv.Type == nil {
+		return nil
+	}
+	t := tv.Type
+	var cands []string
+	if tv.IsType() {
+		// method expressions, T.Method
+		mset := types.NewMethodSet(t)
+		for i := 0; i < mset.Len(); i++ {
+			cands = append(cands, mset.At(i).Obj().Name())
+		}
+	} else {
+		cands = fieldNames(t, make(map[types.Type]bool))
+		cands = append(cands, methodNames(t)...)
+	}
+
+	// keep only those that the selector really
+	// reaches: not shadowed, not ambiguous, and
+	// not unexported from another package.
+	var names []string
+	for _, nm := range cands {
+		if obj, _, _ := types.LookupFieldOrMethod(t, true, pkg, nm); obj != nil {
+			names = append(names, nm)
+		}
+	}
+	return names
+}
+
+// packageMembers lists the exported names of p; and
+// of the shadow package registered for p, if any,
+// since that may carry more than the type checker
+// has been told about.
+func packageMembers(p *types.Package) []string {
+	var names []string
+	for _, nm := range p.Scope().Names() {
+		if ast.IsExported(nm) {
+			names = append(names, nm)
+		}
+	}
+	if sp := LookupShadowPackage(exportImportPath(p)); sp != nil {
+		for nm := range sp.Pkg {
+			names = append(names, nm)
+		}
+	}
+	return names
+}
+
+// fieldNames lists the fields of the struct t, or
+// that t points to, including those promoted from
+// embedded fields.
+func fieldNames(t types.Type, seen map[types.Type]bool) []string {
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	var names []string
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		names = append(names, f.Name())
+		if f.Anonymous() {
+			names = append(names, fieldNames(f.Type(), seen)...)
+		}
+	}
+	return names
+}
+
+// methodNames lists the methods callable on an
+// addressable value of type t.
+func methodNames(t types.Type) []string {
+	mset := types.NewMethodSet(t)
+	if _, isPtr := t.Underlying().(*types.Pointer); !isPtr && !types.IsInterface(t) {
+		mset = types.NewMethodSet(types.NewPointer(t))
+	}
+	var names []string
+	for i := 0; i < mset.Len(); i++ {
+		names = append(names, mset.At(i).Obj().Name())
+	}
+	return names
+}
+
+// sourceIdents lists the identifiers in src, which
+// need not parse.
+func sourceIdents(src string) []string {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	s.Init(file, []byte(src), nil, 0)
+
+	var names []string
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.IDENT {
+			names = append(names, lit)
+		}
+	}
+	return names
+}
+
+// wordStart gives the offset in s of the selector
+// expression that ends s, such as `fmt.Pr` or
+// `xs[i].pt.X`. Calls and index expressions are
+// skipped over whole.
+func wordStart(s string) int {
+	rs := []rune(s)
+	i := len(rs)
+	for i > 0 {
+		c := rs[i-1]
+		switch {
+		case c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			i--
+		case (c == ')' || c == ']') && i < len(rs) && rs[i] == '.':
+			open := '('
+			if c == ']' {
+				open = '['
+			}
+			depth := 0
+			j := i - 1
+			for ; j >= 0; j-- {
+				if rs[j] == c {
+					depth++
+				} else if rs[j] == open {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if j < 0 {
+				return len(string(rs[:i]))
+			}
+			i = j
+		default:
+			return len(string(rs[:i]))
+		}
+	}
+	return 0
+}
+
+// inLiteral reports whether s ends inside a string,
+// rune or comment, where there is nothing to
+// complete.
+func inLiteral(s string) bool {
+	var quote rune
+	esc := false
+	rs := []rune(s)
+	for i, c := range rs {
+		switch {
+		case quote == 0:
+			if c == '"' || c == '\'' || c == '`' {
+				quote = c
+			} else if c == '/' && i+1 < len(rs) && rs[i+1] == '/' {
+				return true
+			}
+		case esc:
+			esc = false
+		case c == '\\' && quote != '`':
+			esc = true
+		case c == quote:
+			quote = 0
+		}
+	}
+	return quote != 0
+}
+
+// matchPrefix gives the sorted, distinct, members
+// of names that start with prefix.
+func matchPrefix(names []string, prefix string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, nm := range names {
+		if strings.HasPrefix(nm, prefix) && !seen[nm] {
+			seen[nm] = true
+			out = append(out, nm)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
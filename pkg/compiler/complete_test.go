@@ -0,0 +1,78 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test620TabCompletion(t *testing.T) {
+
+	cv.Convey(`tab completes identifiers in scope and keywords, package members after pkg., fields and methods after x., the colon commands, and names declared on earlier lines of a multi-line entry`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) {
+			LuaRunAndReport(vm, string(inc.Tr([]byte(src))))
+		}
+		run(`import "gitesting"`)
+		run(`type inner struct{ Depth int }`)
+		run(`func (n *inner) Deeper() { n.Depth++ }`)
+		run(`type pt struct{ X, Y int; inner; label string }`)
+		run(`func (p pt) Sum() int { return p.X + p.Y }`)
+		run(`p := pt{X: 1}`)
+		run(`pts := []*pt{&p}`)
+		run(`func pick() *pt { return &p }`)
+		run(`total := 0`)
+
+		complete := func(pending, line string) (string, []string, string) {
+			return inc.Complete(pending, line, len([]rune(line)))
+		}
+
+		head, got, tail := complete("", "x := to")
+		cv.So(head, cv.ShouldEqual, "x := ")
+		cv.So(got, cv.ShouldResemble, []string{"total"})
+		cv.So(tail, cv.ShouldEqual, "")
+
+		_, got, _ = complete("", "p")
+		cv.So(got, cv.ShouldResemble, []string{"p", "package", "panic", "pick", "print", "println", "pt", "pts"})
+
+		head, got, _ = complete("", "gitesting.Su")
+		cv.So(head, cv.ShouldEqual, "gitesting.")
+		cv.So(got, cv.ShouldResemble, []string{"SumArrayInt64", "Summer", "SummerAny"})
+
+		head, got, _ = complete("", "a := p.")
+		cv.So(head, cv.ShouldEqual, "a := p.")
+		cv.So(got, cv.ShouldResemble, []string{"Deeper", "Depth", "Sum", "X", "Y", "inner", "label"})
+
+		_, got, _ = complete("", "pts[0].S")
+		cv.So(got, cv.ShouldResemble, []string{"Sum"})
+
+		_, got, _ = complete("", "pick().D")
+		cv.So(got, cv.ShouldResemble, []string{"Deeper", "Depth"})
+
+		// the cursor in mid-line
+		head, got, tail = inc.Complete("", "fmt(p.X, t)", len("fmt(p.X, t"))
+		cv.So(head, cv.ShouldEqual, "fmt(p.X, ")
+		cv.So(got, cv.ShouldResemble, []string{"total", "true", "type"})
+		cv.So(tail, cv.ShouldEqual, ")")
+
+		_, got, _ = complete("", ":ex")
+		cv.So(got, cv.ShouldResemble, []string{":export"})
+
+		head, got, _ = complete("", ":info p")
+		cv.So(head, cv.ShouldEqual, ":info ")
+		cv.So(got, cv.ShouldContain, "pt")
+
+		// within a string there is nothing to complete.
+		_, got, _ = complete("", `s := "p.`)
+		cv.So(got, cv.ShouldBeEmpty)
+
+		// continuation lines see what the earlier lines declared.
+		_, got, _ = complete("for counter := 0; counter < 3; counter++ {", "  cou")
+		cv.So(got, cv.ShouldResemble, []string{"counter"})
+	})
+}
@@ -0,0 +1,96 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test580ComplexArithmeticAndEquality(t *testing.T) {
+
+	cv.Convey(`complex128 values add, subtract, multiply, divide and compare as in Go, and real, imag and complex take them apart and put them together`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+a := 1+2i
+b := complex(3, -4)
+m := map[complex128]int{a: 1}
+m[complex(1, 2)] = 2
+`)
+		panicOn(err)
+
+		expect := func(expr string, want interface{}) {
+			vals, err := in.Eval(expr)
+			panicOn(err)
+			cv.So(vals[0].Interface(), cv.ShouldEqual, want)
+		}
+		expect(`a + b`, complex(4, -2))
+		expect(`a - b`, complex(-2, 6))
+		expect(`a * b`, complex(11, 2))
+		expect(`a / b`, complex(-0.2, 0.4))
+		expect(`-a`, complex(-1, -2))
+		expect(`a == complex(1, 2)`, true)
+		expect(`a != b`, true)
+		expect(`real(b)`, 3.0)
+		expect(`imag(b)`, -4.0)
+		expect(`len(m)`, 1)
+		expect(`m[1+2i]`, 2)
+	})
+}
+
+func Test581Complex64RoundsAndConverts(t *testing.T) {
+
+	cv.Convey(`complex64 keeps float32 precision, converts to and from complex128, and complex values print as Go prints them`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+var f complex64 = complex(0.1, 1.0/3)
+d := complex128(f)
+g := complex64(d) * 2
+`)
+		panicOn(err)
+
+		vals, err := in.Eval(`d`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, complex128(complex64(complex(0.1, 1.0/3))))
+
+		vals, err = in.Eval(`g`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, complex64(complex(0.2, 2.0/3)))
+
+		LuaRunAndReport(in.vm, `
+s1 = tostring(f)
+s2 = tostring(complex128(123456789, -0.5))
+s3 = tostring(complex128(1/0, 0/0))
+`)
+		LuaMustString(in.vm, "s1", "(0.1+0.33333334i)")
+		LuaMustString(in.vm, "s2", "(1.23456789e+08-0.5i)")
+		LuaMustString(in.vm, "s3", "(+Inf+NaNi)")
+	})
+}
+
+func Test582CmplxInThePrelude(t *testing.T) {
+
+	cv.Convey(`the prelude's cmplx table, which backs import "math/cmplx", computes Abs, Sqrt, Exp and Pow`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		LuaRunAndReport(in.vm, `
+abs = cmplx.Abs(complex128(3, 4))
+sq = tostring(cmplx.Sqrt(complex128(-1, 0)))
+e = cmplx.Exp(complex128(0, math.pi)).re
+p = tostring(cmplx.Pow(complex128(0, 0), complex128(0, 0)))
+nan = cmplx.IsNaN(cmplx.NaN())
+`)
+		LuaMustFloat64(in.vm, "abs", 5)
+		LuaMustString(in.vm, "sq", "(0+1i)")
+		LuaMustFloat64(in.vm, "e", -1)
+		LuaMustString(in.vm, "p", "(1+0i)")
+		LuaMustBool(in.vm, "nan", true)
+	})
+}
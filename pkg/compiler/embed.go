@@ -0,0 +1,467 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gijit/gi/pkg/muse"
+	"github.com/gijit/gi/pkg/parser"
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+	golua "github.com/glycerine/golua/lua"
+	"github.com/glycerine/luar"
+)
+
+// Interpreter lets a Go program use gijit as an
+// in-process scripting language. Unlike the Repl,
+// it prints nothing: results come back as Go values.
+//
+// An Interpreter is not safe for concurrent use.
+type Interpreter struct {
+	vm  *golua.State
+	inc *IncrState
+
+	timeout time.Duration
+	stop    interrupter
+}
+
+// NewInterpreter starts a new LuaJIT vm with the
+// prelude loaded. cfg may be nil. When cfg.PreludePath
+// is empty we look for the prelude as the REPL does;
+// see GIConfig.ValidateConfig.
+func NewInterpreter(cfg *GIConfig) (*Interpreter, error) {
+	if cfg == nil {
+		cfg = NewGIConfig()
+	}
+	err := cfg.ValidateConfig()
+	if err != nil {
+		return nil, err
+	}
+	vmCfg := NewVmConfig()
+	vmCfg.PreludePath = cfg.PreludePath
+	vmCfg.Quiet = true
+	vmCfg.NotTestMode = !cfg.IsTestMode
+	vm, err := NewLuaVmWithPrelude(vmCfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Interpreter{
+		vm:   vm,
+		inc:  NewIncrState(vm, vmCfg),
+		stop: interrupter{vm: vm},
+	}, nil
+}
+
+// Close shuts down the vm. The Interpreter
+// cannot be used afterwards.
+func (in *Interpreter) Close() {
+	in.vm.Close()
+}
+
+// SetTimeout limits each later Eval, Set or Get to
+// running for d, after which it is stopped and returns
+// ErrTimeout. Zero, the default, means no limit.
+func (in *Interpreter) SetTimeout(d time.Duration) {
+	in.timeout = d
+}
+
+// Interrupt stops the Eval, Set or Get that is
+// running, which then returns ErrInterrupted; it
+// does nothing if none is. Unlike the rest of the
+// Interpreter, Interrupt may be called from any
+// goroutine. Either way, the variables and functions
+// defined so far are kept.
+func (in *Interpreter) Interrupt() {
+	in.stop.interrupt(ErrInterrupted)
+}
+
+// Eval compiles and runs src, which may hold any
+// number of declarations and statements, just as a
+// line typed at the REPL would. If src is a single
+// expression, Eval returns its values: one for each
+// result of a multi-valued call, and none for a call
+// without results. Any goroutines started by src run
+// until they finish or block, before Eval returns.
+//
+// Values of named types come back as their underlying
+// type: a struct P as a struct{...} with P's fields,
+// unexported ones included, and a *P as a pointer to
+// that. Recursive types, such as a P with a *P field,
+// cannot be returned.
+func (in *Interpreter) Eval(src string) (vals []reflect.Value, err error) {
+
+	_, perr := parser.ParseExpr(src)
+	if perr != nil {
+		// not an expression, so there is nothing to return.
+		return nil, in.run(src)
+	}
+	tv, err := in.typeOf(src)
+	if err != nil {
+		return nil, err
+	}
+	if tv.IsType() {
+		return nil, fmt.Errorf("'%s' is a type, not an expression", src)
+	}
+	if tv.IsVoid() {
+		return nil, in.run(src)
+	}
+
+	var ts []types.Type
+	if tup, ok := tv.Type.(*types.Tuple); ok {
+		for i := 0; i < tup.Len(); i++ {
+			ts = append(ts, tup.At(i).Type())
+		}
+	} else {
+		ts = append(ts, types.Default(tv.Type))
+	}
+
+	rts := make([]reflect.Type, len(ts))
+	for i, t := range ts {
+		rts[i], err = punType(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// give the results names, so the type checker
+	// and the translator do all the work of
+	// assigning them. The same names serve every
+	// Eval; forget frees them once read.
+	names := make([]string, len(ts))
+	for i := range names {
+		names[i] = fmt.Sprintf("__gijit_eval_%v", i)
+	}
+	err = in.run(strings.Join(names, ", ") + " := " + src)
+	if err != nil {
+		return nil, err
+	}
+	defer in.forget(names)
+
+	top := in.vm.GetTop()
+	defer in.vm.SetTop(top)
+	err = in.runLua(in.inc.CurPkg.envPrefix()+"return "+strings.Join(names, ", "), "", len(names))
+	if err != nil {
+		return nil, err
+	}
+	for i, rt := range rts {
+		v, err := luaToValue(in.vm, top+1+i, rt)
+		if err != nil {
+			return nil, fmt.Errorf("converting value %v of '%s': %v", i, src, err)
+		}
+		vals = append(vals, v)
+	}
+	return vals, nil
+}
+
+// forget removes names, which Eval gave its results,
+// from the Lua VM and from the type checker's scope,
+// so that the results can be collected, however
+// large, and the names declared again.
+func (in *Interpreter) forget(names []string) {
+	nils := make([]string, len(names))
+	for i, nm := range names {
+		nils[i] = nm + " = nil;"
+	}
+	top := in.vm.GetTop()
+	defer in.vm.SetTop(top)
+	in.runLua(in.inc.CurPkg.envPrefix()+strings.Join(nils, " "), "", 0)
+	if arch := in.inc.CurPkg.Arch; arch != nil {
+		for _, nm := range names {
+			arch.Pkg.Scope().DeleteByName(nm)
+		}
+	}
+}
+
+// Get returns the value of the variable or
+// constant name in the current package.
+func (in *Interpreter) Get(name string) (interface{}, error) {
+	if !isPackageName(name) {
+		return nil, fmt.Errorf("Get: '%s' is not an identifier", name)
+	}
+	if in.inc.CurPkg.Arch == nil || in.inc.CurPkg.Arch.Pkg.Scope().Lookup(name) == nil {
+		return nil, fmt.Errorf("Get: '%s' is not defined", name)
+	}
+	vals, err := in.Eval(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) != 1 {
+		return nil, fmt.Errorf("Get: '%s' is not a value", name)
+	}
+	return vals[0].Interface(), nil
+}
+
+// Set declares the variable name in the current
+// package, or replaces it, giving it the type and
+// value of v. Supported are the basic types, slices,
+// arrays and maps built from them, and funcs, which
+// the script can then call.
+func (in *Interpreter) Set(name string, v interface{}) error {
+	if !isPackageName(name) {
+		return fmt.Errorf("Set: '%s' is not an identifier", name)
+	}
+	if v == nil {
+		return fmt.Errorf("Set: cannot infer a type for nil")
+	}
+	rv := reflect.ValueOf(v)
+	rt := rv.Type()
+	typ, err := goTypeSyntax(rt)
+	if err != nil {
+		return fmt.Errorf("Set: %v", err)
+	}
+
+	if rt.Kind() == reflect.Func {
+		err = in.run(fmt.Sprintf("var %s %s", name, typ))
+		if err != nil {
+			return err
+		}
+		// the Go func is called through its luar proxy.
+		luar.GoToLua(in.vm, rv)
+		if in.inc.CurPkg.key == "main" {
+			in.vm.SetGlobal(name)
+			return nil
+		}
+		in.vm.GetGlobal(pkgTableVar(in.inc.CurPkg.key))
+		in.vm.Insert(-2)
+		in.vm.SetField(-2, name)
+		in.vm.Pop(1)
+		return nil
+	}
+
+	lit, err := goLiteral(rv)
+	if err != nil {
+		return fmt.Errorf("Set: %v", err)
+	}
+	return in.run(fmt.Sprintf("var %s %s = %s", name, typ, lit))
+}
+
+// typeOf type checks the expression src against
+// the current package, without running it.
+func (in *Interpreter) typeOf(src string) (tv types.TypeAndValue, err error) {
+	pk := in.inc.CurPkg
+	if pk.Arch == nil {
+		// nothing has been compiled in this package
+		// yet, so there is no types.Package to look in.
+		err = in.run("")
+		if err != nil {
+			return
+		}
+	}
+	return types.Eval(pk.fileSet, pk.Arch.Pkg, token.NoPos, src)
+}
+
+// run translates src from Go to Lua and runs it.
+// Then any goroutines get a chance to run. All of
+// this falls under the time limit, if there is one.
+func (in *Interpreter) run(src string) error {
+	translation, err := translateAndCatchPanic(in.inc, []byte(src))
+	if err != nil {
+		return err
+	}
+	chunk := in.inc.LastChunk()
+	return in.stop.run(in.timeout, false, func() error {
+		err := in.runLua(translation, chunk, 0)
+		if err != nil {
+			return err
+		}
+		return in.inc.MapLuaError(LuaRunGoroutines(in.vm))
+	})
+}
+
+// runLua runs lua, named chunk, leaving nres
+// results on the stack.
+func (in *Interpreter) runLua(lua, chunk string, nres int) error {
+	err := LoadChunk(in.vm, lua, chunk)
+	if err != nil {
+		return fmt.Errorf("error loading translation '%s': %v", lua, err)
+	}
+	err = CallChunk(in.vm, 0, nres)
+	if err != nil {
+		return in.inc.MapLuaError(err)
+	}
+	return nil
+}
+
+// punType gives the reflect.Type for values of t.
+// Named types become their underlying type, so that
+// a struct P comes back as a struct{...} with the
+// same fields, and interfaces become interface{}.
+func punType(t types.Type) (rt reflect.Type, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("values of type '%v' cannot be returned to Go yet: %v", t, r)
+		}
+	}()
+	switch u := t.Underlying().(type) {
+	case *types.Interface:
+		return reflect.TypeOf((*interface{})(nil)).Elem(), nil
+	default:
+		return muse.NewMuse().Pun(u)
+	}
+}
+
+// luaToValue converts the Lua value at idx into a
+// Go value of type rt. Integers arrive either as
+// int64/uint64 cdata or as Lua numbers, which luar
+// only converts to some kinds, so we handle those.
+func luaToValue(L *golua.State, idx int, rt reflect.Type) (reflect.Value, error) {
+	v := reflect.New(rt).Elem()
+	isCdata := L.Type(idx) == 10 // LUA_TCDATA
+	switch rt.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch {
+		case isCdata:
+			v.SetInt(L.CdataToInt64(idx))
+			return v, nil
+		case L.Type(idx) == golua.LUA_TNUMBER:
+			v.SetInt(int64(L.ToNumber(idx)))
+			return v, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch {
+		case isCdata:
+			v.SetUint(L.CdataToUint64(idx))
+			return v, nil
+		case L.Type(idx) == golua.LUA_TNUMBER:
+			v.SetUint(uint64(L.ToNumber(idx)))
+			return v, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if L.Type(idx) == golua.LUA_TNUMBER {
+			v.SetFloat(L.ToNumber(idx))
+			return v, nil
+		}
+	case reflect.Complex64, reflect.Complex128:
+		if isCdata {
+			// the prelude's complex64/complex128 structs.
+			if idx < 0 {
+				idx = L.GetTop() + idx + 1
+			}
+			L.GetField(idx, "re")
+			re := L.ToNumber(-1)
+			L.GetField(idx, "im")
+			im := L.ToNumber(-1)
+			L.Pop(2)
+			v.SetComplex(complex(re, im))
+			return v, nil
+		}
+	case reflect.Interface:
+		if L.IsNil(idx) {
+			return v, nil
+		}
+	}
+	err := luar.LuaToGo(L, idx, v.Addr().Interface())
+	return v, err
+}
+
+// goTypeSyntax writes rt as Go source. Only types
+// that need no import qualify.
+func goTypeSyntax(rt reflect.Type) (string, error) {
+	switch rt.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Func:
+		if rt.Name() != "" {
+			break
+		}
+		var elems []reflect.Type
+		switch rt.Kind() {
+		case reflect.Map:
+			elems = append(elems, rt.Key(), rt.Elem())
+		case reflect.Func:
+			for i := 0; i < rt.NumIn(); i++ {
+				elems = append(elems, rt.In(i))
+			}
+			for i := 0; i < rt.NumOut(); i++ {
+				elems = append(elems, rt.Out(i))
+			}
+		default:
+			elems = append(elems, rt.Elem())
+		}
+		for _, e := range elems {
+			if _, err := goTypeSyntax(e); err != nil {
+				return "", err
+			}
+		}
+		return rt.String(), nil
+	case reflect.Chan, reflect.Ptr, reflect.Struct, reflect.UnsafePointer:
+	default:
+		if rt.PkgPath() == "" {
+			// predeclared: bool, int, string, error...
+			return rt.String(), nil
+		}
+	}
+	return "", fmt.Errorf("values of type '%v' are not supported", rt)
+}
+
+// goLiteral writes v as a Go expression.
+func goLiteral(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", fmt.Errorf("no Go literal for %v", f)
+		}
+		return strconv.FormatFloat(f, 'g', -1, v.Type().Bits()), nil
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return fmt.Sprintf("complex(%s, %s)",
+			strconv.FormatFloat(real(c), 'g', -1, 64),
+			strconv.FormatFloat(imag(c), 'g', -1, 64)), nil
+	case reflect.String:
+		return strconv.Quote(v.String()), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return "nil", nil
+		}
+		elems := make([]string, v.Len())
+		for i := range elems {
+			e, err := goLiteral(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			elems[i] = e
+		}
+		return v.Type().String() + "{" + strings.Join(elems, ", ") + "}", nil
+	case reflect.Map:
+		if v.IsNil() {
+			return "nil", nil
+		}
+		var elems []string
+		for _, k := range v.MapKeys() {
+			ks, err := goLiteral(k)
+			if err != nil {
+				return "", err
+			}
+			vs, err := goLiteral(v.MapIndex(k))
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, ks+": "+vs)
+		}
+		return v.Type().String() + "{" + strings.Join(elems, ", ") + "}", nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return "nil", nil
+		}
+		// keep the dynamic type.
+		typ, err := goTypeSyntax(v.Elem().Type())
+		if err != nil {
+			return "", err
+		}
+		lit, err := goLiteral(v.Elem())
+		if err != nil {
+			return "", err
+		}
+		return typ + "(" + lit + ")", nil
+	}
+	return "", fmt.Errorf("values of type '%v' are not supported", v.Type())
+}
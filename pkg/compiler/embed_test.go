@@ -0,0 +1,290 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func newTestInterpreter() *Interpreter {
+	cfg := NewGIConfig()
+	cfg.PreludePath = "."
+	in, err := NewInterpreter(cfg)
+	panicOn(err)
+	return in
+}
+
+func Test530InterpreterEvalReturnsGoValues(t *testing.T) {
+
+	cv.Convey(`Interpreter.Eval runs statements, and returns the values of an expression as reflect.Values`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		vals, err := in.Eval(`
+a := 6
+func twice(x int) (int, string) {
+    return 2 * x, "twice"
+}
+`)
+		panicOn(err)
+		cv.So(len(vals), cv.ShouldEqual, 0)
+
+		vals, err = in.Eval(`a * 7`)
+		panicOn(err)
+		cv.So(len(vals), cv.ShouldEqual, 1)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 42)
+
+		vals, err = in.Eval(`twice(a)`)
+		panicOn(err)
+		cv.So(len(vals), cv.ShouldEqual, 2)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 12)
+		cv.So(vals[1].Interface(), cv.ShouldEqual, "twice")
+
+		vals, err = in.Eval(`[]int{1, 2, 3}`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldResemble, []int{1, 2, 3})
+
+		vals, err = in.Eval(`float32(1.5) + 1`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, float32(2.5))
+
+		vals, err = in.Eval(`uint8(200) > 100`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, true)
+
+		// a call without results runs, and returns nothing.
+		vals, err = in.Eval(`func bump() { a++ }`)
+		panicOn(err)
+		vals, err = in.Eval(`bump()`)
+		panicOn(err)
+		cv.So(len(vals), cv.ShouldEqual, 0)
+		x, err := in.Get("a")
+		panicOn(err)
+		cv.So(x, cv.ShouldEqual, 7)
+	})
+}
+
+func Test531InterpreterSetAndGet(t *testing.T) {
+
+	cv.Convey(`Interpreter.Set declares typed variables and Go funcs for the script, Interpreter.Get reads them back`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		panicOn(in.Set("n", int64(5)))
+		panicOn(in.Set("name", "gijit"))
+		panicOn(in.Set("xs", []float64{1.5, 2.5}))
+		panicOn(in.Set("m", map[int]string{1: "a"}))
+		panicOn(in.Set("scale", func(x float64) float64 { return x * 10 }))
+
+		_, err := in.Eval(`
+tot := 0.0
+for _, x := range xs {
+   tot += scale(x)
+}
+greeting := name + "!"
+n2 := n * 2
+m[2] = "b"
+sz := len(m)
+`)
+		panicOn(err)
+
+		tot, err := in.Get("tot")
+		panicOn(err)
+		cv.So(tot, cv.ShouldEqual, 40.0)
+
+		greeting, err := in.Get("greeting")
+		panicOn(err)
+		cv.So(greeting, cv.ShouldEqual, "gijit!")
+
+		n2, err := in.Get("n2")
+		panicOn(err)
+		cv.So(n2, cv.ShouldEqual, int64(10))
+
+		sz, err := in.Get("sz")
+		panicOn(err)
+		cv.So(sz, cv.ShouldEqual, 2)
+
+		// Set replaces an earlier value, even with a new type.
+		panicOn(in.Set("n", "five"))
+		n, err := in.Get("n")
+		panicOn(err)
+		cv.So(n, cv.ShouldEqual, "five")
+
+		_, err = in.Get("nope")
+		cv.So(err, cv.ShouldNotBeNil)
+
+		err = in.Set("p", &struct{}{})
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(strings.Contains(err.Error(), "not supported"), cv.ShouldBeTrue)
+
+		// funcs go into the package table outside main.
+		_, err = in.inc.SwitchPackage("tools")
+		panicOn(err)
+		panicOn(in.Set("half", func(x float64) float64 { return x / 2 }))
+		vals, err := in.Eval(`half(9)`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 4.5)
+	})
+}
+
+func Test532InterpreterEvalReportsErrors(t *testing.T) {
+
+	cv.Convey(`type errors and runtime panics come back from Eval as errors, and the Interpreter stays usable`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`b := "x" + 1`)
+		cv.So(err, cv.ShouldNotBeNil)
+
+		_, err = in.Eval(`panic("oh no")`)
+		cv.So(err, cv.ShouldNotBeNil)
+
+		vals, err := in.Eval(`1 + 2`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 3)
+	})
+}
+
+func Test533InterpreterTimeoutAndInterrupt(t *testing.T) {
+
+	cv.Convey(`a runaway loop is stopped by the time limit, or by Interrupt from another goroutine, even once LuaJIT has compiled it; what ran before the stop is kept`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		in.SetTimeout(100 * time.Millisecond)
+		_, err := in.Eval(`n := 0`)
+		panicOn(err)
+		_, err = in.Eval(`for { n++ }`)
+		cv.So(err, cv.ShouldEqual, ErrTimeout)
+		v, err := in.Get("n")
+		panicOn(err)
+		cv.So(v, cv.ShouldBeGreaterThan, 1000)
+
+		// a loop inside a function, with no time limit.
+		in.SetTimeout(0)
+		_, err = in.Eval(`func spin() { for i := 0; ; i++ { n = i } }`)
+		panicOn(err)
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			in.Interrupt()
+		}()
+		_, err = in.Eval(`spin()`)
+		cv.So(err, cv.ShouldEqual, ErrInterrupted)
+
+		// an Interrupt with nothing running is forgotten.
+		in.Interrupt()
+		vals, err := in.Eval(`n > 1000`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, true)
+	})
+}
+
+func Test535InterruptStopsGoroutinesAndGotoLoops(t *testing.T) {
+
+	cv.Convey(`the time limit, and Interrupt, stop a runaway goroutine, and a loop made with a backward goto, and Eval reports it`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		in.SetTimeout(100 * time.Millisecond)
+		_, err := in.Eval(`n := 0`)
+		panicOn(err)
+		_, err = in.Eval(`go func() { for { n++ } }()`)
+		cv.So(err, cv.ShouldEqual, ErrTimeout)
+
+		in.SetTimeout(0)
+		_, err = in.Eval(`func spin() {
+	i := 0
+top:
+	i++
+	if i == 5000 {
+		n = i
+	}
+	goto top
+}`)
+		panicOn(err)
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			in.Interrupt()
+		}()
+		_, err = in.Eval(`spin()`)
+		cv.So(err, cv.ShouldEqual, ErrInterrupted)
+
+		vals, err := in.Eval(`n > 1000`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, true)
+	})
+}
+
+func Test534InterpreterEvalLeavesNothingBehind(t *testing.T) {
+
+	cv.Convey(`Eval frees the variables that carry its results, in Lua and in the type checker, so a long running embedder does not keep every result alive`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		for i := 0; i < 20; i++ {
+			vals, err := in.Eval(`make([]int, 1000)`)
+			panicOn(err)
+			cv.So(vals[0].Len(), cv.ShouldEqual, 1000)
+		}
+		_, err := in.Eval(`func two() (string, int) { return "a", 2 }`)
+		panicOn(err)
+		vals, err := in.Eval(`two()`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, "a")
+		cv.So(vals[1].Interface(), cv.ShouldEqual, 2)
+
+		for _, nm := range in.inc.CurPkg.Arch.Pkg.Scope().Names() {
+			cv.So(strings.HasPrefix(nm, "__gijit_eval"), cv.ShouldBeFalse)
+		}
+		LuaRunAndReport(in.vm, `left = __gijit_eval_0 == nil and __gijit_eval_1 == nil`)
+		LuaMustBool(in.vm, "left", true)
+	})
+}
+
+func Test536InterpreterEvalReturnsStructs(t *testing.T) {
+
+	cv.Convey(`Eval returns structs, and pointers to structs, defined in the script, as Go structs with the same fields`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+type P struct {
+	X, Y int
+	name string
+}
+type Line struct {
+	A, B  P
+	Label string
+}
+`)
+		panicOn(err)
+
+		vals, err := in.Eval(`P{1, 2, "p"}`)
+		panicOn(err)
+		p := vals[0]
+		cv.So(p.FieldByName("X").Interface(), cv.ShouldEqual, 1)
+		cv.So(p.FieldByName("Y").Interface(), cv.ShouldEqual, 2)
+		cv.So(p.FieldByName("name").String(), cv.ShouldEqual, "p")
+
+		vals, err = in.Eval(`&P{X: 3, Y: 4}`)
+		panicOn(err)
+		cv.So(vals[0].Elem().FieldByName("X").Interface(), cv.ShouldEqual, 3)
+		cv.So(vals[0].Elem().FieldByName("Y").Interface(), cv.ShouldEqual, 4)
+
+		vals, err = in.Eval(`Line{A: P{X: 5}, B: P{Y: 6}, Label: "l"}`)
+		panicOn(err)
+		ln := vals[0]
+		cv.So(ln.FieldByName("A").FieldByName("X").Interface(), cv.ShouldEqual, 5)
+		cv.So(ln.FieldByName("B").FieldByName("Y").Interface(), cv.ShouldEqual, 6)
+		cv.So(ln.FieldByName("Label").Interface(), cv.ShouldEqual, "l")
+	})
+}
@@ -0,0 +1,492 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/format"
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+)
+
+/*
+:export main.go writes the REPL session out as a
+Go program.
+
+The history is no good for this: it holds lines that
+failed, and replays. Instead, each time a chunk of Go
+translates, Tr stages its top-level declarations and
+statements here, by their source text, and the REPL
+commits them once the chunk has run without error.
+
+Declarations are kept by the names they declare, so
+a redefinition replaces the earlier version. If that
+changes what the name is, say `x := "s"` after
+`x := pt{1, 2}`, the entries that used the earlier x
+would no longer compile, so they go too; and so on
+for the entries that used theirs. The
+statements go, in order, into func main(); or into
+func init(), if the session defined its own main.
+Since funcs may use the variables that top-level
+statements declare, `x := 1` becomes a package-level
+`var x int`, and `x = 1` in main.
+*/
+
+// replSession holds the committed declarations and
+// statements of one package.
+type replSession struct {
+	pkg    *types.Package
+	decls  []*sessionEntry
+	stmts  []*sessionEntry
+	staged []*sessionEntry
+}
+
+// sessionEntry is one top-level declaration, or one
+// top-level statement, with the imports it needs.
+type sessionEntry struct {
+	names   []string // declared; none for a statement
+	src     string
+	imports []importSpec
+
+	// uses holds the package-level names, and the
+	// methods, as "T.m", that the entry refers to.
+	uses map[string]bool
+
+	// shape is what users of names depend on: the
+	// source, but only the signature of a func, so
+	// that a new body does not drop its callers.
+	shape string
+}
+
+type importSpec struct {
+	name string // "" for the package's own name
+	path string
+}
+
+func newReplSession() *replSession {
+	return &replSession{}
+}
+
+// commit keeps what the last Tr staged.
+func (s *replSession) commit() {
+	changed := make(map[string]bool)
+	for _, e := range s.staged {
+		for _, d := range s.decls {
+			for _, nm := range d.names {
+				if e.declares(nm) && d.shape != e.shape {
+					changed[nm] = true
+				}
+			}
+		}
+		s.drop(e.names)
+	}
+	s.dropUsers(changed)
+	for _, e := range s.staged {
+		if e.names == nil {
+			s.stmts = append(s.stmts, e)
+			continue
+		}
+		s.decls = append(s.decls, e)
+	}
+	s.staged = nil
+}
+
+func (e *sessionEntry) declares(name string) bool {
+	for _, nm := range e.names {
+		if nm == name {
+			return true
+		}
+	}
+	return false
+}
+
+// drop removes the declarations of any of names.
+func (s *replSession) drop(names []string) {
+	gone := make(map[string]bool)
+	for _, nm := range names {
+		gone[nm] = true
+	}
+	keep := s.decls[:0]
+	for _, d := range s.decls {
+		clash := false
+		for _, nm := range d.names {
+			if gone[nm] {
+				clash = true
+				break
+			}
+		}
+		if !clash {
+			keep = append(keep, d)
+		}
+	}
+	s.decls = keep
+}
+
+// dropUsers removes the declarations and statements
+// that use any of the names in gone; and then those
+// that use what they declared, and so on.
+func (s *replSession) dropUsers(gone map[string]bool) {
+	uses := func(e *sessionEntry) bool {
+		for nm := range e.uses {
+			if gone[nm] {
+				return true
+			}
+		}
+		return false
+	}
+	for more := len(gone) > 0; more; {
+		more = false
+		keep := s.decls[:0]
+		for _, d := range s.decls {
+			if !uses(d) {
+				keep = append(keep, d)
+				continue
+			}
+			for _, nm := range d.names {
+				if !gone[nm] {
+					gone[nm] = true
+					more = true
+				}
+			}
+		}
+		s.decls = keep
+	}
+	keep := s.stmts[:0]
+	for _, st := range s.stmts {
+		if !uses(st) {
+			keep = append(keep, st)
+		}
+	}
+	s.stmts = keep
+}
+
+// CommitSession records that the Go most recently
+// translated by Tr ran without error, so that
+// :export will include it.
+func (tr *IncrState) CommitSession() {
+	tr.CurPkg.session.commit()
+}
+
+// ExportMain writes the session in package main as
+// a Go program.
+func (tr *IncrState) ExportMain() ([]byte, error) {
+	pk := tr.pkgMap["main"]
+	s := pk.session
+
+	imports := make(map[importSpec]bool)
+	var body bytes.Buffer
+	hasMain := false
+	for _, d := range s.decls {
+		for _, nm := range d.names {
+			if nm == "main" {
+				hasMain = true
+			}
+		}
+		for _, im := range d.imports {
+			imports[im] = true
+		}
+		fmt.Fprintf(&body, "%s\n\n", d.src)
+	}
+	entry := "main"
+	if hasMain {
+		entry = "init"
+	}
+	fmt.Fprintf(&body, "func %s() {\n", entry)
+	for _, st := range s.stmts {
+		for _, im := range st.imports {
+			imports[im] = true
+		}
+		fmt.Fprintf(&body, "%s\n", st.src)
+	}
+	fmt.Fprintf(&body, "}\n")
+
+	var specs []importSpec
+	for im := range imports {
+		specs = append(specs, im)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		if specs[i].path != specs[j].path {
+			return specs[i].path < specs[j].path
+		}
+		return specs[i].name < specs[j].name
+	})
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// exported from a gijit session.\n\npackage main\n\n")
+	if len(specs) > 0 {
+		fmt.Fprintf(&out, "import (\n")
+		for _, im := range specs {
+			if im.name != "" {
+				fmt.Fprintf(&out, "\t%s %s\n", im.name, strconv.Quote(im.path))
+				continue
+			}
+			fmt.Fprintf(&out, "\t%s\n", strconv.Quote(im.path))
+		}
+		fmt.Fprintf(&out, ")\n\n")
+	}
+	out.Write(body.Bytes())
+
+	pretty, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("exported session does not format: %v", err)
+	}
+	return pretty, nil
+}
+
+// stage records the top-level declarations and
+// statements of file, parsed from src, to be kept by
+// the next commit.
+func (s *replSession) stage(fset *token.FileSet, src []byte, file *ast.File, info *types.Info, pkg *types.Package) {
+	s.pkg = pkg
+	s.staged = nil
+	text := func(n ast.Node) string {
+		f := fset.File(n.Pos())
+		return string(src[f.Offset(n.Pos()):f.Offset(n.End())])
+	}
+
+	for _, node := range file.Nodes {
+		switch n := node.(type) {
+		case *ast.GenDecl:
+			switch n.Tok {
+			case token.IMPORT:
+				// imports are written for the entries that use them.
+			case token.TYPE:
+				for _, spec := range n.Specs {
+					ts := spec.(*ast.TypeSpec)
+					s.add(info, []string{ts.Name.Name}, "type "+text(ts), ts)
+				}
+			case token.CONST:
+				// kept whole, for iota.
+				var names []string
+				for _, spec := range n.Specs {
+					for _, id := range spec.(*ast.ValueSpec).Names {
+						names = append(names, id.Name)
+					}
+				}
+				s.add(info, names, text(n), n)
+			case token.VAR:
+				for _, spec := range n.Specs {
+					vs := spec.(*ast.ValueSpec)
+					s.addVars(info, vs.Names)
+					if len(vs.Values) > 0 {
+						var lhs []string
+						for _, id := range vs.Names {
+							lhs = append(lhs, id.Name)
+						}
+						f := fset.File(vs.Values[0].Pos())
+						rhs := string(src[f.Offset(vs.Values[0].Pos()):f.Offset(vs.Values[len(vs.Values)-1].End())])
+						s.add(info, nil, strings.Join(lhs, ", ")+" = "+rhs, vs)
+					}
+				}
+			}
+
+		case *ast.FuncDecl:
+			name := n.Name.Name
+			if n.Recv != nil && len(n.Recv.List) == 1 {
+				recv := n.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				if id, ok := recv.(*ast.Ident); ok {
+					name = id.Name + "." + name
+				}
+			}
+			e := s.add(info, []string{name}, text(n), n)
+			if obj := info.Defs[n.Name]; obj != nil {
+				e.shape = types.TypeString(obj.Type(), nil)
+			}
+
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE {
+				s.add(info, nil, text(n), n)
+				break
+			}
+			if len(n.Lhs) == 1 {
+				if id, ok := n.Lhs[0].(*ast.Ident); ok && id.Name == "__gijit_ans" {
+					// from `= 3 + 4`, which prints the value.
+					s.addPrint(info, text(n.Rhs[0]), n.Rhs[0])
+					break
+				}
+			}
+			var ids []*ast.Ident
+			for _, x := range n.Lhs {
+				ids = append(ids, x.(*ast.Ident))
+			}
+			s.addVars(info, ids)
+			f := fset.File(n.Pos())
+			stmt := text(n)
+			at := f.Offset(n.TokPos) - f.Offset(n.Pos())
+			s.add(info, nil, stmt[:at]+"="+stmt[at+len(":="):], n)
+
+		case *ast.ExprStmt:
+			if isStatementCall(info, n.X) {
+				s.add(info, nil, text(n), n)
+			} else {
+				// the REPL displays the value of an expression.
+				s.addPrint(info, text(n.X), n.X)
+			}
+
+		case ast.Stmt:
+			s.add(info, nil, text(n), n)
+		}
+	}
+}
+
+// add stages an entry with the source src, which
+// comes from node n.
+func (s *replSession) add(info *types.Info, names []string, src string, n ast.Node) *sessionEntry {
+	e := &sessionEntry{names: names, src: src, shape: src, uses: make(map[string]bool)}
+	seen := make(map[importSpec]bool)
+	ast.Inspect(n, func(x ast.Node) bool {
+		if id, ok := x.(*ast.Ident); ok {
+			obj := info.Uses[id]
+			if obj == nil {
+				obj = info.Defs[id]
+			}
+			if nm := s.sessionName(obj); nm != "" && !e.declares(nm) {
+				e.uses[nm] = true
+			}
+			if pn, ok := obj.(*types.PkgName); ok {
+				im := importSpec{path: exportImportPath(pn.Imported())}
+				if pn.Name() != pn.Imported().Name() {
+					im.name = pn.Name()
+				}
+				if !seen[im] {
+					seen[im] = true
+					e.imports = append(e.imports, im)
+				}
+			}
+		}
+		return true
+	})
+	s.staged = append(s.staged, e)
+	return e
+}
+
+// addPrint stages fmt.Println(expr).
+func (s *replSession) addPrint(info *types.Info, expr string, n ast.Node) {
+	e := s.add(info, nil, "fmt.Println("+expr+")", n)
+	e.imports = append(e.imports, importSpec{path: "fmt"})
+}
+
+// addVars stages a package-level var for each of ids.
+func (s *replSession) addVars(info *types.Info, ids []*ast.Ident) {
+	for _, id := range ids {
+		if id.Name == "_" {
+			continue
+		}
+		obj := info.Defs[id]
+		if obj == nil {
+			obj = info.Uses[id]
+		}
+		if obj == nil {
+			continue
+		}
+		e := &sessionEntry{names: []string{id.Name}, uses: make(map[string]bool)}
+		e.src = "var " + id.Name + " " + types.TypeString(obj.Type(), func(p *types.Package) string {
+			if p.Name() == "main" || p.Name() == "" {
+				return ""
+			}
+			e.imports = append(e.imports, importSpec{path: exportImportPath(p)})
+			return p.Name()
+		})
+		e.shape = e.src
+		// the var's type may be one of the session's.
+		s.typeNames(obj.Type(), e.uses)
+		s.staged = append(s.staged, e)
+	}
+}
+
+// sessionName gives the name by which the session
+// keeps obj: its own for package-level objects, and
+// "T.m" for the methods of a package-level type T.
+// Anything else gets "".
+func (s *replSession) sessionName(obj types.Object) string {
+	if obj == nil || obj.Pkg() != s.pkg {
+		return ""
+	}
+	if obj.Parent() == s.pkg.Scope() {
+		return obj.Name()
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			t := recv.Type()
+			if p, ok := t.(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if named, ok := t.(*types.Named); ok && named.Obj().Parent() == s.pkg.Scope() {
+				return named.Obj().Name() + "." + fn.Name()
+			}
+		}
+	}
+	return ""
+}
+
+// typeNames adds to names the session's named types
+// that t is built from.
+func (s *replSession) typeNames(t types.Type, names map[string]bool) {
+	switch t := t.(type) {
+	case *types.Named:
+		if nm := s.sessionName(t.Obj()); nm != "" {
+			names[nm] = true
+		}
+	case *types.Pointer:
+		s.typeNames(t.Elem(), names)
+	case *types.Slice:
+		s.typeNames(t.Elem(), names)
+	case *types.Array:
+		s.typeNames(t.Elem(), names)
+	case *types.Chan:
+		s.typeNames(t.Elem(), names)
+	case *types.Map:
+		s.typeNames(t.Key(), names)
+		s.typeNames(t.Elem(), names)
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			s.typeNames(t.Field(i).Type(), names)
+		}
+	case *types.Signature:
+		for _, tup := range []*types.Tuple{t.Params(), t.Results()} {
+			for i := 0; i < tup.Len(); i++ {
+				s.typeNames(tup.At(i).Type(), names)
+			}
+		}
+	}
+}
+
+// exportImportPath undoes the shadow path that
+// ActuallyImportPackage gives packages.
+func exportImportPath(p *types.Package) string {
+	return strings.TrimPrefix(p.Path(), "github.com/gijit/gi/pkg/compiler/shadow/")
+}
+
+// isStatementCall reports whether x may stand alone
+// as a statement in Go: a call of a func, or of one
+// of the builtins whose result may be dropped, or a
+// receive.
+func isStatementCall(info *types.Info, x ast.Expr) bool {
+	switch e := x.(type) {
+	case *ast.ParenExpr:
+		return isStatementCall(info, e.X)
+	case *ast.UnaryExpr:
+		return e.Op == token.ARROW
+	case *ast.CallExpr:
+		if tv, ok := info.Types[e.Fun]; ok && tv.IsType() {
+			return false // a conversion
+		}
+		if id, ok := e.Fun.(*ast.Ident); ok {
+			if b, ok := info.Uses[id].(*types.Builtin); ok {
+				switch b.Name() {
+				case "append", "cap", "complex", "imag", "len", "make", "new", "real":
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
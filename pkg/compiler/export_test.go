@@ -0,0 +1,150 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test600ExportSessionAsProgram(t *testing.T) {
+
+	cv.Convey(`:export writes the surviving declarations, the latest version of each, and puts the top-level statements in func main`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) {
+			translation := inc.Tr([]byte(src))
+			LuaRunAndReport(vm, string(translation))
+			inc.CommitSession()
+		}
+		run(`type pt struct{ X, Y int }`)
+		run(`func (p pt) sum() int { return p.X }`)
+		run(`func (p pt) sum() int { return p.X + p.Y }`)
+		run(`const (
+	a = iota
+	b
+)`)
+		run(`x := pt{1, 2}`)
+		run(`var n int = x.sum()`)
+		run(`x.Y = b`)
+		run(`n`)
+
+		// translated, but never committed, as if it had failed.
+		inc.Tr([]byte(`n = 99`))
+
+		by, err := inc.ExportMain()
+		panicOn(err)
+		cv.So(string(by), cv.ShouldEqual, `// exported from a gijit session.
+
+package main
+
+import (
+	"fmt"
+)
+
+type pt struct{ X, Y int }
+
+func (p pt) sum() int { return p.X + p.Y }
+
+const (
+	a = iota
+	b
+)
+
+var x pt
+
+var n int
+
+func main() {
+	x = pt{1, 2}
+	n = x.sum()
+	x.Y = b
+	fmt.Println(n)
+}
+`)
+		goVet(t, by)
+	})
+}
+
+func Test601ExportDropsUsersOfARedefinedName(t *testing.T) {
+
+	cv.Convey(`when :export finds a name redefined as something else, it leaves out the statements and declarations that used the old one, so the program still builds`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) {
+			translation := inc.Tr([]byte(src))
+			LuaRunAndReport(vm, string(translation))
+			inc.CommitSession()
+		}
+		run(`type pt struct{ X, Y int }`)
+		run(`func (p pt) sum() int { return p.X + p.Y }`)
+		run(`x := pt{1, 2}`)
+		run(`var n int = x.sum()`)
+		run(`func getY() int { return x.Y }`)
+		run(`x.Y = 5`)
+		run(`y := 3`)
+		run(`y = y + n`)
+		run(`x := "s"`)
+		run(`y := 4`)
+		run(`x`)
+
+		by, err := inc.ExportMain()
+		panicOn(err)
+		cv.So(string(by), cv.ShouldEqual, `// exported from a gijit session.
+
+package main
+
+import (
+	"fmt"
+)
+
+type pt struct{ X, Y int }
+
+func (p pt) sum() int { return p.X + p.Y }
+
+var n int
+
+var x string
+
+var y int
+
+func main() {
+	y = 3
+	y = y + n
+	x = "s"
+	y = 4
+	fmt.Println(x)
+}
+`)
+		goVet(t, by)
+	})
+}
+
+// goVet checks that the exported program builds,
+// and passes go vet.
+func goVet(t *testing.T, prog []byte) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go tool to vet the export with")
+	}
+	dir, err := ioutil.TempDir("", "gi-export")
+	panicOn(err)
+	defer os.RemoveAll(dir)
+	panicOn(ioutil.WriteFile(filepath.Join(dir, "main.go"), prog, 0644))
+
+	cmd := exec.Command("go", "vet", "main.go")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	cv.So(string(out), cv.ShouldEqual, "")
+	cv.So(err, cv.ShouldBeNil)
+}
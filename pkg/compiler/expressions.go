@@ -81,9 +81,8 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			return c.formatExpr("%s", strconv.FormatBool(constant.BoolVal(value)))
 		case isInteger(basic):
 
-			// jea: for now, all int types are 64-bit
-			// jea: TODO: come back and handle int32, uint32, int16, uint16, int8, uint8
-			//if is64Bit(basic) {
+			// jea: all int types are held in 64-bit cdata; the
+			// narrower ones are kept in range by fixNumber.
 			k := basic.Kind()
 			if desiredType != nil {
 				switch bk := desiredType.(type) {
@@ -134,9 +133,10 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			if basic.Kind() == types.UntypedComplex {
 				exprType = types.Typ[types.Complex128]
 			}
-			// jea, luajit has native complex number syntax
-			return c.formatExpr("%s+%si", strconv.FormatFloat(r, 'g', -1, 64), strconv.FormatFloat(i, 'g', -1, 64))
-			//return c.formatExpr("new %s(%s, %s)", c.typeName(exprType), strconv.FormatFloat(r, 'g', -1, 64), strconv.FormatFloat(i, 'g', -1, 64))
+			// jea: luajit's native 1+2i complex cdata has no
+			// arithmetic, so we use the prelude's complex64
+			// and complex128 metatypes instead.
+			return c.formatExpr("%s(%s, %s)", exprType.Underlying().(*types.Basic).Name(), strconv.FormatFloat(r, 'g', -1, 64), strconv.FormatFloat(i, 'g', -1, 64))
 		case isString(basic):
 			pp("jea, in translateExpr(), value = '%v'", value)
 			return c.formatExpr("%s", encodeString(constant.StringVal(value)))
@@ -175,6 +175,9 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			return c.formatExpr("undefined")
 		}
 	}
+	if obj != nil && typesutil.IsLuaPackage(obj.Pkg()) && obj.Name() == "Global" {
+		return c.formatExpr("_G")
+	}
 
 	//pp("expressions.go:115, expr is '%#v'/Type=%T", expr, expr)
 	switch e := expr.(type) {
@@ -261,11 +264,11 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			entries := make([]string, len(e.Elts))
 			for i, element := range e.Elts {
 				kve := element.(*ast.KeyValueExpr)
-				entries[i] = fmt.Sprintf(`["%s"]=%s`, c.translateImplicitConversionWithCloning(kve.Key, t.Key()), c.translateImplicitConversionWithCloning(kve.Value, t.Elem()))
+				entries[i] = fmt.Sprintf(`{%s, %s}`, c.translateImplicitConversionWithCloning(kve.Key, t.Key()), c.translateImplicitConversionWithCloning(kve.Value, t.Elem()))
 			}
 			joined := strings.Join(entries, ", ")
 			pp("joined = '%#v'", joined)
-			return c.formatExpr(`_gi_NewMap("%s", "%s", {%s})`, c.typeName(t.Key()), c.typeName(t.Elem()), joined)
+			return c.formatExpr(`_gi_NewMap("%s", "%s", %s, {%s})`, c.typeName(t.Key()), c.typeName(t.Elem()), typeKind(t.Key()), joined)
 
 			// return c.formatExpr("$makeMap(%s.keyFor, [%s])", c.typeName(t.Key()), strings.Join(entries, ", "))
 		case *types.Struct:
@@ -375,15 +378,12 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			}
 
 		case token.ARROW:
-			call := &ast.CallExpr{
-				Fun:  c.newIdent("$recv", types.NewSignature(nil, types.NewTuple(types.NewVar(0, nil, "", t)), types.NewTuple(types.NewVar(0, nil, "", exprType), types.NewVar(0, nil, "", types.Typ[types.Bool])), false)),
-				Args: []ast.Expr{e.X},
-			}
-			c.Blocking[call] = true
+			// __gi_recv in goroutines.lua returns (value, ok).
 			if _, isTuple := exprType.(*types.Tuple); isTuple {
-				return c.formatExpr("%e", call)
+				return c.formatExpr("__gi_recv(%e)", e.X)
 			}
-			return c.formatExpr("%e[0]", call)
+			// parenthesize to keep just the value.
+			return c.formatExpr("(__gi_recv(%e))", e.X)
 		}
 
 		basic := t.Underlying().(*types.Basic)
@@ -394,20 +394,16 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			return tx
 		case token.SUB:
 			switch {
-			case is64Bit(basic):
-				return c.formatExpr("new %1s(-%2h, -%2l)", c.typeName(t), e.X)
 			case isComplex(basic):
-				return c.formatExpr("new %1s(-%2r, -%2i)", c.typeName(t), e.X)
-			case isUnsigned(basic):
+				return c.formatExpr("-%e", e.X)
+			case isInteger(basic):
+				// -(-128) is -128 for an int8.
 				return c.fixNumber(c.formatExpr("-%e", e.X), basic)
 			default:
 				return c.formatExpr("-%e", e.X)
 			}
 		case token.XOR:
-			if is64Bit(basic) {
-				return c.formatExpr("new %1s(~%2h, ~%2l >>> 0)", c.typeName(t), e.X)
-			}
-			return c.fixNumber(c.formatExpr("~%e", e.X), basic)
+			return c.fixNumber(c.formatExpr("__gi_bnot(%e)", e.X), basic)
 		case token.NOT:
 			return c.formatExpr(" not %e", e.X)
 		default:
@@ -469,13 +465,11 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			if isComplex(basic) {
 				switch e.Op {
 				case token.EQL:
-					return c.formatExpr("(%1r == %2r && %1i == %2i)", e.X, e.Y)
-				case token.ADD, token.SUB:
-					return c.formatExpr("new %3s(%1r %4t %2r, %1i %4t %2i)", e.X, e.Y, c.typeName(t), e.Op)
-				case token.MUL:
-					return c.formatExpr("new %3s(%1r * %2r - %1i * %2i, %1r * %2i + %1i * %2r)", e.X, e.Y, c.typeName(t))
-				case token.QUO:
-					return c.formatExpr("$divComplex(%e, %e)", e.X, e.Y)
+					return c.formatParenExpr("%e == %e", e.X, e.Y)
+				case token.ADD, token.SUB, token.MUL, token.QUO:
+					// the prelude's complex metatypes do the work,
+					// and keep the operands' precision.
+					return c.formatParenExpr("%e %t %e", e.X, e.Op, e.Y)
 				default:
 					panic(e.Op)
 				}
@@ -493,58 +487,38 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 				return c.fixNumber(xx, basic)
 			case token.MUL:
 				switch basic.Kind() {
-				case types.Int32, types.Int:
-					//return c.formatParenExpr("$imul(%e, %e)", e.X, e.Y)
-					return c.formatParenExpr("(%e * %e)", e.X, e.Y)
-				case types.Uint32, types.Uintptr:
-					//return c.formatParenExpr("$imul(%e, %e) >>> 0", e.X, e.Y)
+				case types.Int, types.Uint, types.Uintptr:
 					return c.formatParenExpr("(%e * %e)", e.X, e.Y)
 				}
 				return c.fixNumber(c.formatExpr("%e * %e", e.X, e.Y), basic)
 			case token.QUO:
 				if isInteger(basic) {
-					// cut off decimals
-					// shift := ">>"
-					//if isUnsigned(basic) {
-					//	shift = ">>>"
-					//}
-
-					// jea:
-					return c.formatExpr(`__integerByZeroCheck(%1e / %2e)`, e.X, e.Y)
-					// return c.formatExpr(`(%1s = %2e / %3e, (%1s == %1s && %1s ~= 1/0 && %1s ~= -1/0) ? %1s %4s 0 : error("integer divide by zero"))`, c.newVariable("_q"), e.X, e.Y, shift)
+					// jea: truncates, and panics on a zero divisor.
+					return c.fixNumber(c.formatExpr("__gi_div(%e, %e)", e.X, e.Y), basic)
 				}
 				if basic.Kind() == types.Float32 {
 					return c.fixNumber(c.formatExpr("%e / %e", e.X, e.Y), basic)
 				}
 				return c.formatExpr("%e / %e", e.X, e.Y)
 			case token.REM:
-				return c.formatExpr(`__integerByZeroCheck(%1e %% %2e)`, e.X, e.Y)
-			case token.SHL, token.SHR:
-				op := e.Op.String()
-				if e.Op == token.SHR && isUnsigned(basic) {
-					op = ">>>"
-				}
-				if v := c.p.Types[e.Y].Value; v != nil {
-					i, _ := constant.Uint64Val(constant.ToInt(v))
-					if i >= 32 {
-						return c.formatExpr("0")
-					}
-					return c.fixNumber(c.formatExpr("%e %s %s", e.X, op, strconv.FormatUint(i, 10)), basic)
-				}
-				if e.Op == token.SHR && !isUnsigned(basic) {
-					return c.fixNumber(c.formatParenExpr("%e >> $min(%f, 31)", e.X, e.Y), basic)
-				}
-				y := c.newVariable("y")
-				return c.fixNumber(c.formatExpr("(%s = %f, %s < 32 ? (%e %s %s) : 0)", y, e.Y, y, e.X, op, y), basic)
-			case token.AND, token.OR:
+				return c.fixNumber(c.formatExpr("__gi_rem(%e, %e)", e.X, e.Y), basic)
+			case token.SHL:
+				return c.fixNumber(c.formatExpr("__gi_shl(%e, %e)", e.X, e.Y), basic)
+			case token.SHR:
+				// values of the narrower types are already in
+				// range, so shifting right cannot overflow them.
 				if isUnsigned(basic) {
-					return c.formatParenExpr("(%e %t %e) >>> 0", e.X, e.Op, e.Y)
+					return c.formatExpr("__gi_ushr(%e, %e)", e.X, e.Y)
 				}
-				return c.formatParenExpr("%e %t %e", e.X, e.Op, e.Y)
-			case token.AND_NOT:
-				return c.fixNumber(c.formatParenExpr("%e & ~%e", e.X, e.Y), basic)
+				return c.formatExpr("__gi_shr(%e, %e)", e.X, e.Y)
+			case token.AND:
+				return c.formatExpr("__gi_band(%e, %e)", e.X, e.Y)
+			case token.OR:
+				return c.formatExpr("__gi_bor(%e, %e)", e.X, e.Y)
 			case token.XOR:
-				return c.fixNumber(c.formatParenExpr("%e ^ %e", e.X, e.Y), basic)
+				return c.formatExpr("__gi_bxor(%e, %e)", e.X, e.Y)
+			case token.AND_NOT:
+				return c.formatExpr("__gi_bandnot(%e, %e)", e.X, e.Y)
 			default:
 				panic(e.Op)
 			}
@@ -631,12 +605,8 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			if typesutil.IsJsObject(c.p.TypeOf(e.Index)) {
 				c.p.errList = append(c.p.errList, types.Error{Fset: c.p.fileSet, Pos: e.Index.Pos(), Msg: "cannot use js.Object as map key"})
 			}
+			// jea: the map hashes the key itself; see map.lua.
 			key := fmt.Sprintf("%s", c.translateImplicitConversion(e.Index, t.Key()))
-			pp("t.Key()='%T'/%#v", t.Key(), t.Key())
-			switch t.Key().(type) {
-			case *types.Basic:
-				key = `"` + key + `"`
-			}
 			//key := fmt.Sprintf("%s.keyFor(%s)", c.typeName(t.Key()), c.translateImplicitConversion(e.Index, t.Key()))
 			if _, isTuple := exprType.(*types.Tuple); isTuple {
 				return c.formatExpr(` %1e('get', %2s, %3e) `, e.X, key, c.zeroValue(t.Elem()))
@@ -858,6 +828,10 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 					}
 				}
 
+				if typesutil.IsLuaObject(declaredFuncRecv) {
+					return c.translateLuaObjectCall(e, sel.Obj().Name(), recv)
+				}
+
 				methodName := sel.Obj().Name()
 				if reservedKeywords[methodName] {
 					methodName += "_" // jea, was "$"
@@ -949,6 +923,9 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			if typesutil.IsJsObject(exprType) {
 				return c.formatExpr("null")
 			}
+			if typesutil.IsLuaObject(exprType) {
+				return c.formatExpr("nil")
+			}
 			switch t := exprType.Underlying().(type) {
 			case *types.Basic:
 				if t.Kind() != types.UnsafePointer {
@@ -958,13 +935,13 @@ func (c *funcContext) translateExpr(expr ast.Expr, desiredType types.Type) (xprn
 			case *types.Slice, *types.Pointer:
 				return c.formatExpr("%s.__nil", c.typeName(exprType))
 			case *types.Chan:
-				return c.formatExpr("$chanNil")
+				return c.formatExpr("__gi_chanNil")
 			case *types.Map:
 				return c.formatExpr("false")
 			case *types.Interface:
 				return c.formatExpr("nil")
 			case *types.Signature:
-				return c.formatExpr("$throwNilPointerError")
+				return c.formatExpr("__gi_throwNilPointerError")
 			default:
 				panic(fmt.Sprintf("unexpected type: %T", t))
 			}
@@ -1004,21 +981,18 @@ func (c *funcContext) translateCall(e *ast.CallExpr, sig *types.Signature, fun *
 	// jea
 	//resumeCase := c.caseCounter
 	c.caseCounter++
-	returnVar := "$r"
-	if sig.Results().Len() != 0 {
-		returnVar = c.newVariable("_r")
+	if sig.Results().Len() == 0 {
+		c.Printf("%s(%s);", fun, strings.Join(args, ", "))
+		return c.formatExpr("")
 	}
+	returnVar := c.newVariable("_r")
 	// jea
 	c.Printf(" %[1]s = %[2]s(%[3]s);", returnVar, fun, strings.Join(args, ", "))
 	// jea debug:
 	//c.Printf("/*jea expressions.go:873*/ %[1]s = %[2]s(%[3]s);", returnVar, fun, strings.Join(args, ", "))
 
 	//c.Printf("%[1]s = %[2]s(%[3]s); /* */ $s = %[4]d; case %[4]d: if($c) { $c = false; %[1]s = %[1]s.$blk(); } if (%[1]s && %[1]s.$blk !== undefined) { break s; }", returnVar, fun, strings.Join(args, ", "), resumeCase)
-	if sig.Results().Len() != 0 {
-		return c.formatExpr("%s", returnVar)
-	}
-	return c.formatExpr("")
-
+	return c.formatExpr("%s", returnVar)
 }
 
 func (c *funcContext) makeReceiver(e *ast.SelectorExpr) *expression {
@@ -1092,16 +1066,15 @@ func (c *funcContext) translateBuiltin(name string, sig *types.Signature, args [
 				t, zero, args[1])
 
 		case *types.Map:
-			if len(args) == 2 && c.p.Types[args[1]].Value == nil {
-				return c.formatExpr(`((%1f < 0 || %1f > 2147483647) ? error("makemap: size out of range") : {})`, args[1])
-			}
-			return c.formatExpr(`_gi_NewMap("%s", "%s", {})`, c.typeName(argType.Key()), c.typeName(argType.Elem()))
+			// jea: the size hint is of no use to a Lua table.
+			return c.formatExpr(`_gi_NewMap("%s", "%s", %s, {})`, c.typeName(argType.Key()), c.typeName(argType.Elem()), typeKind(argType.Key()))
 		case *types.Chan:
 			length := "0"
 			if len(args) == 2 {
 				length = c.formatExpr("%f", args[1]).String()
 			}
-			return c.formatExpr("new $Chan(%s, %s)", c.typeName(c.p.TypeOf(args[0]).Underlying().(*types.Chan).Elem()), length)
+			elem := c.p.TypeOf(args[0]).Underlying().(*types.Chan).Elem()
+			return c.formatExpr("__gi_NewChan(%s, %e)", length, c.zeroValue(elem))
 		default:
 			panic(fmt.Sprintf("Unhandled make type: %T\n", argType))
 		}
@@ -1117,15 +1090,17 @@ func (c *funcContext) translateBuiltin(name string, sig *types.Signature, args [
 		case *types.Map:
 			return c.formatExpr(" #%e", args[0])
 		case *types.Chan:
-			return c.formatExpr("%e.$buffer.length", args[0])
+			return c.formatExpr("__gi_chanLen(%e)", args[0])
 		// length of array is constant
 		default:
 			panic(fmt.Sprintf("Unhandled len type: %T\n", argType))
 		}
 	case "cap":
 		switch argType := c.p.TypeOf(args[0]).Underlying().(type) {
-		case *types.Slice, *types.Chan:
+		case *types.Slice:
 			return c.formatExpr("%e.$capacity", args[0])
+		case *types.Chan:
+			return c.formatExpr("__gi_chanCap(%e)", args[0])
 		case *types.Pointer:
 			return c.formatExpr("(%e, %d)", args[0], argType.Elem().(*types.Array).Len())
 		// capacity of array is constant
@@ -1154,15 +1129,15 @@ func (c *funcContext) translateBuiltin(name string, sig *types.Signature, args [
 		return c.formatExpr("print(%s)", strings.Join(c.translateExprSlice(args, nil), ", "))
 	case "complex":
 		argStr := c.translateArgs(sig, args, ellipsis)
-		return c.formatExpr("new %s(%s, %s)", c.typeName(sig.Results().At(0).Type()), argStr[0], argStr[1])
+		return c.formatExpr("%s(%s, %s)", sig.Results().At(0).Type().Underlying().(*types.Basic).Name(), argStr[0], argStr[1])
 	case "real":
-		return c.formatExpr("%e.$real", args[0])
+		return c.formatExpr("%e.re", args[0])
 	case "imag":
-		return c.formatExpr("%e.$imag", args[0])
+		return c.formatExpr("%e.im", args[0])
 	case "recover":
 		return c.formatExpr("recover()")
 	case "close":
-		return c.formatExpr(`$close(%e)`, args[0])
+		return c.formatExpr(`__gi_close(%e)`, args[0])
 	default:
 		panic(fmt.Sprintf("Unhandled builtin: %s\n", name))
 	}
@@ -1185,6 +1160,73 @@ func (c *funcContext) identifierConstant(expr ast.Expr) (string, bool) {
 	return s, true
 }
 
+// translateLuaObjectCall compiles a call to the method
+// name of *lua.Object, on recv, into the Lua table
+// operation it stands for. The helpers are in luaobj.lua.
+func (c *funcContext) translateLuaObjectCall(e *ast.CallExpr, name string, recv *expression) *expression {
+	luaArgs := func(args []ast.Expr) string {
+		if e.Ellipsis.IsValid() {
+			return c.formatExpr("__gi_toLuaArgs(%e)", args[len(args)-1]).String()
+		}
+		s := make([]string, len(args))
+		for i, arg := range args {
+			s[i] = c.toLua(arg)
+		}
+		return strings.Join(s, ", ")
+	}
+	switch name {
+	case "Get":
+		return c.formatExpr("%s[%e]", recv, e.Args[0])
+	case "Set":
+		return c.formatExpr("%s[%e] = %s", recv, e.Args[0], c.toLua(e.Args[1]))
+	case "Delete":
+		return c.formatExpr("%s[%e] = nil", recv, e.Args[0])
+	case "Len":
+		return c.formatExpr("(#%s + 0LL)", recv)
+	case "Index":
+		return c.formatExpr("%s[%s]", recv, c.toLua(e.Args[0]))
+	case "SetIndex":
+		return c.formatExpr("%s[%s] = %s", recv, c.toLua(e.Args[0]), c.toLua(e.Args[1]))
+	case "Call":
+		return c.formatExpr("%s[%e](%s)", recv, e.Args[0], luaArgs(e.Args[1:]))
+	case "Invoke":
+		return c.formatExpr("%s(%s)", recv, luaArgs(e.Args))
+	case "Bool":
+		return c.formatExpr("(not not %s)", recv)
+	case "String":
+		return c.formatExpr("__gi_luaString(%s)", recv)
+	case "Int", "Int64":
+		return c.formatExpr("__gi_luaInt64(%s)", recv)
+	case "Float":
+		return c.formatExpr("__gi_luaFloat(%s)", recv)
+	case "Interface":
+		return recv
+	default:
+		panic("Invalid lua package object: " + name)
+	}
+}
+
+// toLua translates arg, a Go value on its way into
+// Lua, to the plain Lua value it should arrive as:
+// integers, which are int64 cdata, become numbers.
+func (c *funcContext) toLua(arg ast.Expr) string {
+	if val := c.p.Types[arg].Value; val != nil && val.Kind() == constant.Int {
+		return val.ExactString()
+	}
+	switch t := c.p.TypeOf(arg).Underlying().(type) {
+	case *types.Basic:
+		if t.Kind() == types.UntypedNil {
+			return "nil"
+		}
+		if isInteger(t) {
+			return c.formatExpr("tonumber(%e)", arg).String()
+		}
+	case *types.Interface:
+		return c.formatExpr("__gi_toLua(%e)", arg).String()
+	}
+	return c.translateExpr(arg, nil).String()
+}
+
 func (c *funcContext) translateExprSlice(exprs []ast.Expr, desiredType types.Type) []string {
 	parts := make([]string, len(exprs))
 	for i, expr := range exprs {
@@ -1220,28 +1262,17 @@ func (c *funcContext) translateConversion(expr ast.Expr, desiredType types.Type)
 		case isInteger(t):
 			basicExprType := exprType.Underlying().(*types.Basic)
 			switch {
-			case is64Bit(t):
-				if !is64Bit(basicExprType) {
-					if basicExprType.Kind() == types.Uintptr { // this might be an Object returned from reflect.Value.Pointer()
-						return c.formatExpr("new %1s(0, %2e.constructor == Number ? %2e : 1)", c.typeName(desiredType), expr)
-					}
-					return c.formatExpr("new %s(0, %e)", c.typeName(desiredType), expr)
-				}
-				return c.formatExpr("new %1s(%2h, %2l)", c.typeName(desiredType), expr)
-			case is64Bit(basicExprType):
-				if !isUnsigned(t) && !isUnsigned(basicExprType) {
-					return c.fixNumber(c.formatParenExpr("%1l + ((%1h >> 31) * 4294967296)", expr), t)
-				}
-				return c.fixNumber(c.formatExpr("%s.$low", c.translateExpr(expr, nil)), t)
-			case isFloat(basicExprType):
-				// jea
-				//return c.formatParenExpr("%e >> 0", expr)
-				return c.formatParenExpr("int(%e)", expr)
 			case types.Identical(exprType, types.Typ[types.UnsafePointer]):
 				return c.translateExpr(expr, nil)
+			case isFloat(basicExprType):
+				// jea: truncates toward zero.
+				return c.formatExpr("__gi_%s(%e)", intWrapName(t), expr)
+			case isUnsigned(t) == isUnsigned(basicExprType) && intWidth(t) >= intWidth(basicExprType):
+				// the value fits as it is.
+				return c.translateExpr(expr, nil)
 			default:
-				// 201 not here
-				return c.fixNumber(c.translateExpr(expr, nil), t)
+				// narrow, or change between int64 and uint64 cdata.
+				return c.formatExpr("__gi_%s(%e)", intWrapName(t), expr)
 			}
 		case isFloat(t):
 			if t.Kind() == types.Float32 && exprType.Underlying().(*types.Basic).Kind() == types.Float64 {
@@ -1249,7 +1280,7 @@ func (c *funcContext) translateConversion(expr ast.Expr, desiredType types.Type)
 			}
 			return c.formatExpr("tonumber(%f)", expr)
 		case isComplex(t):
-			return c.formatExpr("new %1s(%2r, %2i)", c.typeName(desiredType), expr)
+			return c.formatExpr("__gi_%s(%e)", t.Name(), expr)
 		case isString(t):
 			value := c.translateExpr(expr, nil)
 			switch et := exprType.Underlying().(type) {
@@ -1382,7 +1413,7 @@ func (c *funcContext) translateImplicitConversionWithCloning(expr ast.Expr, desi
 			if isAnon {
 				return c.formatExpr(`__gi_clone2(%e, %s)`, expr, c.typeName(anonType.Type()))
 			} else {
-				return c.formatExpr(`__gi_clone2(%e, %s)`, expr, typName)
+				return c.formatExpr(`__gi_clone2(%e, __type__%s)`, expr, typName)
 			}
 
 		}
@@ -1440,7 +1471,12 @@ func (c *funcContext) translateImplicitConversion(expr ast.Expr, desiredType typ
 		pp("!isWrapped for exprType='%#v'", exprType)
 		if _, isStruct := exprType.Underlying().(*types.Struct); isStruct {
 			pp("YYY 7 translateImplicitConversion exiting early")
-			return c.formatExpr("%1e.__constructor.__elem(%1e)", expr)
+			// an interface holds a copy of the struct.
+			typName, isAnon, anonType, _ := c.typeNameWithAnonInfo(exprType)
+			if isAnon {
+				return c.formatExpr("__gi_clone2(%e, %s)", expr, c.typeName(anonType.Type()))
+			}
+			return c.formatExpr("__gi_clone2(%e, __type__%s)", expr, typName)
 		}
 	}
 	pp("bottom of expressions.go:1250 calling c.translateExpr, for expr='%#v', exprType='%v'", expr, exprType)
@@ -1516,29 +1552,13 @@ func (c *funcContext) fixNumber(value *expression, basic *types.Basic) (xprn *ex
 		pp("returning from fixNumber with xprn='%s'", x2s(xprn))
 	}()
 	switch basic.Kind() {
-	case types.Int8:
-		// jea
-		fallthrough
-		//return c.formatParenExpr("%s << 24 >> 24", value)
-	case types.Uint8:
-		// jea
-		fallthrough
-		//return c.formatParenExpr("%s << 24 >>> 24", value)
-	case types.Int16:
-		// jea
-		fallthrough
-		//return c.formatParenExpr("%s << 16 >> 16", value)
-	case types.Uint16:
-		// jea
-		fallthrough
-		//return c.formatParenExpr("%s << 16 >>> 16", value)
-	case types.Uint32, types.Uint, types.Uintptr:
-		// jea
-		fallthrough
-		//return c.formatParenExpr("%s >>> 0", value)
-	case types.Int32, types.Int, types.Int64, types.UntypedInt:
-		// jea
-		//return c.formatParenExpr("%s >> 0", value)
+	case types.Int8, types.Uint8, types.Int16, types.Uint16, types.Int32, types.Uint32:
+		// jea: all integers live in 64-bit cdata, so the
+		// narrower types must be wrapped back into range.
+		// See int64.lua.
+		return c.formatExpr("__gi_%s(%s)", intWrapName(basic), value)
+	case types.Int, types.Int64, types.Uint, types.Uint64, types.Uintptr, types.UntypedInt:
+		// jea: 64-bit arithmetic wraps by itself.
 		return c.formatParenExpr("%s", value)
 	case types.Float32:
 		// jea:
@@ -323,3 +323,110 @@ switch v.(type) {
 		LuaMustBool(vm, "asUnNil", true)
 	})
 }
+
+func Test203EmbeddingPromotesMethodsIntoInterfaces(t *testing.T) {
+
+	cv.Convey(`a struct satisfies an interface with methods promoted from its embedded structs, pointers and interfaces, by Go's rules: the shallowest name wins, one of two at the same depth is none, and a field hides a method`, t, func() {
+
+		code := `
+type A struct{ X int }
+func (a A) Get() int { return a.X }
+func (a *A) Inc() { a.X++ }
+func (a A) Name() string { return "A" }
+
+type Getter interface{ Get() int }
+type Incer interface{ Inc() }
+type Namer interface{ Name() string }
+
+type B struct{ A }
+type C struct{ *A }
+type D struct{ B }
+
+d := D{B{A{10}}}
+var gd Getter = d
+dg := gd.Get()
+var id Incer = &d
+id.Inc()
+dx := d.X
+
+a := &A{1}
+var ic Incer = C{a}
+ic.Inc()
+ax := a.X
+
+var x interface{} = B{A{5}}
+_, okB := x.(Getter)
+
+// an embedded interface brings its methods along.
+type E struct {
+	Getter
+	N int
+}
+e := E{A{4}, 1}
+eg := e.Get()
+var ge Getter = e
+eg2 := ge.Get()
+x = E{A{6}, 0}
+_, okE := x.(Getter)
+
+// a method of the outer struct wins.
+type S struct{ A }
+func (s S) Name() string { return "S" }
+var ns Namer = S{}
+sn := ns.Name()
+
+type P struct{}
+func (P) M() int { return 1 }
+type Q struct{}
+func (Q) M() int { return 2 }
+type Mer interface{ M() int }
+
+type R struct {
+	P
+	Q
+}
+type W struct{ Q }
+type U struct {
+	P
+	W
+}
+type V struct {
+	P
+	M int
+}
+x = R{}
+_, okR := x.(Mer)
+var mu Mer = U{}
+um := mu.M()
+x = V{}
+_, okV := x.(Mer)
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		translation := inc.Tr([]byte(code))
+		fmt.Printf("\n translation='%s'\n", translation)
+
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustInt64(vm, "dg", 10)
+		LuaMustInt64(vm, "dx", 11)
+		LuaMustInt64(vm, "ax", 2)
+		LuaMustBool(vm, "okB", true)
+
+		LuaMustInt64(vm, "eg", 4)
+		LuaMustInt64(vm, "eg2", 4)
+		LuaMustBool(vm, "okE", true)
+
+		LuaMustString(vm, "sn", "S")
+
+		// P.M and Q.M are both at depth 1 in R.
+		LuaMustBool(vm, "okR", false)
+		// P.M is shallower than W.Q.M in U.
+		LuaMustInt64(vm, "um", 1)
+		// V's own field M hides P.M.
+		LuaMustBool(vm, "okV", false)
+	})
+}
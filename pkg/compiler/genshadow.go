@@ -1,8 +1,12 @@
 package compiler
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/gijit/gi/pkg/importer"
 	"github.com/gijit/gi/pkg/types"
@@ -28,20 +32,29 @@ func GenShadowImport(importPath, dirForVendor, residentPkg, outDir string) error
 		return err
 	}
 
-	pkgName := pkg.Name()
-
-	o, err := os.Create(outDir + string(os.PathSeparator) + pkgName + ".genimp.go")
+	o, err := os.Create(outDir + string(os.PathSeparator) + pkg.Name() + ".genimp.go")
 	if err != nil {
 		return err
 	}
+	defer o.Close()
+	return genShadow(o, pkg, importPath, residentPkg)
+}
 
-	fmt.Fprintf(o, `package shadow_%s
+// genShadow writes the shadow of pkg, which was
+// imported from importPath, to w.
+func genShadow(w io.Writer, pkg *types.Package, importPath, residentPkg string) error {
+
+	pkgName := pkg.Name()
 
-import "%s"
+	// the body goes first into o, so that we know
+	// what the adapters need imported.
+	o := &bytes.Buffer{}
+	imports := map[string]bool{importPath: true}
 
+	fmt.Fprintf(o, `
 var Pkg = make(map[string]interface{})
 func init() {
-`, residentPkg, importPath)
+`)
 
 	scope := pkg.Scope()
 	nms := scope.Names()
@@ -97,6 +110,7 @@ func init() {
 				switch obj.(type) {
 				case *types.TypeName:
 					ifaceTemplate(o, obj, nm, pkgName, oty, under, &atEnd)
+					adapterTemplate(o, pkg, nm, under.(*types.Interface), imports, &atEnd)
 				case *types.Var:
 					direct(o, nm, pkgName)
 				default:
@@ -114,7 +128,36 @@ func init() {
 	for _, s := range atEnd {
 		fmt.Fprintf(o, "%s\n", s)
 	}
-	return nil
+
+	// the standard library first, then the rest.
+	var std, other []string
+	for path := range imports {
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	fmt.Fprintf(w, "package shadow_%s\n\n", residentPkg)
+	if len(imports) == 1 {
+		fmt.Fprintf(w, "import %q\n", append(std, other...)[0])
+	} else {
+		fmt.Fprintf(w, "import (\n")
+		for _, path := range std {
+			fmt.Fprintf(w, "\t%q\n", path)
+		}
+		if len(std) > 0 && len(other) > 0 {
+			fmt.Fprintf(w, "\n")
+		}
+		for _, path := range other {
+			fmt.Fprintf(w, "\t%q\n", path)
+		}
+		fmt.Fprintf(w, ")\n")
+	}
+	_, err := o.WriteTo(w)
+	return err
 }
 
 /* make a function like:
@@ -150,7 +193,7 @@ func %s(x interface{}) %s.%s {
 	return
 }
 
-func direct(o *os.File, nm, pkgName string) {
+func direct(o io.Writer, nm, pkgName string) {
 	fmt.Fprintf(o, "    Pkg[\"%s\"] = %s.%s\n", nm, pkgName, nm)
 }
 
@@ -159,7 +202,7 @@ func GijitShadow_NewStruct_PipeReader() *io.PipeReader {
 	return &io.PipeReader{}
 }
 */
-func structTemplate(o *os.File, obj types.Object, nm, pkgName string, oty, under types.Type, atEnd *[]string) {
+func structTemplate(o io.Writer, obj types.Object, nm, pkgName string, oty, under types.Type, atEnd *[]string) {
 	// example from "io":
 	/*
 		type PipeReader struct {
@@ -179,7 +222,7 @@ func GijitShadow_NewStruct_%s() *%s.%s {
 
 }
 
-func ifaceTemplate(o *os.File, obj types.Object, nm, pkgName string, oty, under types.Type, atEnd *[]string) {
+func ifaceTemplate(o io.Writer, obj types.Object, nm, pkgName string, oty, under types.Type, atEnd *[]string) {
 
 	//pp("ifaceTemplate:: we see Named '%s'\n. oty:'%#v',\n under:'%#v',\n, obj='%#v', \n", nm, oty, under, obj)
 
@@ -188,3 +231,195 @@ func ifaceTemplate(o *os.File, obj types.Object, nm, pkgName string, oty, under
 	fmt.Fprintf(o, "    Pkg[\"%s\"] = %s\n", nm, funcName2)
 	//fmt.Fprintf(o, "    Pkg[\"%s\"] = %s\n", nm, funcName1)
 }
+
+/* make an adapter, so that a value typed in at the
+REPL can be used as the interface, like:
+
+type GijitShadow_Adapter_Reader struct {
+	obj *luar.LuaObject
+}
+
+func (a *GijitShadow_Adapter_Reader) Read(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Read", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+and register it with luar, which picks it when
+converting such a value to io.Reader.
+
+Interfaces that cannot be implemented outside of
+their package, or whose methods mention types that
+cannot be named there, get no adapter.
+*/
+func adapterTemplate(o io.Writer, pkg *types.Package, nm string, iface *types.Interface, imports map[string]bool, atEnd *[]string) {
+	if iface.NumMethods() == 0 {
+		return
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		if !m.Exported() || !nameableOutside(m.Type(), map[types.Type]bool{}) {
+			return
+		}
+	}
+	used := map[string]bool{}
+	qual := func(p *types.Package) string {
+		used[p.Path()] = true
+		return p.Name()
+	}
+
+	adapter := "GijitShadow_Adapter_" + nm
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `
+// %[1]s implements %[2]s.%[3]s by calling
+// the methods of a value defined at the REPL.
+type %[1]s struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_%[3]s(obj *luar.LuaObject) interface{} {
+	return &%[1]s{obj: obj}
+}
+
+func (a *%[1]s) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+`, adapter, pkg.Name(), nm)
+
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		sig := m.Type().(*types.Signature)
+		var params, args, results, resultPtrs []string
+		for j := 0; j < sig.Params().Len(); j++ {
+			t := sig.Params().At(j).Type()
+			ts := types.TypeString(t, qual)
+			if sig.Variadic() && j == sig.Params().Len()-1 {
+				ts = "..." + types.TypeString(t.(*types.Slice).Elem(), qual)
+			}
+			params = append(params, fmt.Sprintf("p%d %s", j, ts))
+			args = append(args, fmt.Sprintf(", p%d", j))
+		}
+		for j := 0; j < sig.Results().Len(); j++ {
+			results = append(results, fmt.Sprintf("r%d %s", j, types.TypeString(sig.Results().At(j).Type(), qual)))
+			resultPtrs = append(resultPtrs, fmt.Sprintf("&r%d", j))
+		}
+		res := ""
+		if len(results) > 0 {
+			res = " (" + strings.Join(results, ", ") + ")"
+		}
+		fmt.Fprintf(&b, `
+func (a *%s) %s(%s)%s {
+	if err := a.obj.CallMethod(%q, []interface{}{%s}%s); err != nil {
+		panic(err)
+	}
+	return
+}
+`, adapter, m.Name(), strings.Join(params, ", "), res,
+			m.Name(), strings.Join(resultPtrs, ", "), strings.Join(args, ""))
+	}
+	*atEnd = append(*atEnd, b.String())
+
+	for path := range used {
+		imports[path] = true
+	}
+	imports["reflect"] = true
+	imports["github.com/glycerine/luar"] = true
+	fmt.Fprintf(o, "    luar.RegisterAdapter(reflect.TypeOf((*%s.%s)(nil)).Elem(), GijitShadow_NewAdapter_%s)\n", pkg.Name(), nm, nm)
+}
+
+// nameableOutside reports whether t can be written
+// down in a package other than its own.
+func nameableOutside(t types.Type, seen map[types.Type]bool) bool {
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *types.Basic:
+		return true
+	case *types.Named:
+		return t.Obj().Exported() || t.Obj().Pkg() == nil
+	case *types.Pointer:
+		return nameableOutside(t.Elem(), seen)
+	case *types.Slice:
+		return nameableOutside(t.Elem(), seen)
+	case *types.Array:
+		return nameableOutside(t.Elem(), seen)
+	case *types.Map:
+		return nameableOutside(t.Key(), seen) && nameableOutside(t.Elem(), seen)
+	case *types.Chan:
+		return nameableOutside(t.Elem(), seen)
+	case *types.Signature:
+		for _, tup := range []*types.Tuple{t.Params(), t.Results()} {
+			for i := 0; i < tup.Len(); i++ {
+				if !nameableOutside(tup.At(i).Type(), seen) {
+					return false
+				}
+			}
+		}
+		return true
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if !t.Field(i).Exported() || !nameableOutside(t.Field(i).Type(), seen) {
+				return false
+			}
+		}
+		return true
+	case *types.Interface:
+		for i := 0; i < t.NumMethods(); i++ {
+			if !t.Method(i).Exported() || !nameableOutside(t.Method(i).Type(), seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+//
+// GenShadowBundle writes the source of a main package
+// that registers the shadows of importPaths, using
+// RegisterShadowPackage, and then runs the gi REPL.
+// The shadows themselves are expected to have been
+// generated already by GenShadowImport, under
+// shadowPrefix + "/" + importPath. Building that main
+// gives a gi that can import those packages, without
+// any change to GiImportFunc.
+//
+func GenShadowBundle(w io.Writer, importPaths []string, shadowPrefix string) error {
+
+	fmt.Fprintf(w, `// Code generated by gen-gijit-shadow-import -bundle. DO NOT EDIT.
+
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/gijit/gi/pkg/compiler"
+
+`)
+	for i, path := range importPaths {
+		fmt.Fprintf(w, "\tshadow%d %q\n", i, shadowPrefix+"/"+path)
+	}
+	fmt.Fprintf(w, `)
+
+func main() {
+`)
+	for i, path := range importPaths {
+		fmt.Fprintf(w, "\tcompiler.RegisterShadowPackage(%q, shadow%d.Pkg, nil)\n", path, i)
+	}
+	_, err := fmt.Fprintf(w, `
+	myflags := flag.NewFlagSet("gi", flag.ExitOnError)
+	cfg := compiler.NewGIConfig()
+	cfg.DefineFlags(myflags)
+	myflags.Parse(os.Args[1:])
+	err := cfg.ValidateConfig()
+	if err != nil {
+		log.Fatalf("gi command line flag error: '%%s'", err)
+	}
+	cfg.LuajitMain()
+}
+`)
+	return err
+}
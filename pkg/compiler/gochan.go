@@ -0,0 +1,179 @@
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+
+	golua "github.com/glycerine/golua/lua"
+	"github.com/glycerine/luar"
+)
+
+// Native Go channels, such as the one returned by
+// time.After, reach Lua as luar userdata proxies.
+// The channel runtime in goroutines.lua tells them
+// apart from its own channels by their metatable
+// (luar replaces the global type() function, so we
+// can't just ask for "userdata"), and calls the
+// helpers below to operate on them.
+//
+// All but __gi_goChanSelect are non-blocking, so
+// that the cooperative scheduler keeps running the
+// other goroutines while some wait on Go.
+// __gi_goChanSelect blocks, and is only used when
+// nothing at all in Lua can make progress.
+
+func registerGoChanHelpers(vm *golua.State) {
+	vm.Register("__gi_goChanTryRecv", goChanTryRecv)
+	vm.Register("__gi_goChanTrySend", goChanTrySend)
+	vm.Register("__gi_goChanClose", goChanClose)
+	vm.Register("__gi_goChanLen", goChanLen)
+	vm.Register("__gi_goChanCap", goChanCap)
+	vm.Register("__gi_goChanSelect", goChanSelect)
+}
+
+// goChanAt returns the Go channel proxied at stack
+// position idx.
+func goChanAt(L *golua.State, idx int) reflect.Value {
+	var ch interface{}
+	err := luar.LuaToGo(L, idx, &ch)
+	if err != nil {
+		L.RaiseError(fmt.Sprintf("expected a Go channel: %v", err))
+	}
+	v := reflect.ValueOf(ch)
+	if v.Kind() != reflect.Chan {
+		L.RaiseError(fmt.Sprintf("expected a Go channel, got %T", ch))
+	}
+	return v
+}
+
+// goChanValueAt converts the Lua value at idx into
+// something that can be sent on ch.
+func goChanValueAt(L *golua.State, idx int, ch reflect.Value) reflect.Value {
+	elem := ch.Type().Elem()
+	val := reflect.New(elem)
+	err := luar.LuaToGo(L, idx, val.Interface())
+	if err != nil {
+		L.RaiseError(fmt.Sprintf("channel requires %v value type", elem))
+	}
+	return val.Elem()
+}
+
+// goChanTryRecv(ch) returns ready, value, ok. When
+// ready is false, nothing was received.
+func goChanTryRecv(L *golua.State) int {
+	ch := goChanAt(L, 1)
+	val, ok := ch.TryRecv()
+	if !val.IsValid() {
+		// would block
+		L.PushBoolean(false)
+		return 1
+	}
+	L.PushBoolean(true)
+	luar.GoToLuaProxy(L, val)
+	L.PushBoolean(ok)
+	return 3
+}
+
+// goChanTrySend(ch, value) returns ready, closed. A
+// send on a closed channel reports closed == true, and
+// goroutines.lua turns that into the Go panic.
+func goChanTrySend(L *golua.State) int {
+	ch := goChanAt(L, 1)
+	val := goChanValueAt(L, 2, ch)
+	ready, closed := tryGoSend(ch, val)
+	L.PushBoolean(ready)
+	L.PushBoolean(closed)
+	return 2
+}
+
+func tryGoSend(ch, val reflect.Value) (ready, closed bool) {
+	defer func() {
+		if recover() != nil {
+			ready, closed = true, true
+		}
+	}()
+	ready = ch.TrySend(val)
+	return
+}
+
+// goChanClose(ch) returns the panic message, or nothing
+// when the close succeeded.
+func goChanClose(L *golua.State) (nret int) {
+	ch := goChanAt(L, 1)
+	defer func() {
+		if r := recover(); r != nil {
+			L.PushString(fmt.Sprintf("%v", r))
+			nret = 1
+		}
+	}()
+	ch.Close()
+	return 0
+}
+
+func goChanLen(L *golua.State) int {
+	L.PushInteger(int64(goChanAt(L, 1).Len()))
+	return 1
+}
+
+func goChanCap(L *golua.State) int {
+	L.PushInteger(int64(goChanAt(L, 1).Cap()))
+	return 1
+}
+
+// goChanSelect blocks until one of its cases can
+// proceed. Its arguments are triples of
+//
+//	"recv", ch, nil
+//	"send", ch, value
+//
+// and it returns the 0-based index of the chosen
+// case, then for a receive the value and ok flag; for
+// a send, whether the channel was closed on us.
+func goChanSelect(L *golua.State) int {
+	n := L.GetTop() / 3
+	cases := make([]reflect.SelectCase, n)
+	for i := 0; i < n; i++ {
+		ch := goChanAt(L, 3*i+2)
+		switch L.ToString(3*i + 1) {
+		case "recv":
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: ch}
+		case "send":
+			cases[i] = reflect.SelectCase{
+				Dir:  reflect.SelectSend,
+				Chan: ch,
+				Send: goChanValueAt(L, 3*i+3, ch),
+			}
+		default:
+			L.RaiseError(fmt.Sprintf("__gi_goChanSelect: bad direction '%s'", L.ToString(3*i+1)))
+		}
+	}
+	chosen, val, ok, closed := goSelect(cases)
+	L.PushInteger(int64(chosen))
+	if cases[chosen].Dir == reflect.SelectSend {
+		L.PushBoolean(closed)
+		return 2
+	}
+	luar.GoToLuaProxy(L, val)
+	L.PushBoolean(ok)
+	return 3
+}
+
+func goSelect(cases []reflect.SelectCase) (chosen int, val reflect.Value, ok, closed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			// reflect.Select panics on a send to a closed
+			// channel without saying which one, and we can't
+			// probe the others without sending on them. Blame
+			// the first send case; the panic is the same.
+			for i, c := range cases {
+				if c.Dir == reflect.SelectSend {
+					chosen, closed = i, true
+					return
+				}
+			}
+			panic(r)
+		}
+	}()
+	chosen, val, ok = reflect.Select(cases)
+	return
+}
@@ -0,0 +1,76 @@
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/gijit/gi/pkg/muse"
+	"github.com/gijit/gi/pkg/types"
+	golua "github.com/glycerine/golua/lua"
+	"github.com/glycerine/luar"
+)
+
+// Numeric slices and arrays made at the REPL keep
+// their elements in Go memory, so that they pass to
+// Go functions without a copy. slice.lua asks for that
+// memory with __gi_goSliceAlloc, reaches it through
+// an FFI pointer, and gives it back with
+// __gi_goSliceRelease once the pointer is collected.
+// luar keeps the memory pinned in between, and
+// rebuilds the Go slice from its address.
+
+func registerGoSliceHelpers(vm *golua.State) {
+	vm.Register("__gi_goSliceAlloc", goSliceAlloc)
+	vm.Register("__gi_goSliceRelease", goSliceRelease)
+}
+
+// goElemTypes caches the reflect.Type for each of the
+// element kinds that slice.lua keeps in Go memory.
+var goElemTypes = make(map[string]reflect.Type)
+
+// goElemType returns the reflect.Type of the basic
+// Go type named kind, such as "float64".
+func goElemType(kind string) (reflect.Type, error) {
+	if rt, ok := goElemTypes[kind]; ok {
+		return rt, nil
+	}
+	obj := types.Universe.Lookup(kind)
+	if obj == nil {
+		return nil, fmt.Errorf("no basic Go type '%s'", kind)
+	}
+	if _, ok := obj.(*types.TypeName); !ok {
+		return nil, fmt.Errorf("'%s' is not a type", kind)
+	}
+	rt, err := muse.NewMuse().Pun(obj.Type())
+	if err != nil {
+		return nil, err
+	}
+	goElemTypes[kind] = rt
+	return rt, nil
+}
+
+// goSliceAlloc(kind, n) returns the address of n
+// zeroed elements of the Go type named kind, pinned
+// until released.
+func goSliceAlloc(L *golua.State) int {
+	kind := L.ToString(1)
+	n := int(L.ToNumber(2))
+	rt, err := goElemType(kind)
+	if err != nil {
+		L.RaiseError(fmt.Sprintf("__gi_goSliceAlloc: %v", err))
+	}
+	if n < 1 {
+		// so that even an empty slice has an address
+		// of its own.
+		n = 1
+	}
+	addr := luar.PinSlice(reflect.MakeSlice(reflect.SliceOf(rt), n, n))
+	L.PushNumber(float64(addr))
+	return 1
+}
+
+// goSliceRelease(addr) releases a goSliceAlloc.
+func goSliceRelease(L *golua.State) int {
+	luar.UnpinSlice(uintptr(L.ToNumber(1)))
+	return 0
+}
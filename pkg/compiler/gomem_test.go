@@ -0,0 +1,156 @@
+package compiler
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/parser"
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+	cv "github.com/glycerine/goconvey/convey"
+	"github.com/glycerine/luar"
+)
+
+func Test680NumericSlicesShareGoMemory(t *testing.T) {
+
+	cv.Convey(`numeric slices and arrays made at the REPL live in Go memory, reached from LuaJIT through the FFI, so Go functions get them without a copy, and see, and make, the same changes`, t, func() {
+
+		path := "example.com/gomem/vec"
+		src := `package vec
+
+func Scale(x []float64, k float64) {}
+func Sum(x []float64) float64 { return 0 }
+func SumInts(x []int) int { return 0 }
+func Trace(a [3]float64) float64 { return 0 }
+func Fill(f func(x []float64)) float64 { return 0 }
+`
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "vec.go", src, 0)
+		panicOn(err)
+		tpkg, _, err := (&types.Config{Sizes: sizes64}).Check(nil, nil, path, fset, []*ast.File{f}, nil, nil)
+		panicOn(err)
+
+		// where the elements were, as Go saw them.
+		var scaled, filled uintptr
+		RegisterShadowPackage(path, map[string]interface{}{
+			"Scale": func(x []float64, k float64) {
+				scaled = uintptr(unsafe.Pointer(&x[0]))
+				for i := range x {
+					x[i] *= k
+				}
+			},
+			"Sum": func(x []float64) (tot float64) {
+				for _, v := range x {
+					tot += v
+				}
+				return
+			},
+			"SumInts": func(x []int) (tot int) {
+				for _, v := range x {
+					tot += v
+				}
+				return
+			},
+			"Trace": func(a [3]float64) float64 { return a[0] + a[1] + a[2] },
+			"Fill": func(f func(x []float64)) float64 {
+				x := make([]float64, 3)
+				filled = uintptr(unsafe.Pointer(&x[0]))
+				f(x)
+				return x[0] + x[1] + x[2]
+			},
+		}, tpkg)
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		translation := inc.Tr([]byte(`
+import "example.com/gomem/vec"
+
+v := []float64{1, 2, 3, 4}
+vec.Scale(v, 10)
+v0 := v[0]
+v3 := v[3]
+`))
+		LuaRunAndReport(vm, string(translation))
+		LuaMustFloat64(vm, "v0", 10)
+		LuaMustFloat64(vm, "v3", 40)
+
+		// Go had the very memory that Lua indexes.
+		LuaRunAndReport(vm, `addr = rawget(v, _giPrivateSliceProps).goaddr`)
+		LuaMustFloat64(vm, "addr", float64(scaled))
+
+		// a subslice starts part way into the same memory;
+		// and slicing a subslice counts from its start.
+		translation = inc.Tr([]byte(`
+w := v[1:][1:]
+vec.Scale(w, 2)
+w0 := w[0]
+lw := len(w)
+v2 := v[2]
+v1 := v[1]
+`))
+		LuaRunAndReport(vm, string(translation))
+		LuaMustFloat64(vm, "w0", 60)
+		LuaMustInt(vm, "lw", 2)
+		LuaMustFloat64(vm, "v2", 60)
+		LuaMustFloat64(vm, "v1", 20)
+		LuaMustFloat64(vm, "addr", float64(scaled-16))
+
+		// make, append, copy, range, and ints.
+		translation = inc.Tr([]byte(`
+m := make([]float64, 2, 4)
+m[1] = 0.5
+m = append(m, 1.5)
+grown := append(m, 2, 3)
+n := copy(m, []float64{7})
+tot := 0.0
+for i, x := range grown {
+	tot += x * float64(i)
+}
+sm := vec.Sum(m)
+sg := vec.Sum(grown)
+si := vec.SumInts(append([]int{1, 2}, 3))
+`))
+		LuaRunAndReport(vm, string(translation))
+		LuaMustInt64(vm, "n", 1)
+		LuaMustFloat64(vm, "sm", 9)
+		LuaMustFloat64(vm, "sg", 7)
+		LuaMustFloat64(vm, "tot", 0.5+3+6+12)
+		LuaMustInt64(vm, "si", 6)
+
+		// arrays, and slices of them.
+		translation = inc.Tr([]byte(`
+a := [3]float64{0, 1, 0}
+a[1] = 2.5
+s := a[:]
+s[2] = 4
+a2 := a[2]
+tr := vec.Trace(a)
+`))
+		LuaRunAndReport(vm, string(translation))
+		LuaMustFloat64(vm, "a2", 4)
+		LuaMustFloat64(vm, "tr", 6.5)
+
+		// a REPL func handed a Go slice writes into it directly.
+		translation = inc.Tr([]byte(`
+got := vec.Fill(func(x []float64) {
+	for i := range x {
+		x[i] = float64(i + 1)
+	}
+})
+`))
+		LuaRunAndReport(vm, string(translation))
+		LuaMustFloat64(vm, "got", 6)
+		cv.So(filled, cv.ShouldNotEqual, 0)
+
+		// the Go memory goes once Lua is done with it.
+		before := luar.NumPinned()
+		LuaRunAndReport(vm, string(inc.Tr([]byte(`tmp := make([]float64, 1000)`))))
+		cv.So(luar.NumPinned(), cv.ShouldEqual, before+1)
+		LuaRunAndReport(vm, `tmp = nil; collectgarbage(); collectgarbage()`)
+		cv.So(luar.NumPinned(), cv.ShouldBeLessThanOrEqualTo, before)
+	})
+}
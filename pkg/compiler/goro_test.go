@@ -0,0 +1,447 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test400GoroutinesRunAfterEval(t *testing.T) {
+
+	cv.Convey(`go f(x) starts a goroutine that the scheduler runs to completion; arguments are evaluated at the go statement`, t, func() {
+
+		code := `
+a := 0
+b := 0
+func f(x int) {
+    a += x
+}
+y := 3
+go f(y)
+y = 100
+go func() {
+    b = 7
+}()
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		translation := inc.Tr([]byte(code))
+		pp("translation='%s'", string(translation))
+
+		LuaRunAndReport(vm, string(translation))
+
+		// nothing has run yet; the go statements only queue.
+		LuaMustInt64(vm, "a", 0)
+		LuaMustInt64(vm, "b", 0)
+
+		panicOn(LuaRunGoroutines(vm))
+		LuaMustInt64(vm, "a", 3)
+		LuaMustInt64(vm, "b", 7)
+	})
+}
+
+func Test402GoStatementEvaluatesFuncAndReceiverNow(t *testing.T) {
+
+	cv.Convey(`go f() and go x.m() read the function value, and the method's receiver, at the go statement, not when the goroutine first runs`, t, func() {
+
+		code := `
+a := 0
+g := func() { a = 1 }
+go g()
+g = func() { a = 2 }
+
+type T struct{ n int }
+func (t T) get() { a += t.n * 10 }
+func (t *T) add() { t.n += 1000 }
+x := T{n: 3}
+go x.get()
+x.n = 4
+p := &T{n: 5}
+go p.add()
+p = &T{n: 6}
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		translation := inc.Tr([]byte(code))
+		pp("translation='%s'", string(translation))
+
+		LuaRunAndReport(vm, string(translation))
+		panicOn(LuaRunGoroutines(vm))
+
+		LuaRunAndReport(vm, `pn = p.n`)
+		LuaMustInt64(vm, "a", 31)
+		LuaMustInt64(vm, "pn", 6)
+	})
+}
+
+func Test401GoroutinesInterleaveOnGosched(t *testing.T) {
+
+	cv.Convey(`goroutines yield to each other cooperatively, and a panic in one goroutine does not stop the others`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		LuaRunAndReport(vm, `
+trace = ""
+__go(function() trace = trace .. "a1 "; __gi_Gosched(); trace = trace .. "a2 " end)
+__go(function() trace = trace .. "b1 "; __gi_Gosched(); trace = trace .. "b2 " end)
+__go(function() error("boom") end)
+`)
+		panicOn(LuaRunGoroutines(vm))
+		LuaMustString(vm, "trace", "a1 b1 a2 b2 ")
+		LuaRunAndReport(vm, `nGo = __gi_NumGoroutine()`)
+		LuaMustInt(vm, "nGo", 1)
+	})
+}
+
+func Test410BufferedChannelSendRecvLenCap(t *testing.T) {
+
+	cv.Convey(`buffered channels: make(chan int, 3), ch <- v, <-ch, len, cap`, t, func() {
+
+		code := `
+ch := make(chan int, 3)
+ch <- 1
+ch <- 2
+n := len(ch)
+c := cap(ch)
+a := <-ch
+b := <-ch
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		translation := inc.Tr([]byte(code))
+		pp("translation='%s'", string(translation))
+
+		cv.So(string(translation), cv.ShouldMatchModuloWhiteSpace, `
+ch = __gi_NewChan(3, 0LL);
+__gi_send(ch, 1LL);
+__gi_send(ch, 2LL);
+n = __gi_chanLen(ch);
+c = __gi_chanCap(ch);
+a = (__gi_recv(ch));
+b = (__gi_recv(ch));
+`)
+		LuaRunAndReport(vm, string(translation))
+		LuaMustInt(vm, "n", 2)
+		LuaMustInt(vm, "c", 3)
+		LuaMustInt64(vm, "a", 1)
+		LuaMustInt64(vm, "b", 2)
+	})
+}
+
+func Test411UnbufferedChannelRangeCloseCommaOk(t *testing.T) {
+
+	cv.Convey(`unbuffered channels hand off between goroutines; range stops at close; comma-ok receive reports closed`, t, func() {
+
+		code := `
+ch := make(chan int)
+done := make(chan bool)
+sum := 0
+go func() {
+   for v := range ch {
+      sum += v
+   }
+   done <- true
+}()
+for i := 1; i <= 4; i++ {
+   ch <- i
+}
+close(ch)
+<-done
+x, ok := <-ch
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		translation := inc.Tr([]byte(code))
+		pp("translation='%s'", string(translation))
+
+		LuaRunAndReport(vm, string(translation))
+		panicOn(LuaRunGoroutines(vm))
+
+		LuaMustInt64(vm, "sum", 10)
+		LuaMustInt64(vm, "x", 0)
+		LuaMustBool(vm, "ok", false)
+	})
+}
+
+func Test412ChannelPanicsAndDeadlock(t *testing.T) {
+
+	cv.Convey(`send on a closed channel and double close panic; blocking main with nothing runnable reports deadlock`, t, func() {
+
+		code := `
+ch := make(chan int, 1)
+close(ch)
+sendPanicked := false
+closePanicked := false
+func trySend() {
+   defer func() {
+      sendPanicked = recover() != nil
+   }()
+   ch <- 1
+}
+func tryClose() {
+   defer func() {
+      closePanicked = recover() != nil
+   }()
+   close(ch)
+}
+trySend()
+tryClose()
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		translation := inc.Tr([]byte(code))
+		pp("translation='%s'", string(translation))
+
+		LuaRunAndReport(vm, string(translation))
+		LuaMustBool(vm, "sendPanicked", true)
+		LuaMustBool(vm, "closePanicked", true)
+
+		LuaRunAndReport(vm, `_, e = pcall(__gi_send, ch, 2LL); msg = tostring(e)`)
+		LuaMustString(vm, "msg", "a-panic-value:send on closed channel")
+
+		err = vm.DoString(`__gi_recv(__gi_NewChan(0, 0LL))`)
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldContainSubstring, "all goroutines are asleep - deadlock!")
+		vm.Pop(1)
+	})
+}
+
+func Test420SelectRecvSendAndCommaOk(t *testing.T) {
+
+	cv.Convey(`select blocks until a receive or send case is ready, and supports v, ok := <-ch`, t, func() {
+
+		code := `
+a := make(chan int)
+b := make(chan string)
+quit := make(chan bool)
+got := 0
+sends := 0
+heard := ""
+closedSeen := false
+go func() {
+  heard = <-b
+  for i := 1; i <= 3; i++ {
+    a <- i
+  }
+  close(quit)
+}()
+for done := false; !done; {
+  select {
+  case v := <-a:
+     got += v
+  case b <- "hi":
+     sends++
+  case _, ok := <-quit:
+     closedSeen = !ok
+     done = true
+  }
+}
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		translation := inc.Tr([]byte(code))
+		pp("translation='%s'", string(translation))
+
+		LuaRunAndReport(vm, string(translation))
+		panicOn(LuaRunGoroutines(vm))
+
+		LuaMustInt64(vm, "got", 6)
+		LuaMustBool(vm, "closedSeen", true)
+		LuaMustInt64(vm, "sends", 1)
+		LuaMustString(vm, "heard", "hi")
+	})
+}
+
+func Test421SelectDefaultDoesNotBlock(t *testing.T) {
+
+	cv.Convey(`select with a default case takes it when nothing else is ready`, t, func() {
+
+		code := `
+ch := make(chan int)
+which := 0
+select {
+case <-ch:
+   which = 1
+default:
+   which = 2
+}
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		translation := inc.Tr([]byte(code))
+
+		cv.So(string(translation), cv.ShouldMatchModuloWhiteSpace, `
+ch = __gi_NewChan(0, 0LL);
+which = 0LL;
+_selection = {__gi_select({{"recv", ch}, {"default"}})};
+if (_selection[1] == 0LL) then
+   which = 1LL;
+elseif (_selection[1] == 1LL) then
+   which = 2LL;
+end
+`)
+		LuaRunAndReport(vm, string(translation))
+		LuaMustInt64(vm, "which", 2)
+	})
+}
+
+func Test422SelectPicksRandomlyAmongReadyCases(t *testing.T) {
+
+	cv.Convey(`when several select cases are ready, each gets picked some of the time`, t, func() {
+
+		code := `
+a := make(chan int, 1)
+b := make(chan int, 1)
+na := 0
+nb := 0
+for i := 0; i < 200; i++ {
+   a <- 1
+   b <- 2
+   select {
+   case <-a:
+      na++
+      <-b
+   case <-b:
+      nb++
+      <-a
+   }
+}
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		translation := inc.Tr([]byte(code))
+
+		LuaRunAndReport(vm, string(translation))
+		LuaRunAndReport(vm, `bothPicked = (na > 0 and nb > 0 and na + nb == 200)`)
+		LuaMustBool(vm, "bothPicked", true)
+	})
+}
+
+func Test430ReceiveFromNativeGoChannels(t *testing.T) {
+
+	cv.Convey(`channels made in Go and returned through luar work with <-, range, len, cap and close`, t, func() {
+
+		code := `
+import "gitesting"
+sum := 0
+for v := range gitesting.GoCount(4) {
+   sum += v
+}
+g := gitesting.GoIntChan(2)
+g <- 5
+g <- 6
+n := len(g)
+c := cap(g)
+x := <-g
+y := <-g
+close(g)
+z, ok := <-g
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		translation := inc.Tr([]byte(code))
+		pp("translation='%s'", string(translation))
+
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustInt64(vm, "sum", 10)
+		LuaMustInt(vm, "n", 2)
+		LuaMustInt(vm, "c", 2)
+		LuaMustInt64(vm, "x", 5)
+		LuaMustInt64(vm, "y", 6)
+		LuaMustInt64(vm, "z", 0)
+		LuaMustBool(vm, "ok", false)
+
+		LuaRunAndReport(vm, `_, e = pcall(__gi_send, g, 7LL); msg = tostring(e)`)
+		LuaMustString(vm, "msg", "a-panic-value:send on closed channel")
+	})
+}
+
+func Test431GoroutinesKeepRunningWhileWaitingOnGo(t *testing.T) {
+
+	cv.Convey(`a goroutine blocked on a native Go channel doesn't stop the other goroutines, and select mixes Go and REPL channels`, t, func() {
+
+		code := `
+import "gitesting"
+src := gitesting.GoCount(3)
+fromGo := 0
+pings := 0
+ping := make(chan int)
+finished := make(chan bool)
+go func() {
+   for v := range src {
+      fromGo += v
+   }
+   finished <- true
+}()
+go func() {
+   for i := 0; i < 5; i++ {
+      ping <- i
+   }
+}()
+gotFinished := false
+for pings < 5 || !gotFinished {
+   select {
+   case <-ping:
+      pings++
+   case <-finished:
+      gotFinished = true
+   }
+}
+
+fromSelect := 0
+more := gitesting.GoCount(2)
+for open := true; open; {
+   select {
+   case v, ok := <-more:
+      fromSelect += v
+      open = ok
+   case <-finished:
+   }
+}
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		translation := inc.Tr([]byte(code))
+		pp("translation='%s'", string(translation))
+
+		LuaRunAndReport(vm, string(translation))
+		panicOn(LuaRunGoroutines(vm))
+
+		LuaMustInt64(vm, "fromGo", 6)
+		LuaMustInt64(vm, "pings", 5)
+		LuaMustInt64(vm, "fromSelect", 3)
+	})
+}
@@ -0,0 +1,591 @@
+-- goroutines.lua: goroutines on top of LuaJIT coroutines,
+-- and the channels they communicate over.
+--
+-- A goroutine is a coroutine plus a little bookkeeping.
+-- Scheduling is cooperative: a goroutine runs
+-- until it finishes or parks itself (on a
+-- channel operation, select, or Gosched).
+--
+-- The main Lua thread (the REPL) is not a coroutine,
+-- so it cannot yield. When main needs to block, it
+-- instead pumps the scheduler from __gi_park()
+-- until somebody makes it ready again.
+--
+-- cf. gopherjs/compiler/prelude/goroutines.go
+
+-- ready to run goroutines, in FIFO order.
+__gi_runQueue = {}
+
+-- the currently executing goroutine; __gi_mainG when
+-- we are on the main Lua thread.
+__gi_mainG = {__id = 0, __main = true, __parked = false}
+__gi_curG = __gi_mainG
+
+__gi_goroutineIdCounter = 0
+
+-- number of started goroutines that have not yet finished.
+__gi_liveGoroutines = 0
+
+-- goroutines blocked on native Go channels; see
+-- __gi_addGoWaiter below.
+__gi_goWaiters = {}
+
+-- __go starts fun(...) as a new goroutine. The arguments
+-- are evaluated by the caller, at the go statement.
+function __go(fun, ...)
+   if fun == nil then
+      error("go of nil func value")
+   end
+   local args = {...}
+   local nargs = select('#', ...)
+
+   __gi_goroutineIdCounter = __gi_goroutineIdCounter + 1
+   local g = {
+      __id = __gi_goroutineIdCounter,
+      __parked = false,
+   }
+   g.__co = coroutine.create(function()
+         fun(unpack(args, 1, nargs))
+   end)
+   __gi_liveGoroutines = __gi_liveGoroutines + 1
+   __gi_ready(g)
+   return g
+end
+
+-- __gi_ready makes a parked goroutine runnable again.
+function __gi_ready(g)
+   g.__parked = false
+   if g.__main then
+      -- main is not a coroutine; __gi_park notices
+      -- the flag change and returns.
+      return
+   end
+   __gi_runQueue[#__gi_runQueue+1] = g
+end
+
+-- __gi_runOne resumes the goroutine at the head of the
+-- run queue. It returns false if nothing was runnable.
+function __gi_runOne()
+   if #__gi_runQueue == 0 then
+      -- maybe a Go channel has become ready since we last looked.
+      if not __gi_pollGoWaiters() then
+         return false
+      end
+      if #__gi_runQueue == 0 then
+         -- only main was woken.
+         return true
+      end
+   end
+   local g = table.remove(__gi_runQueue, 1)
+
+   local prev = __gi_curG
+   __gi_curG = g
+   local ok, err = coroutine.resume(g.__co)
+   __gi_curG = prev
+
+   if not ok then
+      -- an unrecovered panic in a goroutine. Go would
+      -- crash the whole program; at the REPL we just
+      -- report it and drop that goroutine.
+      __gi_liveGoroutines = __gi_liveGoroutines - 1
+      if __gi_interrupted() then
+         -- not a panic: Ctrl-C or a time limit stopped
+         -- it, and must stop main too, so that the
+         -- caller learns of it.
+         error("interrupted", 0)
+      end
+      print("panic in goroutine "..tostring(g.__id)..": "..tostring(err))
+   elseif coroutine.status(g.__co) == "dead" then
+      __gi_liveGoroutines = __gi_liveGoroutines - 1
+   end
+   return true
+end
+
+-- __gi_park blocks the current goroutine until some other
+-- goroutine calls __gi_ready() on it.
+function __gi_park()
+   local g = __gi_curG
+   g.__parked = true
+   if not g.__main then
+      coroutine.yield()
+      return
+   end
+   -- on main: run other goroutines until we are woken up.
+   while g.__parked do
+      if not __gi_runOne() then
+         if #__gi_goWaiters == 0 then
+            g.__parked = false
+            error("fatal error: all goroutines are asleep - deadlock!")
+         end
+         -- nothing in Lua can make progress, so
+         -- it is safe to block in Go.
+         __gi_blockOnGoWaiters()
+      end
+   end
+end
+
+-- __gi_Gosched yields the processor, allowing other
+-- goroutines to run. Like runtime.Gosched().
+function __gi_Gosched()
+   local g = __gi_curG
+   if g.__main then
+      -- give everything currently queued one turn.
+      local n = #__gi_runQueue
+      for i = 1, n do
+         __gi_runOne()
+      end
+      return
+   end
+   __gi_ready(g)
+   coroutine.yield()
+end
+
+-- __gi_schedule runs goroutines until every one of them
+-- has either finished or is blocked. The REPL calls this
+-- after each evaluation.
+function __gi_schedule()
+   while __gi_runOne() do
+   end
+end
+
+-- __gi_NumGoroutine is like runtime.NumGoroutine(); it
+-- counts main too.
+function __gi_NumGoroutine()
+   return __gi_liveGoroutines + 1
+end
+
+------------------------------
+-- channels
+------------------------------
+
+-- channel metatable, so channels print like pointers.
+__gi_Chan_MT = {
+   __name = "__gi_Chan_MT",
+   __tostring = function(ch)
+      return ch.__addr
+   end
+}
+
+-- __gi_NewChan implements make(chan T, capacity). zeroVal
+-- is what a receive on a closed channel returns.
+function __gi_NewChan(capacity, zeroVal)
+   capacity = tonumber(capacity) or 0
+   if capacity < 0 then
+      panic("makechan: size out of range")
+   end
+   local ch = {
+      __capacity = capacity,
+      -- buffered values are boxed as {value}, so
+      -- that nil values don't punch holes in it.
+      __buffer = {},
+      -- queued senders and receivers: functions
+      -- that hand off a value and wake the waiter.
+      __sendQueue = {},
+      __recvQueue = {},
+      __closed = false,
+      __zero = zeroVal,
+   }
+   -- "table: 0x..." becomes "0x..."
+   ch.__addr = string.sub(tostring(ch), 8)
+   setmetatable(ch, __gi_Chan_MT)
+   return ch
+end
+
+-- the zero value for channel types.
+__gi_chanNil = __gi_NewChan(0, nil)
+__gi_chanNil.__isNil = true
+__gi_chanNil.__addr = "<nil>"
+
+function __gi_chanLen(ch)
+   if getmetatable(ch) ~= __gi_Chan_MT then
+      return __gi_goChanLen(ch)
+   end
+   return #ch.__buffer
+end
+
+function __gi_chanCap(ch)
+   if getmetatable(ch) ~= __gi_Chan_MT then
+      return __gi_goChanCap(ch)
+   end
+   return ch.__capacity
+end
+
+-- __gi_blockForever parks the current goroutine with
+-- nobody left to wake it, as happens on
+-- a nil channel.
+function __gi_blockForever()
+   __gi_park()
+   error("unreachable: goroutine blocked forever was woken")
+end
+
+-- __gi_send implements ch <- value.
+function __gi_send(ch, value)
+   if getmetatable(ch) ~= __gi_Chan_MT then
+      return __gi_goSend(ch, value)
+   end
+   if ch.__isNil then
+      __gi_blockForever()
+   end
+   if ch.__closed then
+      panic("send on closed channel")
+   end
+   local queuedRecv = table.remove(ch.__recvQueue, 1)
+   if queuedRecv ~= nil then
+      queuedRecv(value, true)
+      return
+   end
+   if #ch.__buffer < ch.__capacity then
+      ch.__buffer[#ch.__buffer+1] = {value}
+      return
+   end
+
+   -- block until a receiver takes our value.
+   local g = __gi_curG
+   local closedWhileBlocked = false
+   ch.__sendQueue[#ch.__sendQueue+1] = function(closed)
+      closedWhileBlocked = closed
+      __gi_ready(g)
+      return value
+   end
+   __gi_park()
+   if closedWhileBlocked then
+      panic("send on closed channel")
+   end
+end
+
+-- __gi_recv implements <-ch. It returns the value and
+-- the ok flag of the comma-ok form; ok is false only
+-- when ch is closed and drained.
+function __gi_recv(ch)
+   if getmetatable(ch) ~= __gi_Chan_MT then
+      return __gi_goRecv(ch)
+   end
+   if ch.__isNil then
+      __gi_blockForever()
+   end
+   local queuedSend = table.remove(ch.__sendQueue, 1)
+   if queuedSend ~= nil then
+      ch.__buffer[#ch.__buffer+1] = {queuedSend(false)}
+   end
+   if #ch.__buffer > 0 then
+      return table.remove(ch.__buffer, 1)[1], true
+   end
+   if ch.__closed then
+      return ch.__zero, false
+   end
+
+   -- block until a sender or close wakes us.
+   local g = __gi_curG
+   local got = {}
+   ch.__recvQueue[#ch.__recvQueue+1] = function(value, ok)
+      got.value = value
+      got.ok = ok
+      __gi_ready(g)
+   end
+   __gi_park()
+   return got.value, got.ok
+end
+
+-- __gi_close implements close(ch).
+function __gi_close(ch)
+   if getmetatable(ch) ~= __gi_Chan_MT then
+      local msg = __gi_goChanClose(ch)
+      if msg ~= nil then
+         panic(msg)
+      end
+      return
+   end
+   if ch.__isNil then
+      panic("close of nil channel")
+   end
+   if ch.__closed then
+      panic("close of closed channel")
+   end
+   ch.__closed = true
+   while true do
+      local queuedSend = table.remove(ch.__sendQueue, 1)
+      if queuedSend == nil then
+         break
+      end
+      queuedSend(true) -- will panic in the sender
+   end
+   while true do
+      local queuedRecv = table.remove(ch.__recvQueue, 1)
+      if queuedRecv == nil then
+         break
+      end
+      queuedRecv(ch.__zero, false)
+   end
+end
+
+------------------------------
+-- select
+------------------------------
+
+-- __gi_removeFromQueue drops entry from a channel's send
+-- or receive queue, if it is still there.
+function __gi_removeFromQueue(queue, entry)
+   for i = 1, #queue do
+      if queue[i] == entry then
+         table.remove(queue, i)
+         return
+      end
+   end
+end
+
+-- __gi_select implements the select statement.
+--
+-- comms is a 1-based table of cases, each one of
+--    {"recv", ch}
+--    {"send", ch, value}
+--    {"default"}
+--
+-- We return the 0-based index of the chosen case and,
+-- for a receive case, the value received and the ok flag.
+--
+-- As in Go, when several cases are ready we pick one
+-- uniformly at random; otherwise we take the default
+-- case, if there is one; otherwise we block until
+-- some case can proceed.
+--
+function __gi_select(comms)
+   -- visit the cases in a random order, and take the
+   -- first one that is ready. Native Go channels can
+   -- only be asked by trying the operation, so this
+   -- is how we stay uniform among the ready cases.
+   local n = #comms
+   local order = {}
+   for i = 1, n do
+      local j = math.random(i)
+      order[i] = order[j]
+      order[j] = i
+   end
+
+   local defaultCase = nil
+   for _, i in ipairs(order) do
+      local comm = comms[i]
+      local kind = comm[1]
+      local ch = comm[2]
+      if kind == "default" then
+         defaultCase = i
+      elseif getmetatable(ch) ~= __gi_Chan_MT then
+         if kind == "recv" then
+            local ready, value, ok = __gi_goChanTryRecv(ch)
+            if ready then
+               return i-1, value, ok
+            end
+         else
+            local ready, closed = __gi_goChanTrySend(ch, comm[3])
+            if ready then
+               if closed then
+                  panic("send on closed channel")
+               end
+               return i-1
+            end
+         end
+      elseif ch.__isNil then
+         -- never ready
+      elseif kind == "recv" then
+         if #ch.__sendQueue ~= 0 or #ch.__buffer ~= 0 or ch.__closed then
+            return i-1, __gi_recv(ch)
+         end
+      else -- "send"
+         -- a send on a closed channel is ready: it panics.
+         if ch.__closed or #ch.__recvQueue ~= 0 or #ch.__buffer < ch.__capacity then
+            __gi_send(ch, comm[3])
+            return i-1
+         end
+      end
+   end
+
+   if defaultCase ~= nil then
+      return defaultCase-1
+   end
+
+   -- nothing ready: queue ourselves on every channel,
+   -- and block until one of them wakes us.
+   local g = __gi_curG
+   local entries = {}
+   local result = {}
+   local goCases = {}
+   local goIndex = {}
+   local goWaiter = nil
+   local cancelAll = function()
+      for _, qe in ipairs(entries) do
+         __gi_removeFromQueue(qe[1], qe[2])
+      end
+      if goWaiter ~= nil then
+         __gi_removeFromQueue(__gi_goWaiters, goWaiter)
+      end
+   end
+
+   for i, comm in ipairs(comms) do
+      local ch = comm[2]
+      if comm[1] == "default" then
+         -- not reached: we would have taken it above.
+      elseif getmetatable(ch) ~= __gi_Chan_MT then
+         goCases[#goCases+1] = comm
+         goIndex[#goCases] = i
+      elseif not ch.__isNil then
+         if comm[1] == "recv" then
+            local entry = function(value, ok)
+               cancelAll()
+               result.selection = i-1
+               result.value = value
+               result.ok = ok
+               __gi_ready(g)
+            end
+            entries[#entries+1] = {ch.__recvQueue, entry}
+            ch.__recvQueue[#ch.__recvQueue+1] = entry
+         else
+            local entry = function(closed)
+               cancelAll()
+               result.selection = i-1
+               result.closed = closed
+               __gi_ready(g)
+               return comm[3]
+            end
+            entries[#entries+1] = {ch.__sendQueue, entry}
+            ch.__sendQueue[#ch.__sendQueue+1] = entry
+         end
+      end
+   end
+
+   if #goCases ~= 0 then
+      goWaiter = __gi_addGoWaiter(goCases, function(k, a, b)
+            cancelAll()
+            result.selection = goIndex[k]-1
+            if goCases[k][1] == "recv" then
+               result.value = a
+               result.ok = b
+            else
+               result.closed = a
+            end
+            __gi_ready(g)
+      end)
+   end
+
+   __gi_park()
+   if result.closed then
+      panic("send on closed channel")
+   end
+   return result.selection, result.value, result.ok
+end
+
+------------------------------
+-- native Go channels
+------------------------------
+
+-- Channels that come from Go through luar, like the
+-- one time.After returns, are userdata rather than
+-- tables with __gi_Chan_MT. The helpers in gochan.go can only try an
+-- operation without blocking, so a goroutine that
+-- must wait on one registers a waiter and parks. The
+-- scheduler polls the waiters whenever its run queue
+-- is empty, so other goroutines keep running in the
+-- meantime. Only when main itself is blocked and
+-- nothing else can run do we block inside Go, in
+-- __gi_blockOnGoWaiters.
+
+-- __gi_addGoWaiter registers cases, each one of
+--    {"recv", ch}
+--    {"send", ch, value}
+-- on Go channels. When case k completes we call
+-- fire(k, value, ok) for a receive, or fire(k, closed)
+-- for a send. fire is responsible for removing the
+-- waiter, via __gi_removeFromQueue(__gi_goWaiters, w).
+function __gi_addGoWaiter(cases, fire)
+   local w = {cases = cases, fire = fire}
+   __gi_goWaiters[#__gi_goWaiters+1] = w
+   return w
+end
+
+-- __gi_pollGoWaiters tries every waiting Go channel
+-- operation once. It returns true if any completed.
+function __gi_pollGoWaiters()
+   if #__gi_goWaiters == 0 then
+      return false
+   end
+   local progress = false
+   -- copy, since firing removes waiters.
+   local waiters = {unpack(__gi_goWaiters)}
+   for _, w in ipairs(waiters) do
+      for k, c in ipairs(w.cases) do
+         if c[1] == "recv" then
+            local ready, value, ok = __gi_goChanTryRecv(c[2])
+            if ready then
+               w.fire(k, value, ok)
+               progress = true
+               break
+            end
+         else
+            local ready, closed = __gi_goChanTrySend(c[2], c[3])
+            if ready then
+               w.fire(k, closed)
+               progress = true
+               break
+            end
+         end
+      end
+   end
+   return progress
+end
+
+-- __gi_blockOnGoWaiters waits in Go until one of the
+-- waiting Go channel operations completes.
+function __gi_blockOnGoWaiters()
+   local args = {}
+   local owners = {}
+   local n = 0
+   for _, w in ipairs(__gi_goWaiters) do
+      for k, c in ipairs(w.cases) do
+         args[n+1] = c[1]
+         args[n+2] = c[2]
+         args[n+3] = c[3]
+         n = n + 3
+         owners[#owners+1] = {w, k}
+      end
+   end
+   local chosen, a, b = __gi_goChanSelect(unpack(args, 1, n))
+   local o = owners[chosen+1]
+   o[1].fire(o[2], a, b)
+end
+
+-- __gi_goWait parks until one of cases completes, and
+-- returns the 1-based index of that case and its results.
+function __gi_goWait(cases)
+   local g = __gi_curG
+   local result = {}
+   local w
+   w = __gi_addGoWaiter(cases, function(k, a, b)
+         __gi_removeFromQueue(__gi_goWaiters, w)
+         result.k = k
+         result.a = a
+         result.b = b
+         __gi_ready(g)
+   end)
+   __gi_park()
+   return result.k, result.a, result.b
+end
+
+-- __gi_goRecv implements <-ch for a Go channel.
+function __gi_goRecv(ch)
+   local ready, value, ok = __gi_goChanTryRecv(ch)
+   if ready then
+      return value, ok
+   end
+   local _, value, ok = __gi_goWait({{"recv", ch}})
+   return value, ok
+end
+
+-- __gi_goSend implements ch <- value for a Go channel.
+function __gi_goSend(ch, value)
+   local ready, closed = __gi_goChanTrySend(ch, value)
+   if not ready then
+      local _
+      _, closed = __gi_goWait({{"send", ch, value}})
+   end
+   if closed then
+      panic("send on closed channel")
+   end
+end
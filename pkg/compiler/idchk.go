@@ -91,6 +91,9 @@ func (iv *identVisitor) Visit(node ast.Node) (w ast.Visitor) {
 			iv.av.which = id.Name
 			return nil
 		}
+	case *ast.IndexExpr:
+		// m[[2]int{1, 2}] = x declares nothing.
+		return nil
 	}
 	return iv
 }
@@ -1,7 +1,11 @@
 package compiler
 
 import (
+	"bytes"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gijit/gi/pkg/importer"
 	"github.com/gijit/gi/pkg/token"
@@ -16,6 +20,7 @@ import (
 	shadow_math_rand "github.com/gijit/gi/pkg/compiler/shadow/math/rand"
 	"github.com/gijit/gi/pkg/compiler/shadow/os"
 	"github.com/gijit/gi/pkg/compiler/shadow/regexp"
+	"github.com/gijit/gi/pkg/compiler/shadow/sort"
 	"github.com/gijit/gi/pkg/compiler/shadow/time"
 
 	// gonum
@@ -78,7 +83,27 @@ func (ic *IncrState) GiImportFunc(path string) (*Archive, error) {
 
 	var pkg *types.Package
 
+	// packages typed in at the REPL with :package
+	if pk, ok := ic.pkgMap[path]; ok {
+		if pk == ic.CurPkg {
+			return nil, fmt.Errorf("import cycle not allowed: package '%s' cannot import itself", path)
+		}
+		if pk.Arch == nil {
+			return nil, fmt.Errorf("package '%s' is empty, nothing to import", path)
+		}
+		ic.CurPkg.importContext.Packages[path] = pk.Arch.Pkg
+		ic.CurPkg.importContext.PkgTables[path] = true
+		return pk.Arch, nil
+	}
+
+	// packages registered at runtime, by embedders
+	if sp := LookupShadowPackage(path); sp != nil {
+		return ic.importRegisteredShadow(sp)
+	}
+
 	switch path {
+	case "github.com/gijit/gi/lua":
+		return ic.importLuaPackage(path)
 	case "gitesting":
 		// test only:
 		if !ic.vmCfg.NotTestMode {
@@ -99,12 +124,17 @@ func (ic *IncrState) GiImportFunc(path string) (*Archive, error) {
 			incr := getFunForIncr(pkg)
 			scope.Insert(incr)
 
+			scope.Insert(getFunForGoCount(pkg))
+			scope.Insert(getFunForGoIntChan(pkg))
+
 			luar.Register(ic.vm, "gitesting", luar.Map{
 				"SumArrayInt64": sumArrayInt64,
 				//"__giClone":     __giClone,
 				"Summer":    Summer,
 				"SummerAny": SummerAny,
 				"Incr":      Incr,
+				"GoCount":   GoCount,
+				"GoIntChan": GoIntChan,
 			})
 
 			ic.CurPkg.importContext.Packages[path] = pkg
@@ -123,12 +153,18 @@ func (ic *IncrState) GiImportFunc(path string) (*Archive, error) {
 		luar.Register(ic.vm, "io", shadow_io.Pkg)
 	case "math":
 		luar.Register(ic.vm, "math", shadow_math.Pkg)
+	case "math/cmplx":
+		// nothing to register: the prelude defines the Lua
+		// cmplx table, since complex values are cdata
+		// that luar cannot hand to Go.
 	case "math/rand":
 		luar.Register(ic.vm, "rand", shadow_math_rand.Pkg)
 	case "os":
 		luar.Register(ic.vm, "os", shadow_os.Pkg)
 	case "regexp":
 		luar.Register(ic.vm, "regexp", shadow_regexp.Pkg)
+	case "sort":
+		luar.Register(ic.vm, "sort", shadow_sort.Pkg)
 	case "time":
 		luar.Register(ic.vm, "time", shadow_time.Pkg)
 
@@ -155,8 +191,15 @@ func (ic *IncrState) GiImportFunc(path string) (*Archive, error) {
 		luar.Register(ic.vm, "unit", shadow_unit.Pkg)
 
 	default:
-		// need to run gen-gijit-shadow-import
-		return nil, fmt.Errorf("erro: package '%s' unknown, or not shadowed. To shadow it, run gen-gijit-shadow-import on the package, add a case and import above, and recompile gijit.", path)
+		// no shadow: try to translate the package from
+		// its source. To call the compiled package instead,
+		// run gen-gijit-shadow-import on it, add a case and
+		// import above, and recompile gijit.
+		arch, err := ic.ImportSourcePackage(path)
+		if err != nil {
+			return nil, fmt.Errorf("erro: package '%s' unknown, not shadowed, and could not be imported from source: %v", path, err)
+		}
+		return arch, nil
 	}
 
 	// loading from real GOROOT/GOPATH.
@@ -267,6 +310,51 @@ func getFunForIncr(pkg *types.Package) *types.Func {
 	return fun
 }
 
+// GoCount sends 1..n on the returned channel from a
+// native Go goroutine, pausing before each send, then
+// closes it. Used to test receiving from Go channels.
+func GoCount(n int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		for i := 1; i <= n; i++ {
+			time.Sleep(time.Millisecond)
+			ch <- i
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func getFunForGoCount(pkg *types.Package) *types.Func {
+	// func GoCount(n int) <-chan int
+	var recv *types.Var
+	nt := types.Typ[types.Int]
+	results := types.NewTuple(types.NewVar(token.NoPos, pkg, "", types.NewChan(types.RecvOnly, nt)))
+	params := types.NewTuple(types.NewVar(token.NoPos, pkg, "n", nt))
+	variadic := false
+	sig := types.NewSignature(recv, params, results, variadic)
+	fun := types.NewFunc(token.NoPos, pkg, "GoCount", sig)
+	return fun
+}
+
+// GoIntChan returns make(chan int, capacity), made on
+// the Go side.
+func GoIntChan(capacity int) chan int {
+	return make(chan int, capacity)
+}
+
+func getFunForGoIntChan(pkg *types.Package) *types.Func {
+	// func GoIntChan(capacity int) chan int
+	var recv *types.Var
+	nt := types.Typ[types.Int]
+	results := types.NewTuple(types.NewVar(token.NoPos, pkg, "", types.NewChan(types.SendRecv, nt)))
+	params := types.NewTuple(types.NewVar(token.NoPos, pkg, "capacity", nt))
+	variadic := false
+	sig := types.NewSignature(recv, params, results, variadic)
+	fun := types.NewFunc(token.NoPos, pkg, "GoIntChan", sig)
+	return fun
+}
+
 // We use the go/importer to load the compiled form of
 // the package. This reads from the
 // last built binary .a lib on disk. Warning: this might
@@ -306,9 +394,46 @@ func (ic *IncrState) ActuallyImportPackage(path, dir, shadowPath string) (*Archi
 	// very important, must do this or we won't locate the package!
 	ic.CurPkg.importContext.Packages[path] = pkg
 
+	err = ic.declareNativeStructs(pkg)
+	if err != nil {
+		return nil, err
+	}
 	return res, nil
 }
 
+// declareNativeStructs gives each exported struct type
+// of the native package pkg a Lua type, so that a REPL
+// literal such as optimize.Problem{Func: f} can be made
+// and handed to Go; see __gi_NativeStruct.
+func (ic *IncrState) declareNativeStructs(pkg *types.Package) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "__type__%[1]s = __type__%[1]s or {};\n", pkg.Name())
+	scope := pkg.Scope()
+	for _, nm := range scope.Names() {
+		tn, ok := scope.Lookup(nm).(*types.TypeName)
+		if !ok || !tn.Exported() || tn.IsAlias() {
+			continue
+		}
+		st, ok := tn.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		props := make([]string, st.NumFields())
+		for i := range props {
+			props[i] = strconv.Quote(fieldName(st, i))
+		}
+		fmt.Fprintf(&b, "__gi_NativeStruct(__type__%s, %q, %q, %q, {%s});\n",
+			pkg.Name(), pkg.Name(), pkg.Path(), nm, strings.Join(props, ", "))
+	}
+	top := ic.vm.GetTop()
+	defer ic.vm.SetTop(top)
+	err := ic.vm.DoString(b.String())
+	if err != nil {
+		return fmt.Errorf("could not declare the struct types of package '%s': %v", pkg.Path(), err)
+	}
+	return nil
+}
+
 // __gijit_printQuoted(a ...interface{})
 func getFunForGijitPrintQuoted(pkg *types.Package) *types.Func {
 	// func __gijit_printQuoted(a ...interface{})
@@ -31,6 +31,62 @@ func addPreludeToNewPkg(pkg *types.Package) {
 	scope.Insert(getFunForGijitPrintQuoted(pkg))
 }
 
+// scopeSnapshot holds the objects of a package scope,
+// and the methods of its named types, so that a chunk
+// that fails to type check can be taken back out of
+// it; see restore.
+type scopeSnapshot struct {
+	objs    map[string]types.Object
+	methods map[*types.Named][]*types.Func
+}
+
+func snapshotScope(s *types.Scope) *scopeSnapshot {
+	snap := &scopeSnapshot{
+		objs:    make(map[string]types.Object),
+		methods: make(map[*types.Named][]*types.Func),
+	}
+	for _, nm := range s.Names() {
+		obj := s.Lookup(nm)
+		snap.objs[nm] = obj
+		if named, ok := obj.Type().(*types.Named); ok && isTypeName(obj) {
+			// copied: redefining a method edits the
+			// list in place.
+			var ms []*types.Func
+			for i := 0; i < named.NumMethods(); i++ {
+				ms = append(ms, named.Method(i))
+			}
+			snap.methods[named] = ms
+		}
+	}
+	return snap
+}
+
+// restore puts s back as it was at the snapshot, so
+// that the half-checked declarations of a chunk with
+// errors don't linger in the incremental type checker:
+// neither its new names, nor the methods it gave the
+// types already declared.
+func (snap *scopeSnapshot) restore(s *types.Scope) {
+	for _, nm := range s.Names() {
+		if _, ok := snap.objs[nm]; !ok {
+			s.DeleteByName(nm)
+		}
+	}
+	for nm, obj := range snap.objs {
+		if s.Lookup(nm) != obj {
+			s.Replace(obj)
+		}
+	}
+	for named, ms := range snap.methods {
+		named.SetMethods(ms)
+	}
+}
+
+func isTypeName(obj types.Object) bool {
+	_, ok := obj.(*types.TypeName)
+	return ok
+}
+
 func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, fileSet *token.FileSet, importContext *ImportContext, minify bool) (*Archive, error) {
 
 	pp("jea debug, top of incrementallyCompile()."+
@@ -65,7 +121,6 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 
 	var importError error
 	var errList ErrorList
-	var previousErr error
 	var config *types.Config
 	if a != nil {
 		config = a.Config
@@ -78,16 +133,18 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 			},
 			//Sizes: sizes32,
 			Sizes: sizes64,
-			Error: func(err error) {
-				panic(fmt.Sprintf("where error? err = '%v'", err))
-				if previousErr != nil && previousErr.Error() == err.Error() {
-					return
-				}
-				errList = append(errList, err)
-				previousErr = err
-			},
 		}
 	}
+	// config is kept in the Archive, so its Error must
+	// be set afresh, to collect into this errList.
+	var previousErr error
+	config.Error = func(err error) {
+		if previousErr != nil && previousErr.Error() == err.Error() {
+			return
+		}
+		errList = append(errList, err)
+		previousErr = err
+	}
 	pp("about to call config.Check")
 	var pkg *types.Package
 	var check *types.Checker
@@ -95,8 +152,15 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 		pkg = a.Pkg
 		check = a.Check
 	}
+	var snap *scopeSnapshot
+	if pkg != nil {
+		snap = snapshotScope(pkg.Scope())
+	}
 	var err error
 	pkg, check, err = config.Check(pkg, check, importPath, fileSet, files, typesInfo, addPreludeToNewPkg)
+	if (errList != nil || err != nil) && snap != nil {
+		snap.restore(pkg.Scope())
+	}
 	if importError != nil {
 		//pp("config.Check: importError")
 		return nil, importError
@@ -200,7 +264,11 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 			// but now we do it here to maintain previous behavior.
 			continue
 		}
-		c.p.pkgVars[importedPkg.Path()] = c.newVariableWithLevel(importedPkg.Name(), true)
+		if importContext.PkgTables[importedPkg.Path()] {
+			c.p.pkgVars[importedPkg.Path()] = pkgTableVar(importedPkg.Path())
+		} else {
+			c.p.pkgVars[importedPkg.Path()] = c.newVariableWithLevel(importedPkg.Name(), true)
+		}
 		importedPaths = append(importedPaths, importedPkg.Path())
 	}
 	sort.Strings(importedPaths)
@@ -521,6 +589,12 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 							default:
 								wrapWithPrint = false
 							}
+						case *ast.UnaryExpr:
+							if z.Op == token.ARROW {
+								// a bare receive, <-ch, discards
+								// its value, as in compiled Go.
+								wrapWithPrint = false
+							}
 						default:
 						}
 					default:
@@ -555,10 +629,13 @@ func IncrementallyCompile(a *Archive, importPath string, files []*ast.File, file
 								splt = splt[:nsplit-1]
 								nsplit = len(splt)
 							}
+							// the value is shown as `= expr` would
+							// show it, which needs its Go type.
+							show := fmt.Sprintf("__gijit_ansTypes = {%s};\n__gijit_printQuoted(%%s);", c.ansTypeString(d.(*ast.ExprStmt).X))
 							if nsplit <= 1 {
-								tmp = fmt.Sprintf(`print(%s);`, ele)
+								tmp = fmt.Sprintf(show, ele)
 							} else {
-								tmp = fmt.Sprintf("%s;\nprint(%s);", strings.Join(splt[:nsplit-1], "\n"), splt[nsplit-1])
+								tmp = fmt.Sprintf("%s;\n"+show, strings.Join(splt[:nsplit-1], "\n"), splt[nsplit-1])
 							}
 						}
 					}
@@ -758,7 +835,7 @@ func (c *funcContext) oneNamedType(collectDependencies func(f func()) []string,
 				//}
 				if t.NumFields() == 0 {
 					//constructor = fmt.Sprintf("function(self) %s\n\t\t self.__gi_val=self; return self; end", diag)
-					constructor = fmt.Sprintf("function(self) %s\n\t\t return self; end", diag)
+					constructor = fmt.Sprintf("function(self) %s\n\t\t if self == nil then self = {}; end\n\t\t return self; end", diag)
 				} else {
 					constructor = fmt.Sprintf("function(self, ...) %s\n\t\t if self == nil then self = {}; end\n\t\t local args={...};\n\t\t if #args == 0 then\n", diag)
 					//constructor = fmt.Sprintf("function(self, ...) %s\n\t\t self.__gi_val=self;\n\t\t local args={...};\n\t\t if #args == 0 then\n", diag)
@@ -850,11 +927,12 @@ func (c *funcContext) oneNamedType(collectDependencies func(f func()) []string,
 
 			}
 			if len(ptrMethods) > 0 {
-				c.TypeNameSetting = SKIP_ANON
-				pn := c.typeName(types.NewPointer(named)) // "kind_ptrType"
-				pp("newPtrTypeName='%s'", pn)
-				pp("c.objectName(o)='%s'", c.objectName(o))
-				pn = c.objectName(o)
+				// jea: not c.typeName(types.NewPointer(named)):
+				// that would make the anon *T type here, where
+				// it can't be printed, and then every later
+				// use would name a variable never set.
+				pn := c.objectName(o)
+				pp("c.objectName(o)='%s'", pn)
 				// so these are the methods for B (test 102 face_test), but
 				// we'll need to get them to __type__B.__ptr and not to ptrType.
 				c.Printf("__type__%s.__ptr.__methods_desc = {%s}; -- incr.go:827 for ptr_methods\n", pn, strings.Join(ptrMethods, ", "))
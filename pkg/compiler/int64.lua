@@ -32,3 +32,82 @@ float32 = ffi.typeof("float")
 -- to use cdata as hash keys... tostring() to make them strings first.
 
 
+-- Go's fixed width integer arithmetic.
+--
+-- Every integer value is held in an int64 or a
+-- uint64 cdata, whatever its Go size; 64-bit
+-- arithmetic wraps by itself, and the translator
+-- wraps the results of the narrower types with
+-- __gi_int8() .. __gi_uint32(). Lua numbers, such
+-- as the length operator gives, are promoted by
+-- adding 0LL.
+
+local i64 = ffi.typeof("int64_t")
+local u64 = ffi.typeof("uint64_t")
+
+__gi_int8   = function(x) return i64(ffi.cast("int8_t", x)) end
+__gi_int16  = function(x) return i64(ffi.cast("int16_t", x)) end
+__gi_int32  = function(x) return i64(ffi.cast("int32_t", x)) end
+__gi_int64  = function(x) return i64(x) end
+__gi_uint8  = function(x) return u64(ffi.cast("uint8_t", x)) end
+__gi_uint16 = function(x) return u64(ffi.cast("uint16_t", x)) end
+__gi_uint32 = function(x) return u64(ffi.cast("uint32_t", x)) end
+__gi_uint64 = function(x) return u64(x) end
+
+-- LuaJIT's int64 division truncates toward zero,
+-- and its remainder takes the sign of the dividend,
+-- as Go's do; but division by zero must panic.
+__gi_div = function(x, y)
+   if y == 0 then
+      error("integer divide by zero")
+   end
+   return (0LL + x) / y
+end
+
+__gi_rem = function(x, y)
+   if y == 0 then
+      error("integer divide by zero")
+   end
+   return (0LL + x) % y
+end
+
+-- shifts by the width or more are well defined in
+-- Go, but not in the bit library, which takes the
+-- count mod 64.
+__gi_shl = function(x, s)
+   if s < 0 then
+      error("negative shift amount")
+   end
+   if s >= 64 then
+      return 0LL * x
+   end
+   return bit.lshift(0LL + x, tonumber(s))
+end
+
+-- __gi_shr is the arithmetic shift, for signed x.
+__gi_shr = function(x, s)
+   if s < 0 then
+      error("negative shift amount")
+   end
+   if s >= 64 then
+      s = 63
+   end
+   return bit.arshift(0LL + x, tonumber(s))
+end
+
+-- __gi_ushr is the logical shift, for unsigned x.
+__gi_ushr = function(x, s)
+   if s < 0 then
+      error("negative shift amount")
+   end
+   if s >= 64 then
+      return 0LL * x
+   end
+   return bit.rshift(0LL + x, tonumber(s))
+end
+
+__gi_band    = function(x, y) return bit.band(0LL + x, y) end
+__gi_bor     = function(x, y) return bit.bor(0LL + x, y) end
+__gi_bxor    = function(x, y) return bit.bxor(0LL + x, y) end
+__gi_bandnot = function(x, y) return bit.band(0LL + x, bit.bnot(0LL + y)) end
+__gi_bnot    = function(x) return bit.bnot(0LL + x) end
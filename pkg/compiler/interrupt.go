@@ -0,0 +1,103 @@
+package compiler
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	golua "github.com/glycerine/golua/lua"
+)
+
+// stopping runaway evaluations: Ctrl-C at the REPL,
+// :timeout, and Interpreter.SetTimeout.
+
+// ErrInterrupted is returned when an evaluation was
+// stopped by Ctrl-C or by Interpreter.Interrupt.
+var ErrInterrupted = errors.New("interrupted")
+
+// ErrTimeout is returned when an evaluation ran past
+// its time limit, and was stopped.
+var ErrTimeout = errors.New("interrupted: time limit exceeded")
+
+// registerInterruptPoll hands __gi_poll, in
+// prelude.lua, the C function and flag it reads.
+func registerInterruptPoll(vm *golua.State) {
+	vm.GetGlobal("__gi_setInterruptPoll")
+	vm.PushInterruptPoll()
+	panicOn(vm.Call(2, 0))
+}
+
+// interrupter stops the Lua code running on vm,
+// at the request of another goroutine. The vm keeps
+// its state: the stopped code simply raises an error.
+type interrupter struct {
+	vm *golua.State
+
+	mu      sync.Mutex
+	running bool
+
+	// why the running code was stopped, if it was.
+	why error
+}
+
+// interrupt stops the code that run is running, if
+// any, and makes run return why.
+func (it *interrupter) interrupt(why error) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if it.running && it.why == nil {
+		it.why = why
+		it.vm.Interrupt()
+	}
+}
+
+// run calls f, which runs Lua code on it.vm, and
+// stops that code after timeout, if timeout is
+// positive; or on SIGINT, if sigint is set. The
+// error that then comes back from f is replaced by
+// ErrTimeout or ErrInterrupted.
+func (it *interrupter) run(timeout time.Duration, sigint bool, f func() error) error {
+	it.vm.ClearInterrupt()
+	it.mu.Lock()
+	it.running = true
+	it.why = nil
+	it.mu.Unlock()
+
+	if timeout > 0 {
+		t := time.AfterFunc(timeout, func() { it.interrupt(ErrTimeout) })
+		defer t.Stop()
+	}
+	if sigint {
+		sig := make(chan os.Signal, 1)
+		done := make(chan bool)
+		signal.Notify(sig, os.Interrupt)
+		go func() {
+			select {
+			case <-sig:
+				it.interrupt(ErrInterrupted)
+			case <-done:
+			}
+		}()
+		defer func() {
+			signal.Stop(sig)
+			close(done)
+		}()
+	}
+
+	err := f()
+
+	it.mu.Lock()
+	it.running = false
+	why := it.why
+	it.mu.Unlock()
+	// an interrupt that came too late to be
+	// seen must not stop the next evaluation.
+	it.vm.ClearInterrupt()
+
+	if err != nil && why != nil {
+		return why
+	}
+	return err
+}
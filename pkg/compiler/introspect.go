@@ -0,0 +1,173 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+)
+
+// the REPL's :ls, :type and :info commands.
+
+// previewLen bounds the value shown by :ls.
+const previewLen = 60
+
+// Ls lists the variables, constants, types and
+// functions in the current package, with their types,
+// and a preview of the value of each variable.
+func (tr *IncrState) Ls() string {
+	pk := tr.CurPkg
+	if pk.Arch == nil {
+		return "(nothing defined yet)\n"
+	}
+	scope := pk.Arch.Pkg.Scope()
+	qf := types.RelativeTo(pk.Arch.Pkg)
+
+	var buf bytes.Buffer
+	names := scope.Names()
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.HasPrefix(name, "__") {
+			// __gijit_ans, and the like.
+			continue
+		}
+		obj := scope.Lookup(name)
+		switch o := obj.(type) {
+		case *types.Var:
+			fmt.Fprintf(&buf, "var   %s %s = %s\n", name, types.TypeString(o.Type(), qf), tr.preview(name, o.Type()))
+		case *types.Const:
+			fmt.Fprintf(&buf, "const %s %s = %s\n", name, types.TypeString(o.Type(), qf), o.Val())
+		case *types.TypeName:
+			fmt.Fprintf(&buf, "type  %s %s\n", name, types.TypeString(o.Type().Underlying(), qf))
+		case *types.Func:
+			fmt.Fprintf(&buf, "func  %s%s\n", name, strings.TrimPrefix(types.TypeString(o.Type(), qf), "func"))
+		}
+	}
+	if buf.Len() == 0 {
+		return "(nothing defined yet)\n"
+	}
+	return buf.String()
+}
+
+// preview gives a short look at the value of the
+// variable name, of type t, from the Lua vm; where it
+// lives under the name the translator gave it. It is
+// written as :print compact writes values.
+func (tr *IncrState) preview(name string, t types.Type) string {
+	vm := tr.vm
+	top := vm.GetTop()
+	defer vm.SetTop(top)
+
+	name = luaIdent(name)
+	val := name
+	if tr.CurPkg.key != "main" {
+		val = fmt.Sprintf("__gi_packages[%q][%q]", tr.CurPkg.key, name)
+	}
+	// the printer adds "..." to what it cuts off.
+	code := fmt.Sprintf("return __gi_sprint(%s, %s, false, %d)", val, encodeString(printTypeString(t)), previewLen-3)
+	if err := vm.DoString(code); err != nil {
+		return "?"
+	}
+	return strings.Replace(vm.ToString(-1), "\n", " ", -1)
+}
+
+// TypeOfExpr type-checks expr in the current package,
+// without evaluating it, and gives its type. This
+// is :type.
+func (tr *IncrState) TypeOfExpr(expr string) (string, error) {
+	pk := tr.CurPkg
+	if pk.Arch == nil {
+		return "", fmt.Errorf("nothing defined yet")
+	}
+	tv, err := types.Eval(pk.fileSet, pk.Arch.Pkg, token.NoPos, expr)
+	if err != nil {
+		return "", err
+	}
+	qf := types.RelativeTo(pk.Arch.Pkg)
+	if tv.IsType() {
+		return fmt.Sprintf("%s is a type: %s", expr, types.TypeString(tv.Type.Underlying(), qf)), nil
+	}
+	return types.TypeString(tv.Type, qf), nil
+}
+
+// Info describes the named type name: its underlying
+// type, its fields if a struct, and the method sets
+// of the type and of a pointer to it. This is :info.
+func (tr *IncrState) Info(name string) (string, error) {
+	pk := tr.CurPkg
+	if pk.Arch == nil {
+		return "", fmt.Errorf("nothing defined yet")
+	}
+	tv, err := types.Eval(pk.fileSet, pk.Arch.Pkg, token.NoPos, name)
+	if err != nil {
+		return "", err
+	}
+	if !tv.IsType() {
+		return "", fmt.Errorf("'%s' is not a type, it is a value of type %s", name, tv.Type)
+	}
+	qf := types.RelativeTo(pk.Arch.Pkg)
+	t := tv.Type
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "type %s %s\n", name, kindName(t.Underlying()))
+	if st, ok := t.Underlying().(*types.Struct); ok {
+		fmt.Fprintf(&buf, "fields:\n")
+		if st.NumFields() == 0 {
+			fmt.Fprintf(&buf, "  (none)\n")
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			f := st.Field(i)
+			embedded := ""
+			if f.Anonymous() {
+				embedded = "  (embedded)"
+			}
+			fmt.Fprintf(&buf, "  %s %s%s\n", f.Name(), types.TypeString(f.Type(), qf), embedded)
+		}
+	} else {
+		fmt.Fprintf(&buf, "underlying: %s\n", types.TypeString(t.Underlying(), qf))
+	}
+
+	writeMethods := func(title string, t types.Type) {
+		mset := types.NewMethodSet(t)
+		fmt.Fprintf(&buf, "methods of %s:\n", title)
+		if mset.Len() == 0 {
+			fmt.Fprintf(&buf, "  (none)\n")
+		}
+		for i := 0; i < mset.Len(); i++ {
+			m := mset.At(i).Obj()
+			fmt.Fprintf(&buf, "  %s%s\n", m.Name(), strings.TrimPrefix(types.TypeString(m.Type(), qf), "func"))
+		}
+	}
+	writeMethods(name, t)
+	if _, isIface := t.Underlying().(*types.Interface); !isIface {
+		writeMethods("*"+name, types.NewPointer(t))
+	}
+	return buf.String(), nil
+}
+
+// kindName gives the kind of Go type t is, as
+// written in a type declaration.
+func kindName(t types.Type) string {
+	switch t.(type) {
+	case *types.Struct:
+		return "struct"
+	case *types.Interface:
+		return "interface"
+	case *types.Map:
+		return "map"
+	case *types.Slice:
+		return "slice"
+	case *types.Array:
+		return "array"
+	case *types.Pointer:
+		return "pointer"
+	case *types.Signature:
+		return "func"
+	case *types.Chan:
+		return "chan"
+	}
+	return t.String()
+}
@@ -0,0 +1,115 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test610LsTypeAndInfo(t *testing.T) {
+
+	cv.Convey(`:ls lists what is defined, with types and a preview of each variable's value; :type gives the type of an expression without running it; :info shows fields and methods, including methods added later`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) {
+			LuaRunAndReport(vm, string(inc.Tr([]byte(src))))
+		}
+		run(`type pt struct{ X, Y int; name string }`)
+		run(`func (p pt) Sum() int { return p.X + p.Y }`)
+		run(`const limit = 10`)
+		run(`greeting := "hello"`)
+		run(`func twice(x int) int { return 2 * x }`)
+
+		cv.So(inc.Ls(), cv.ShouldEqual, `var   greeting string = hello
+const limit untyped int = 10
+type  pt struct{X int; Y int; name string}
+func  twice(x int) int
+`)
+
+		ty, err := inc.TypeOfExpr(`twice(limit) > 3`)
+		panicOn(err)
+		cv.So(ty, cv.ShouldEqual, "untyped bool")
+
+		ty, err = inc.TypeOfExpr(`pt{}.Sum`)
+		panicOn(err)
+		cv.So(ty, cv.ShouldEqual, "func() int")
+
+		_, err = inc.TypeOfExpr(`nosuch + 1`)
+		cv.So(err, cv.ShouldNotBeNil)
+
+		run(`func (p *pt) Scale(k int) { p.X *= k; p.Y *= k }`)
+		info, err := inc.Info("pt")
+		panicOn(err)
+		cv.So(info, cv.ShouldEqual, `type pt struct
+fields:
+  X int
+  Y int
+  name string
+methods of pt:
+  Sum() int
+methods of *pt:
+  Scale(k int)
+  Sum() int
+`)
+	})
+}
+
+func Test611LsPreviewsLuaKeywordNames(t *testing.T) {
+
+	cv.Convey(`:ls previews variables whose Go names are Lua keywords, such as end and local`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) {
+			LuaRunAndReport(vm, string(inc.Tr([]byte(src))))
+		}
+		run(`end := "there"`)
+		run(`local := true`)
+		run(`func then(repeat string) string { return repeat + end }`)
+		run(`until := then("here and ")`)
+
+		cv.So(inc.Ls(), cv.ShouldEqual, `var   end string = there
+var   local bool = true
+func  then(repeat string) string
+var   until string = here and there
+`)
+	})
+}
+
+func Test612LsPreviewsValuesAsPrintCompactDoes(t *testing.T) {
+
+	cv.Convey(`:ls previews ints, structs, pointers, slices and maps as :print compact writes them, cut off at previewLen`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) {
+			LuaRunAndReport(vm, string(inc.Tr([]byte(src))))
+		}
+		run(`type pt struct{ X, Y int }`)
+		run(`n := 6`)
+		run(`a := pt{1, 2}`)
+		run(`p := &a`)
+		run(`s := []string{"x", "y"}`)
+		run(`m := map[string]int{"b": 2, "a": 1}`)
+		run(`long := make([]int, 100)`)
+
+		cv.So(inc.Ls(), cv.ShouldEqual, `var   a pt = {1 2}
+var   long []int = [0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 ...
+var   m map[string]int = map[a:1 b:2]
+var   n int = 6
+var   p *pt = &{1 2}
+type  pt struct{X int; Y int}
+var   s []string = [x y]
+`)
+	})
+}
@@ -0,0 +1,107 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test560Uint32ArithmeticWraps(t *testing.T) {
+
+	cv.Convey(`uint32 multiplication overflows as in Go, so a 32-bit FNV-1a hash of "hello" comes out right`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+func fnv(b []byte) uint32 {
+	h := uint32(2166136261)
+	for _, c := range b {
+		h ^= uint32(c)
+		h *= 16777619
+	}
+	return h
+}
+`)
+		panicOn(err)
+		vals, err := in.Eval(`fnv([]byte{104, 101, 108, 108, 111})`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, uint32(0x4f9f2cab))
+
+		_, err = in.Eval(`u := uint32(0xffffffff)`)
+		panicOn(err)
+		vals, err = in.Eval(`u + 2`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, uint32(1))
+	})
+}
+
+func Test561SmallIntegersWrapAndConvert(t *testing.T) {
+
+	cv.Convey(`int8, uint8 and int16 wrap on overflow and on negation, and conversions between sizes truncate`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+var i8 int8 = -128
+var b byte = 250
+b += 10
+a := 200
+big := int64(0x12345678abcd)
+`)
+		panicOn(err)
+
+		expect := func(expr string, want interface{}) {
+			vals, err := in.Eval(expr)
+			panicOn(err)
+			cv.So(vals[0].Interface(), cv.ShouldEqual, want)
+		}
+		expect(`-i8`, int8(-128))
+		expect(`i8 - 1`, int8(127))
+		expect(`b`, uint8(4))
+		expect(`int8(a)`, int8(-56))
+		expect(`uint16(big)`, uint16(0xabcd))
+		expect(`int32(big)`, int32(0x5678abcd))
+		expect(`uint64(-a)`, uint64(18446744073709551416))
+		expect(`int16(3.9e4 - float64(a))`, int16(-26736))
+		expect(`^b`, uint8(251))
+	})
+}
+
+func Test562ShiftsDivisionAndAndNot(t *testing.T) {
+
+	cv.Convey(`shifts by the width or more give zero (or -1), division truncates and panics on zero, and &^ clears bits`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+var u uint32 = 0xffffffff
+var one int32 = 1
+n := -7
+s := uint(70)
+z := 0
+`)
+		panicOn(err)
+
+		expect := func(expr string, want interface{}) {
+			vals, err := in.Eval(expr)
+			panicOn(err)
+			cv.So(vals[0].Interface(), cv.ShouldEqual, want)
+		}
+		expect(`u << 4`, uint32(0xfffffff0))
+		expect(`u << s`, uint32(0))
+		expect(`u >> 31`, uint32(1))
+		expect(`one << 31`, int32(-2147483648))
+		expect(`n >> s`, -1)
+		expect(`n / 2`, -3)
+		expect(`n % 2`, -1)
+		expect(`u &^ 0xff`, uint32(0xffffff00))
+		expect(`6 & n | 1`, 1)
+
+		_, err = in.Eval(`q := n / z`)
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldContainSubstring, "integer divide by zero")
+	})
+}
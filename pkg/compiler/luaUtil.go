@@ -56,6 +56,18 @@ func NewLuaVmWithPrelude(cfg *VmConfig) (*golua.State, error) {
 	})
 	//fmt.Printf("registered __lua2go with luar.\n")
 
+	// so goroutines.lua can operate on native Go channels.
+	registerGoChanHelpers(vm)
+
+	// so numeric slices can live in Go memory.
+	registerGoSliceHelpers(vm)
+
+	// so print.lua can show proxied Go values.
+	registerPrintHelpers(vm)
+
+	// so loops can be stopped, even once compiled.
+	registerInterruptPoll(vm)
+
 	return vm, err
 }
 
@@ -305,6 +317,18 @@ func LuaRunAndReport(vm *golua.State, s string) {
 	}
 }
 
+// LuaRunGoroutines runs the goroutine scheduler from
+// goroutines.lua until all goroutines have either
+// finished or are blocked.
+func LuaRunGoroutines(vm *golua.State) error {
+	vm.GetGlobal("__gi_schedule")
+	err := vm.Call(0, 0)
+	if err != nil {
+		vm.Pop(1)
+	}
+	return err
+}
+
 func dumpTableString(L *golua.State, index int) (s string) {
 
 	// Push another reference to the table on top of the stack (so we know
@@ -0,0 +1,80 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test650LuaPackageReachesLuaLibraries(t *testing.T) {
+
+	cv.Convey(`typed Go code reaches a Lua library, such as one loaded with :do, through package lua, whose calls compile to plain table operations`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		// as if loaded with :do
+		LuaRunAndReport(vm, `
+shapes = {names = {"circle", "square"}}
+function shapes.area(w, h) return w * h end
+function shapes.describe(self, what) return what .. " of " .. #self.names end
+`)
+
+		code := `import "github.com/gijit/gi/lua"
+shapes := lua.Global.Get("shapes")
+a := shapes.Call("area", 3, 4).Int()`
+		translation := inc.Tr([]byte(code))
+		cv.So(string(translation), cv.ShouldMatchModuloWhiteSpace, `
+shapes = _G["shapes"];
+a = __gi_luaInt64(shapes["area"](3, 4));`)
+		LuaRunAndReport(vm, string(translation))
+
+		translation = inc.Tr([]byte(`
+w := 2.5
+f := shapes.Call("area", w, 2).Float()
+n := shapes.Get("names").Len()
+second := shapes.Get("names").Index(2).String()
+d := shapes.Call("describe", shapes, "list").String()
+shapes.Set("count", n)
+shapes.Set("kept", n)
+count := shapes.Get("count").Int64()
+shapes.Get("names").SetIndex(3, "hexagon")
+last := shapes.Get("names").Index(n+1).String()
+args := []interface{}{5, 6}
+a2 := shapes.Get("area").Invoke(args...).Int()
+shapes.Delete("count")
+gone := shapes.Get("count") == nil
+truth := lua.Global.Get("shapes").Bool()
+var none *lua.Object
+isNil := none == nil
+`))
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustInt64(vm, "a", 12)
+		LuaMustFloat64(vm, "f", 5)
+		LuaMustInt64(vm, "n", 2)
+		LuaMustString(vm, "second", "square")
+		LuaMustString(vm, "d", "list of 2")
+		LuaMustInt64(vm, "count", 2)
+		LuaMustString(vm, "last", "hexagon")
+		LuaMustInt64(vm, "a2", 30)
+		LuaMustBool(vm, "gone", true)
+		LuaMustBool(vm, "truth", true)
+		LuaMustBool(vm, "isNil", true)
+
+		// Go integers arrive in Lua as numbers, not int64 cdata.
+		LuaRunAndReport(vm, `keptType = type(shapes.kept)`)
+		LuaMustString(vm, "keptType", "number")
+
+		// a *lua.Object can be kept in a struct.
+		translation = inc.Tr([]byte(`
+type holder struct { O *lua.Object }
+h := &holder{O: shapes}
+h2 := h.O.Call("area", 7, 1).Int()
+`))
+		LuaRunAndReport(vm, string(translation))
+		LuaMustInt64(vm, "h2", 7)
+	})
+}
@@ -0,0 +1,79 @@
+-- the runtime side of package lua, github.com/gijit/gi/lua.
+-- The translator turns calls on *lua.Object into plain
+-- table operations; these helpers convert the values
+-- that cross between Go and Lua.
+
+local ffi = require("ffi")
+local i64 = ffi.typeof("int64_t")
+local u64 = ffi.typeof("uint64_t")
+
+local function isInt64(x)
+   return type(x) == "cdata" and (ffi.istype(i64, x) or ffi.istype(u64, x))
+end
+
+-- __gi_toLua hands a Go value to Lua. Go's integers
+-- are int64_t or uint64_t cdata, which Lua libraries
+-- do not expect, so they become numbers. Everything
+-- else passes as it is.
+function __gi_toLua(x)
+   if isInt64(x) then
+      return tonumber(x)
+   end
+   return x
+end
+
+-- __gi_toLuaArgs spreads the Go slice s into
+-- arguments for a Lua function.
+function __gi_toLuaArgs(s)
+   local n = tonumber(#s)
+   local args = {}
+   for i = 0, n - 1 do
+      args[i + 1] = __gi_toLua(s[i])
+   end
+   return unpack(args, 1, n)
+end
+
+-- __gi_luaInt64 is lua.Object.Int64: x as a number,
+-- truncated; or 0.
+function __gi_luaInt64(x)
+   if isInt64(x) then
+      return i64(x)
+   end
+   local n = tonumber(x)
+   if n == nil or n ~= n then
+      return 0LL
+   end
+   return i64(n)
+end
+
+-- __gi_luaFloat is lua.Object.Float: x as a number, or 0.
+function __gi_luaFloat(x)
+   return tonumber(x) or 0
+end
+
+-- __gi_luaString is lua.Object.String.
+function __gi_luaString(x)
+   if type(x) == "string" then
+      return x
+   end
+   if isInt64(x) then
+      return (tostring(x):gsub("U?LL$", ""))
+   end
+   return tostring(x)
+end
+
+-- a *lua.Object in a struct field, or in a composite
+-- type, needs a type: the translator refers to
+-- __type__lua.Object. It is made when first used,
+-- after struct.lua has loaded.
+__type__lua = setmetatable({}, {
+      __index = function(t, k)
+         if k ~= "Object" then
+            return nil
+         end
+         local typ = __gi_NewType(8, __gi_kind_Struct, "lua", "Object", "lua.Object", true, "github.com/gijit/gi/lua", true, nil)
+         typ.__init("", {})
+         rawset(t, k, typ)
+         return typ
+      end
+})
@@ -7,6 +7,19 @@
 -- and nearly empty proxy. The only thing the proxy
 -- has in it are a pointer to the actual data,
 -- and a len counter.
+--
+-- Go keys are not used as Lua keys directly: 3LL and
+-- 3LL are two different cdata, and so two different
+-- Lua keys, and two equal structs are two different
+-- tables. Instead each map hashes its keys, by their
+-- Go type and value, into a Lua string or number;
+-- see __gi_mapKeyFor. The raw data then keeps two
+-- tables indexed by that hash: keys, holding the Go
+-- key, and vals, holding the value.
+
+local ffi = require("ffi")
+local i64 = ffi.typeof("int64_t")
+local u64 = ffi.typeof("uint64_t")
 
 -- create private index
 _giPrivateMapRaw = {}
@@ -16,48 +29,185 @@ _giPrivateMapProps = {}
 -- we can recognized stored nil values in maps.
 _intentionalNilValue = {}
 
- _giPrivateMapMt = {
+-- the hash of a nil key, which Lua cannot
+-- use as a table key. Range gives it in place
+-- of the nil key too, since a nil first value
+-- ends a Lua for loop; the translated range
+-- turns it back into nil.
+__gi_nilMapKey = {}
+local nilKey = __gi_nilMapKey
+
+-- NaN is never equal to itself, so each NaN key
+-- gets a hash of its own, and can never be found
+-- again; just as in Go.
+local nanCount = 0
+local function nanKey()
+   nanCount = nanCount + 1
+   return "NaN#" .. nanCount
+end
+
+-- values compared by identity, such as pointers
+-- and channels, are numbered on first use.
+local idOf = setmetatable({}, {__mode = "k"})
+local idCount = 0
+local function identity(x)
+   local id = idOf[x]
+   if id == nil then
+      idCount = idCount + 1
+      id = "#" .. idCount
+      idOf[x] = id
+   end
+   return id
+end
+
+-- __gi_valueKey hashes a value of any type into a
+-- string, tagged by its type, so that 1LL, 1ULL,
+-- 1.0 and "1" are four different keys. Structs
+-- and arrays hash by value, field by field and
+-- element by element. This is the hash for
+-- interface keys, and for struct and array keys.
+--
+-- Limitation: basic values carry no Go type at
+-- run time, only their Lua representation. So as
+-- interface keys, int(1) and int32(1) are the same
+-- key, as are uint8(1) and uint64(1), and a
+-- float32 and a float64 of equal value. Struct
+-- keys do carry their type, and so stay distinct.
+function __gi_valueKey(x)
+   local ty = type(x)
+   if x == nil then
+      return "nil"
+   elseif ty == "string" then
+      return "s" .. #x .. ":" .. x
+   elseif ty == "number" then
+      if x ~= x then
+         return nanKey()
+      end
+      if x == 0 then
+         -- +0 == -0
+         x = 0
+      end
+      return "f" .. string.format("%.17g", x)
+   elseif ty == "boolean" then
+      return x and "T" or "F"
+   elseif ty == "cdata" then
+      if ffi.istype(u64, x) then
+         return "u" .. tostring(x)
+      elseif ffi.istype(i64, x) then
+         return "i" .. tostring(x)
+      elseif complex128 ~= nil and
+      (ffi.istype(complex128, x) or ffi.istype(complex64, x)) then
+         return "z" .. __gi_valueKey(x.re) .. __gi_valueKey(x.im)
+      end
+      return "c" .. tostring(x)
+   elseif ty == "function" then
+      error("runtime error: hash of unhashable type func")
+   end
 
-    __newindex = function(t, k, v)
-       --print("newindex called for key", k, " len at start is ", len)
+   -- tables
+   if rawget(x, _giPrivateMapProps) ~= nil then
+      error("runtime error: hash of unhashable type map")
+   end
+   if rawget(x, _giPrivateSliceProps) ~= nil then
+      error("runtime error: hash of unhashable type slice")
+   end
+   local aprops = rawget(x, _giPrivateArrayProps)
+   if aprops ~= nil then
+      local raw = rawget(x, _giPrivateRaw)
+      local parts = {}
+      for i = 0, aprops.len - 1 do
+         parts[i+1] = __gi_valueKey(raw[i])
+      end
+      return "a" .. aprops.len .. "(" .. table.concat(parts, ",") .. ")"
+   end
+   local mt = getmetatable(x)
+   local typ = type(mt) == "table" and mt[__gi_PropsKey]
+   if typ and typ.__fields ~= nil then
+      -- a struct; its type object gives the fields.
+      local parts = {}
+      for i, f in ipairs(typ.__fields) do
+         parts[i] = __gi_valueKey(x[f.__prop])
+      end
+      return "S" .. identity(typ) .. "{" .. table.concat(parts, ",") .. "}"
+   end
+   return identity(x)
+end
+
+-- __gi_mapKeyFor returns the hash function for keys
+-- of the given kind. Keys of a single basic type need
+-- no type tag; and strings, numbers and booleans
+-- already make good Lua keys.
+function __gi_mapKeyFor(kind)
+   if kind == __gi_kind_int or kind == __gi_kind_int8 or
+      kind == __gi_kind_int16 or kind == __gi_kind_int32 or
+   kind == __gi_kind_int64 then
+      -- also takes the Lua numbers that len() gives.
+      return function(k) return tostring(i64(k)) end
+
+   elseif kind == __gi_kind_uint or kind == __gi_kind_uint8 or
+      kind == __gi_kind_uint16 or kind == __gi_kind_uint32 or
+   kind == __gi_kind_uint64 or kind == __gi_kind_uintptr then
+      return function(k) return tostring(u64(k)) end
+
+   elseif kind == __gi_kind_float32 or kind == __gi_kind_float64 then
+      return function(k)
+         if k ~= k then
+            return nanKey()
+         end
+         if k == 0 then
+            return 0
+         end
+         return k
+      end
 
-       local props = t[_giPrivateMapProps]
-       local len = props.len
+   elseif kind == __gi_kind_String or kind == __gi_kind_bool then
+      return function(k) return k end
 
-       if k == nil then
-          if props.nilKeyStored then
-             -- replacement, no change in len.
-          else
-             -- new key
-             props.len = len + 1
-             props.nilKeyStored = true
-          end
-          props.nilValue = v
-          return
-       end
+   elseif kind == __gi_kind_Ptr or kind == __gi_kind_Chan or
+   kind == __gi_kind_UnsafePointer then
+      return function(k) return k end
+   end
 
-       -- invar: k is not nil
+   -- interfaces, structs and arrays.
+   return __gi_valueKey
+end
+
+-- the random source for map iteration order;
+-- kept apart from math.random, which user code
+-- may have seeded for its own purposes.
+local iterSeed = (os.time() * 69069 + math.floor(os.clock() * 1e6)) % 2147483647
+if iterSeed == 0 then
+   iterSeed = 1
+end
+local function iterRandom(n)
+   -- Park-Miller minimal standard generator
+   iterSeed = (iterSeed * 16807) % 2147483647
+   return iterSeed % n + 1
+end
 
-       ks = tostring(k)
-       if v ~= nil then
-          if t[_giPrivateMapRaw][ks] == nil then
-             -- new key
-             props.len = len + 1
-          end
-          t[_giPrivateMapRaw][ks] = v
-          return
+ _giPrivateMapMt = {
 
-       else
-          -- invar: k is not nil. v is nil.
+    __newindex = function(t, k, v)
+       local props = t[_giPrivateMapProps]
+       local raw = t[_giPrivateMapRaw]
 
-          if t[_giPrivateMapRaw][ks] == nil then
-             -- new key
-             props.len = len + 1
-          end
-          t[_giPrivateMapRaw][ks] = _intentionalNilValue
-          return
-      end
-      --print("len at end of newidnex is ", len)
+       local h = nilKey
+       if k ~= nil then
+          h = props.keyFor(k)
+       end
+       if raw.keys[h] == nil then
+          -- new key
+          props.len = props.len + 1
+       end
+       if k == nil then
+          raw.keys[h] = nilKey
+       else
+          raw.keys[h] = k
+       end
+       if v == nil then
+          v = _intentionalNilValue
+       end
+       raw.vals[h] = v
     end,
 
     __index = function(t, k)
@@ -66,25 +216,7 @@ _intentionalNilValue = {}
        --  proper zero-value return upon not present.
        -- __index only ever returns one value[1].
        -- reference: [1] http://lua-users.org/lists/lua-l/2007-07/msg00182.html
-              
-       --print("__index called for key", k)
-       if k == nil then
-          local props = t[_giPrivateMapProps]
-          if props.nilKeyStored then
-             return props.nilValue
-          else
-             -- TODO: replace nil with zero-value for the value type.
-             return nil
-          end
-       end
-
-       -- k is not nil.
-
-       ks = tostring(k)       
-       local val = t[_giPrivateMapRaw][ks]
-       if val == _intentionalNilValue then
-          return nil
-       end
+       local val = t('get', k)
        return val
     end,
 
@@ -92,11 +224,12 @@ _intentionalNilValue = {}
        local props = t[_giPrivateMapProps]
        local len = props["len"]
        local s = "map["..props["keyType"].. "]"..props["valType"].." of length " .. tostring(len) .. " is _giMap{"
-       local r = t[_giPrivateMapRaw]
-       -- we want to skip both the _giPrivateMapRaw and the len
-       -- when iterating, which happens automatically if we
-       -- iterate on r, the inside private data, and not on the proxy.
-       for i, _ in pairs(r) do s = s .. "["..tostring(i).."]" .. "= " .. tostring(r[i]) .. ", " end
+       for k, v in pairs(t) do
+          if k == nilKey then
+             k = nil
+          end
+          s = s .. "["..tostring(k).."]" .. "= " .. tostring(v) .. ", "
+       end
        return s .. "}"
     end,
 
@@ -107,117 +240,104 @@ _intentionalNilValue = {}
     end,
 
     __pairs = function(t)
-       -- print("__pairs called!")
        -- this makes a _giMap work in a for k,v in pairs() do loop.
-
-       -- Iterator function takes the table and an index and returns the next index and associated value
-       -- or nil to end iteration
-
-       local function stateless_iter(t, k)
-           local v
-           --  Implement your own key,value selection logic in place of next
-           ks = tostring(k)
-           ks, v = next(t[_giPrivateMapRaw], tostring(k))
-           if v then return ks,v end
+       --
+       -- As in Go, the order is random, and changes from
+       -- one range to the next. We take a shuffled
+       -- snapshot of the hashes present at the start;
+       -- entries deleted before we reach them are
+       -- skipped, and entries added meanwhile are not
+       -- visited. A nil key ends a Lua for loop, so it
+       -- is given as __gi_nilMapKey instead.
+
+       local raw = t[_giPrivateMapRaw]
+       local order = {}
+       local n = 0
+       for h, _ in pairs(raw.keys) do
+          n = n + 1
+          order[n] = h
+       end
+       for i = n, 2, -1 do
+          local j = iterRandom(i)
+          order[i], order[j] = order[j], order[i]
        end
 
-       -- Return an iterator function, the table, starting point
-       return stateless_iter, t, nil
+       local i = 0
+       local function iter(t, _)
+          while i < n do
+             i = i + 1
+             local h = order[i]
+             local k = raw.keys[h]
+             if k ~= nil then
+                local v = raw.vals[h]
+                if v == _intentionalNilValue then
+                   v = nil
+                end
+                return k, v
+             end
+          end
+          return nil
+       end
+       return iter, t, nil
     end,
 
     __call = function(t, ...)
         --print("__call() invoked, with ... = ", ...)
         local oper, k, zeroVal = ...
-        --print("oper is", oper)
-        --print("key is ", k)
+
+        local props = t[_giPrivateMapProps]
+        local raw = t[_giPrivateMapRaw]
+        local h = nilKey
+        if k ~= nil then
+           h = props.keyFor(k)
+        end
 
         -- we use __call('get', k, zeroVal) instead of __index
         -- so that we can return multiple values
         -- to match Go's `a, ok := mymap[k]` call.
-        
+
         if oper == "get" then
 
-           --print("get called for key", k)
-           if k == nil then
-              local props = t[_giPrivateMapProps]
-              if props.nilKeyStored then
-                 return props.nilValue, true;
-              else
-                 -- key not present returns the zero value for the value.
-                 return zeroVal, false;
-              end
-           end
-           
-           -- k is not nil.
-           ks = tostring(k)      
-           local val = t[_giPrivateMapRaw][ks]
+           local val = raw.vals[h]
            if val == _intentionalNilValue then
-              --print("val is the _intentinoalNilValue")
               return nil, true;
-
            elseif val == nil then
-              -- key not present
-              --print("key not present, zeroVal=", zeroVal)
-              --for i,v in pairs(t[_giPrivateMapRaw]) do
-              --   print("debug: i=", i, "  v=", v)
-              --end
+              -- key not present returns the zero value for the value.
               return zeroVal, false;
            end
-           
            return val, true
-           
+
         elseif oper == "delete" then
 
            -- the hash table delete operation
 
-           local props = t[_giPrivateMapProps]              
-           local len = props.len
-           --print("delete called for key", k, " len at start is ", len)
-                      
-           if k == nil then
-
-              if props.nilKeyStored then
-                 props.nilKeyStored = false
-                 props.nilVaue = nil
-                 props.len = len -1
-              end
-
-              --print("len at end of delete is ", props.len)              
-              return
-           end
-
-           ks = tostring(k)           
-           if t[_giPrivateMapRaw][ks] == nil then
+           if raw.keys[h] == nil then
               -- key not present
               return
            end
-           
-           -- key present and key is not nil
-           t[_giPrivateMapRaw][ks] = nil
-           props.len = len - 1
-           
-           --print("len at end of delete is ", props.len)
+           raw.keys[h] = nil
+           raw.vals[h] = nil
+           props.len = props.len - 1
         end
     end
  }
- 
-function _gi_NewMap(keyType, valType, x)
-   assert(type(x) == 'table', 'bad parameter #1: must be table')
 
-   local proxy = {}
-   proxy["Typeof"]="_gi_Map"
-   proxy[_giPrivateMapRaw] = x
+-- _gi_NewMap makes a map from keyType to valType,
+-- whose keys are of the given __gi_kind, holding the
+-- entries in x, a list of {key, value} pairs.
+function _gi_NewMap(keyType, valType, keyKind, x)
+   assert(type(x) == 'table', 'bad parameter #4: must be table')
 
-   -- get initial count
-   local len = 0
-   for k, v in pairs(x) do
-      len = len + 1
-   end
+   local proxy = {}
+   proxy[_giPrivateMapRaw] = {keys={}, vals={}}
 
-   local props = {len=len, keyType=keyType, valType=valType, nilKeyStored=false}
+   local props = {len=0, keyType=keyType, valType=valType, keyKind=keyKind, keyFor=__gi_mapKeyFor(keyKind)}
    proxy[_giPrivateMapProps] = props
 
    setmetatable(proxy, _giPrivateMapMt)
+
+   for _, e in ipairs(x) do
+      proxy[e[1]] = e[2]
+   end
    return proxy
 end;
-
@@ -0,0 +1,161 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test570MapKeysHashByTypeAndValue(t *testing.T) {
+
+	cv.Convey(`in a map[interface{}]int the keys 1, "1", 1.0 and uint(1) are all different; string keys work from literals and variables`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+m := map[interface{}]int{}
+m[1] = 1
+m["1"] = 2
+m[1.0] = 3
+m[uint(1)] = 4
+m[1] = 5
+
+s := map[string]int{"a": 1}
+k := "b"
+s[k] = 2
+s[k] += 10
+`)
+		panicOn(err)
+
+		expect := func(expr string, want interface{}) {
+			vals, err := in.Eval(expr)
+			panicOn(err)
+			cv.So(vals[0].Interface(), cv.ShouldEqual, want)
+		}
+		expect(`len(m)`, 4)
+		expect(`m[1]`, 5)
+		expect(`m["1"]`, 2)
+		expect(`m[1.0]`, 3)
+		expect(`s["a"]`, 1)
+		expect(`s["b"]`, 12)
+		expect(`len(s)`, 2)
+
+		vals, err := in.Eval(`s["zed"]`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 0)
+	})
+}
+
+func Test571StructAndArrayKeysHashByValue(t *testing.T) {
+
+	cv.Convey(`equal structs and equal arrays are the same map key, though they are different Lua tables`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+type pt struct { X, Y int }
+m := map[pt]string{pt{1, 2}: "a"}
+m[pt{2, 1}] = "b"
+m[pt{1, 2}] = "c"
+a := map[[2]int]int{}
+a[[2]int{3, 4}] = 7
+`)
+		panicOn(err)
+
+		vals, err := in.Eval(`len(m)`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 2)
+
+		vals, err = in.Eval(`m[pt{1, 2}]`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, "c")
+
+		vals, err = in.Eval(`a[[2]int{3, 4}]`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 7)
+	})
+}
+
+func Test572RangeIsRandomAndAllowsDelete(t *testing.T) {
+
+	cv.Convey(`range over a map visits each entry once, in an order that changes from one range to the next, and may delete as it goes`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+m := make(map[int]int)
+for i := 0; i < 20; i++ {
+	m[i] = i
+}
+firsts := make(map[int]bool)
+for j := 0; j < 20; j++ {
+	for k := range m {
+		firsts[k] = true
+		break
+	}
+}
+sum := 0
+for k, v := range m {
+	if k % 2 == 0 {
+		delete(m, k)
+	}
+	sum += v
+}
+`)
+		panicOn(err)
+
+		vals, err := in.Eval(`len(firsts) > 1`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldBeTrue)
+
+		vals, err = in.Eval(`sum`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 190)
+
+		vals, err = in.Eval(`len(m)`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 10)
+	})
+}
+
+func Test573RangeVisitsTheNilInterfaceKey(t *testing.T) {
+
+	cv.Convey(`range over a map[interface{}]int visits the nil key, and gives it as nil`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+m := map[interface{}]int{}
+m[nil] = 5
+m[1] = 6
+m["a"] = 7
+n := 0
+sum := 0
+sawNil := false
+for k, v := range m {
+	n++
+	sum += v
+	if k == nil {
+		sawNil = true
+	}
+}
+`)
+		panicOn(err)
+
+		vals, err := in.Eval(`n == len(m)`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldBeTrue)
+
+		vals, err = in.Eval(`sum`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldEqual, 18)
+
+		vals, err = in.Eval(`sawNil`)
+		panicOn(err)
+		cv.So(vals[0].Interface(), cv.ShouldBeTrue)
+	})
+}
@@ -19,14 +19,3 @@ __truncateToInt = function(x)
    end
    return x + (-x % 1)
 end
-
-__integerByZeroCheck = function(x)
-   if not math.finite(x) then
-      error("integer divide by zero")
-   end
-   -- eliminate any fractional part
-   if x >= 0 then
-       return x - (x % 1)
-   end
-   return x + (-x % 1)
-end
@@ -97,11 +97,27 @@ type flowData struct {
 	postStmt  func()
 	beginCase int
 	endCase   int
+
+	// jea: Lua has neither continue nor labeled
+	// break, so both become a goto. continueLabel
+	// ends the body of a loop that has a continue;
+	// breakLabel follows the statement, and is
+	// only placed if a goto used it.
+	isLoop        bool
+	breakLabel    string
+	breakUsed     bool
+	continueLabel string
 }
 
 type ImportContext struct {
 	Packages map[string]*types.Package
 	Import   func(string) (*Archive, error)
+
+	// PkgTables holds the import paths of the packages,
+	// among Packages, that were compiled to Lua at the
+	// REPL, and so live in a package table; see
+	// pkgTableVar.
+	PkgTables map[string]bool
 }
 
 // packageImporter implements go/types.Importer interface.
@@ -175,7 +191,7 @@ func (c *funcContext) initArgs(ty types.Type) string {
 			if !field.Exported() {
 				pkgPath = field.Pkg().Path()
 			}
-			fields[i] = fmt.Sprintf(`{__prop= "%s", __name= "%s", __anonymous= %t, __exported= %t, __typ= %s, __tag= %s}`, fieldName(t, i), field.Name(), field.Anonymous(), field.Exported(), c.typeName(field.Type()), encodeString(t.Tag(i)))
+			fields[i] = fmt.Sprintf(`{__prop= "%s", __name= "%s", __anonymous= %t, __exported= %t, __typ= %s, __tag= %s, __typstr= %s}`, fieldName(t, i), field.Name(), field.Anonymous(), field.Exported(), c.fieldTypeName(field.Type()), encodeString(t.Tag(i)), encodeString(printTypeString(field.Type())))
 		}
 		return fmt.Sprintf(`"%s", {%s}`, pkgPath, strings.Join(fields, ", "))
 	default:
@@ -183,6 +199,18 @@ func (c *funcContext) initArgs(ty types.Type) string {
 	}
 }
 
+// fieldTypeName is typeName for a struct field's __typ:
+// a named type declared at the REPL is its __type__
+// object, so that the runtime can follow embedded
+// fields to their methods.
+func (c *funcContext) fieldTypeName(ty types.Type) string {
+	name := c.typeName(ty)
+	if named, ok := ty.(*types.Named); ok && named.Obj().Pkg() == c.p.Pkg {
+		return "__type__" + name
+	}
+	return name
+}
+
 func (c *funcContext) translateToplevelFunction(fun *ast.FuncDecl, info *analysis.FuncInfo) []byte {
 	defer func() {
 		pp("WHOPPER func done")
@@ -391,20 +419,9 @@ func translateFunction(typ *ast.FuncType, recv *ast.Ident, body *ast.BlockStmt,
 		}
 	}
 
-	if len(c.Blocking) != 0 {
-		c.localVars = append(c.localVars, "$r")
-		if funcRef == "" {
-			funcRef = "$b"
-			functionName = " $b"
-		}
-		var stores, loads string
-		for _, v := range c.localVars {
-			loads += fmt.Sprintf("%s = $f.%s; ", v, v)
-			stores += fmt.Sprintf("$f.%s = %s; ", v, v)
-		}
-		prefix = prefix + " var $f, $c = false; if (this !== undefined && this.$blk !== undefined) { $f = this; $c = true; " + loads + "}"
-		suffix = " if ($f === undefined) { $f = { $blk: " + funcRef + " }; } " + stores + "return $f;" + suffix
-	}
+	// jea: GopherJS saves and restores the locals of a
+	// blocking function here, so it can resume it. Our
+	// goroutines are coroutines, which resume by themselves.
 
 	if c.HasDefer {
 		prefix = prefix + " var $err = null; try {"
@@ -0,0 +1,123 @@
+package compiler
+
+import (
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test500PackageDefinedAtReplIsImportableFromMain(t *testing.T) {
+
+	cv.Convey(`after :package foo, declarations go into foo; main can import "foo" and use its exported parts`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+
+		created, err := inc.SwitchPackage("foo")
+		panicOn(err)
+		cv.So(created, cv.ShouldBeTrue)
+
+		translation := inc.Tr([]byte(`
+type Point struct {
+	X, Y int
+}
+func (p *Point) Sum() int {
+	return p.X + p.Y + bonus
+}
+var bonus = 100
+const Scale = 10
+func Double(x int) int {
+	return 2 * x
+}
+`))
+		pp("foo translation='%s'", string(translation))
+		LuaRunAndReport(vm, string(translation))
+
+		created, err = inc.SwitchPackage("main")
+		panicOn(err)
+		cv.So(created, cv.ShouldBeFalse)
+
+		translation = inc.Tr([]byte(`
+import "foo"
+bonus := 1
+d := foo.Double(21)
+pt := &foo.Point{X: 1, Y: 2}
+s := pt.Sum()
+sc := foo.Scale * 2
+`))
+		pp("main translation='%s'", string(translation))
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustInt64(vm, "d", 42)
+		LuaMustInt64(vm, "s", 103)
+		LuaMustInt64(vm, "sc", 20)
+		// main's bonus and foo's bonus are different variables.
+		LuaMustInt64(vm, "bonus", 1)
+
+		// going back into foo, its state is still there.
+		_, err = inc.SwitchPackage("foo")
+		panicOn(err)
+		translation = inc.Tr([]byte(`bonus = Double(bonus)`))
+		LuaRunAndReport(vm, string(translation))
+		LuaRunAndReport(vm, `fooBonus = __gi_packages.foo.bonus`)
+		LuaMustInt64(vm, "fooBonus", 200)
+		LuaMustInt64(vm, "bonus", 1)
+	})
+}
+
+func Test501PackageSwitchingErrors(t *testing.T) {
+
+	cv.Convey(`bad package names are refused, and a package cannot import itself`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+
+		_, err = inc.SwitchPackage("func")
+		cv.So(err, cv.ShouldNotBeNil)
+		_, err = inc.SwitchPackage("9lives")
+		cv.So(err, cv.ShouldNotBeNil)
+		// the Lua libraries the runtime uses.
+		for _, name := range []string{"string", "table", "math", "bit", "coroutine", "__gi_x"} {
+			_, err = inc.SwitchPackage(name)
+			cv.So(err, cv.ShouldNotBeNil)
+		}
+		cv.So(inc.CurPkg.key, cv.ShouldEqual, "main")
+
+		_, err = inc.SwitchPackage("bar")
+		panicOn(err)
+		inc.Tr([]byte(`var A = 1`))
+		_, err = inc.GiImportFunc("bar")
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldContainSubstring, "import cycle")
+	})
+}
+
+func Test502PackageTablesDoNotReplaceGlobals(t *testing.T) {
+
+	cv.Convey(`a package made at the REPL lives in a table of its own, not in the global of its name, so a variable in main with that name is left alone`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		LuaRunAndReport(vm, string(inc.Tr([]byte(`util := 7`))))
+
+		_, err = inc.SwitchPackage("util")
+		panicOn(err)
+		LuaRunAndReport(vm, string(inc.Tr([]byte(`
+type Pt struct{ X int }
+func Twice(x int) int { return 2 * x }
+`))))
+
+		_, err = inc.SwitchPackage("main")
+		panicOn(err)
+		LuaMustInt64(vm, "util", 7)
+	})
+}
@@ -18,48 +18,436 @@ function _gi_SetRangeCheck(x, i, val)
 end;
 
 -- complex numbers
--- "complex[?]" ?
+--
+-- LuaJIT's own complex cdata, as in 1+2i, has no
+-- arithmetic, and its metatable is protected; so
+-- we keep complex values in structs of our own.
+-- complex64 holds floats, so that every result is
+-- rounded to float32, as Go does.
 
 ffi = require('ffi')
-point = ffi.metatype("struct { double re, im; }", {
-    __add = function(a, b)
-     return point(a.re + b.re, a.im + b.im)
- end
-})
-
--- 1+2i
--- if reloaded, we get this error, so comment out for now.
--- prelude.lua:34: cannot change a protected metatable
--- See also https://stackoverflow.com/questions/325323/is-there-anyway-to-avoid-this-security-issue-in-lua
---[[
-point = ffi.metatype("complex", {
-    __add = function(a, b)
-     return point(a.re + b.re, a.im + b.im)
- end
-})
---]]
-
-function _gi_NewComplex128(real, imag)
-
-end
-
-function __gijit_printQuoted(...)
-   a = {...}
-   --print("__gijit_printQuoted called, a = " .. tostring(a), " len=", #a)
-   if a[0] ~= nil then
-      if type(v) == "string" then
-         print("`"..v.."`")
-      else
-         print(v)
+
+local sqrt, exp, log, sin, cos = math.sqrt, math.exp, math.log, math.sin, math.cos
+local sinh, cosh, atan2, abs = math.sinh, math.cosh, math.atan2, math.abs
+local inf = math.huge
+
+local function copysign(x, y)
+   if y < 0 or (y == 0 and 1/y < 0) then
+      return -abs(x)
+   end
+   return abs(x)
+end
+
+local function isinf(x) return x == inf or x == -inf end
+local function isfinite(x) return x == x and not isinf(x) end
+local function b2f(b) if b then return 1 end return 0 end
+
+-- Smith's algorithm, with the C99 corrections
+-- for infinities and zeros; as the Go runtime's
+-- complex128div.
+local function cdiv(a, b, c, d)
+   local e, f
+   if abs(c) >= abs(d) then
+      local ratio = d / c
+      local denom = c + ratio*d
+      e = (a + b*ratio) / denom
+      f = (b - a*ratio) / denom
+   else
+      local ratio = c / d
+      local denom = d + ratio*c
+      e = (a*ratio + b) / denom
+      f = (b*ratio - a) / denom
+   end
+   if e ~= e and f ~= f then
+      if c == 0 and d == 0 and (a == a or b == b) then
+         e = copysign(inf, c) * a
+         f = copysign(inf, c) * b
+      elseif (isinf(a) or isinf(b)) and isfinite(c) and isfinite(d) then
+         a = copysign(b2f(isinf(a)), a)
+         b = copysign(b2f(isinf(b)), b)
+         e = inf * (a*c + b*d)
+         f = inf * (b*c - a*d)
+      elseif (isinf(c) or isinf(d)) and isfinite(a) and isfinite(b) then
+         c = copysign(b2f(isinf(c)), c)
+         d = copysign(b2f(isinf(d)), d)
+         e = 0 * (a*c + b*d)
+         f = 0 * (b*c - a*d)
+      end
+   end
+   return e, f
+end
+
+-- __gi_formatFloat gives the shortest decimal that
+-- reads back as x, laid out as Go's %v does: in %e
+-- form when the exponent is below -4 or above 5,
+-- otherwise in %f form. Infinities always carry
+-- their sign.
+local f32 = ffi.typeof("float")
+function __gi_formatFloat(x, bits32, plus)
+   local sign = ""
+   if x ~= x then
+      return plus and "+NaN" or "NaN"
+   elseif x < 0 or (x == 0 and 1/x < 0) then
+      sign = "-"
+      x = -x
+   elseif plus then
+      sign = "+"
+   end
+   if x == inf then
+      if sign == "" then
+         sign = "+"
+      end
+      return sign .. "Inf"
+   end
+   local digits, e
+   for p = 0, 16 do
+      local s = string.format("%." .. p .. "e", x)
+      local y = tonumber(s)
+      if bits32 then
+         y = tonumber(f32(y))
+      end
+      if y == x then
+         local m, ex = s:match("^([%d%.]+)e([-+]%d+)$")
+         digits = m:gsub("%.", ""):gsub("0+$", "")
+         e = tonumber(ex)
+         break
       end
    end
-   for i,v in ipairs(a) do
-      if type(v) == "string" then
-         print("`"..v.."`")
-      else
-         print(v)
+   if digits == nil or digits == "" then
+      return sign .. "0"
+   end
+   if e < -4 or e >= 6 then
+      local s = digits:sub(1, 1)
+      if #digits > 1 then
+         s = s .. "." .. digits:sub(2)
+      end
+      local es = "+"
+      if e < 0 then
+         es = "-"
+         e = -e
       end
+      return sign .. s .. "e" .. es .. string.format("%02d", e)
    end
+   if e < 0 then
+      return sign .. "0." .. string.rep("0", -e-1) .. digits
+   end
+   if #digits <= e+1 then
+      return sign .. digits .. string.rep("0", e+1-#digits)
+   end
+   return sign .. digits:sub(1, e+1) .. "." .. digits:sub(e+2)
 end
 
+local function complexType(decl, bits32)
+   local ct
+   local function parts(x)
+      if type(x) == "number" then
+         return x, 0
+      end
+      return x.re, x.im
+   end
+   ct = ffi.metatype(decl, {
+      __add = function(x, y)
+         local a, b = parts(x)
+         local c, d = parts(y)
+         return ct(a+c, b+d)
+      end,
+      __sub = function(x, y)
+         local a, b = parts(x)
+         local c, d = parts(y)
+         return ct(a-c, b-d)
+      end,
+      __mul = function(x, y)
+         local a, b = parts(x)
+         local c, d = parts(y)
+         return ct(a*c - b*d, a*d + b*c)
+      end,
+      __div = function(x, y)
+         local a, b = parts(x)
+         local c, d = parts(y)
+         return ct(cdiv(a, b, c, d))
+      end,
+      __unm = function(x)
+         return ct(-x.re, -x.im)
+      end,
+      __eq = function(x, y)
+         if (type(x) ~= "cdata" and type(x) ~= "number") or
+         (type(y) ~= "cdata" and type(y) ~= "number") then
+            return false
+         end
+         local a, b = parts(x)
+         local c, d = parts(y)
+         return a == c and b == d
+      end,
+      __tostring = function(x)
+         return "(" .. __gi_formatFloat(x.re, bits32) .. __gi_formatFloat(x.im, bits32, true) .. "i)"
+      end,
+   })
+   return ct
+end
+
+complex128 = complexType("struct { double re, im; }", false)
+complex64 = complexType("struct { float re, im; }", true)
 
+-- conversions between the two.
+function __gi_complex128(x) return complex128(x.re, x.im) end
+function __gi_complex64(x) return complex64(x.re, x.im) end
+
+-- math/cmplx, written in Lua, so that complex
+-- values need not cross into Go.
+cmplx = {}
+
+local function abs2(a, b)
+   -- hypot, without overflow.
+   a, b = abs(a), abs(b)
+   if isinf(a) or isinf(b) then
+      return inf
+   end
+   if a < b then
+      a, b = b, a
+   end
+   if a == 0 then
+      return 0
+   end
+   local q = b / a
+   return a * sqrt(1 + q*q)
+end
+
+function cmplx.Abs(x) return abs2(x.re, x.im) end
+function cmplx.Phase(x) return atan2(x.im, x.re) end
+function cmplx.Polar(x) return abs2(x.re, x.im), atan2(x.im, x.re) end
+function cmplx.Rect(r, theta) return complex128(r*cos(theta), r*sin(theta)) end
+function cmplx.Conj(x) return complex128(x.re, -x.im) end
+function cmplx.Inf() return complex128(inf, inf) end
+function cmplx.NaN() return complex128(0/0, 0/0) end
+
+function cmplx.IsInf(x)
+   return isinf(x.re) or isinf(x.im)
+end
+
+function cmplx.IsNaN(x)
+   if cmplx.IsInf(x) then
+      return false
+   end
+   return x.re ~= x.re or x.im ~= x.im
+end
+
+function cmplx.Sqrt(x)
+   local a, b = x.re, x.im
+   if b == 0 then
+      if a == 0 then
+         return complex128(0, b)
+      end
+      if a < 0 then
+         return complex128(0, copysign(sqrt(-a), b))
+      end
+      return complex128(sqrt(a), b)
+   end
+   local r = abs2(a, b)
+   local t
+   if a >= 0 then
+      t = sqrt(0.5 * (r + a))
+      return complex128(t, 0.5 * b / t)
+   end
+   t = sqrt(0.5 * (r - a))
+   if b < 0 then
+      t = -t
+   end
+   return complex128(0.5 * b / t, t)
+end
+
+function cmplx.Exp(x)
+   local r = exp(x.re)
+   return complex128(r * cos(x.im), r * sin(x.im))
+end
+
+function cmplx.Log(x)
+   return complex128(log(abs2(x.re, x.im)), atan2(x.im, x.re))
+end
+
+function cmplx.Log10(x)
+   local z = cmplx.Log(x)
+   local log10e = 1 / log(10)
+   return complex128(log10e * z.re, log10e * z.im)
+end
+
+function cmplx.Pow(x, y)
+   if x.re == 0 and x.im == 0 then
+      if cmplx.IsNaN(y) then
+         return cmplx.NaN()
+      end
+      local r, i = y.re, y.im
+      if r == 0 then
+         return complex128(1, 0)
+      elseif r < 0 then
+         if i == 0 then
+            return complex128(inf, 0)
+         end
+         return cmplx.Inf()
+      end
+      return complex128(0, 0)
+   end
+   local modulus = abs2(x.re, x.im)
+   local r = modulus ^ y.re
+   local arg = atan2(x.im, x.re)
+   local theta = y.re * arg
+   if y.im ~= 0 then
+      r = r * exp(-y.im * arg)
+      theta = theta + y.im * log(modulus)
+   end
+   return complex128(r * cos(theta), r * sin(theta))
+end
+
+function cmplx.Sin(x)
+   return complex128(sin(x.re) * cosh(x.im), cos(x.re) * sinh(x.im))
+end
+
+function cmplx.Cos(x)
+   return complex128(cos(x.re) * cosh(x.im), -sin(x.re) * sinh(x.im))
+end
+
+function cmplx.Tan(x)
+   local d = cos(2*x.re) + cosh(2*x.im)
+   return complex128(sin(2*x.re) / d, sinh(2*x.im) / d)
+end
+
+function cmplx.Cot(x)
+   return complex128(1, 0) / cmplx.Tan(x)
+end
+
+function cmplx.Sinh(x)
+   return complex128(cos(x.im) * sinh(x.re), sin(x.im) * cosh(x.re))
+end
+
+function cmplx.Cosh(x)
+   return complex128(cos(x.im) * cosh(x.re), sin(x.im) * sinh(x.re))
+end
+
+function cmplx.Tanh(x)
+   local d = cosh(2*x.re) + cos(2*x.im)
+   return complex128(sinh(2*x.re) / d, sin(2*x.im) / d)
+end
+
+local one, i1 = complex128(1, 0), complex128(0, 1)
+
+function cmplx.Asin(x)
+   -- -i log(ix + sqrt(1 - x*x))
+   local w = cmplx.Log(i1*x + cmplx.Sqrt(one - x*x))
+   return complex128(w.im, -w.re)
+end
+
+function cmplx.Acos(x)
+   local w = cmplx.Asin(x)
+   return complex128(math.pi/2 - w.re, -w.im)
+end
+
+function cmplx.Atan(x)
+   -- (i/2) log((i + x)/(i - x))
+   local w = cmplx.Log((i1 + x) / (i1 - x))
+   return complex128(-0.5 * w.im, 0.5 * w.re)
+end
+
+function cmplx.Asinh(x)
+   return cmplx.Log(x + cmplx.Sqrt(x*x + one))
+end
+
+function cmplx.Acosh(x)
+   local w = cmplx.Acos(x)
+   if w.im <= 0 then
+      return complex128(-w.im, w.re)
+   end
+   return complex128(w.im, -w.re)
+end
+
+function cmplx.Atanh(x)
+   local w = cmplx.Log((one + x) / (one - x))
+   return complex128(0.5 * w.re, 0.5 * w.im)
+end
+
+-- packages other than main, created at the REPL
+-- with :package foo or imported from source, keep
+-- their top-level names in their own table, so
+-- foo.Bar can be reached from main after import
+-- "foo". Their code runs with that table as its
+-- environment; lookups that miss fall back to the
+-- globals, where the runtime lives.
+--
+-- Other packages reach the table through the global
+-- named by pkgTableVar in translate.go, made from the
+-- whole import path: foo.Bar is __gi_pkg_foo.Bar.
+__gi_packages = __gi_packages or {}
+
+function __gi_PackageEnv(path, global)
+   local env = __gi_packages[path]
+   if env == nil then
+      env = setmetatable({}, {__index = _G})
+      __gi_packages[path] = env
+      _G[global] = env
+      -- the translator writes the type of foo.T as
+      -- __type____gi_pkg_foo.T, but inside foo it
+      -- is __type__T.
+      _G["__type__"..global] = setmetatable({}, {
+            __index = function(t, k)
+               return env["__type__"..k]
+            end
+      })
+   end
+   return env
+end
+
+-- interrupts
+--
+-- __gi_poll begins each iteration of a loop, so that
+-- an Interrupt from Go stops even a loop that the JIT
+-- has compiled, where LuaJIT runs no hooks. Every
+-- 1000th call, it calls into C through the ffi, which
+-- the JIT will not hoist out of the loop as it would
+-- a load of a Lua value; counting first keeps tight
+-- loops fast. registerInterruptPoll in interrupt.go
+-- supplies the C function and its flag.
+
+local interruptPoll = function() return 0 end
+local interruptFlag = nil
+
+function __gi_setInterruptPoll(fn, flag)
+   interruptPoll = ffi.cast("int (*)(volatile int *)", fn)
+   interruptFlag = ffi.cast("volatile int *", flag)
+end
+
+-- __gi_interrupted reports whether an Interrupt from
+-- Go is pending.
+function __gi_interrupted()
+   return interruptFlag ~= nil and interruptPoll(interruptFlag) ~= 0
+end
+
+local ticks = 0
+
+function __gi_poll()
+   ticks = ticks + 1
+   if ticks < 1000 then
+      return
+   end
+   ticks = 0
+   if interruptPoll(interruptFlag) ~= 0 then
+      error("interrupted", 0)
+   end
+end
+
+-- errors
+--
+-- __gi_msgh is the message handler that CallChunk in
+-- srcmap.go runs code under. It notes the frames of
+-- the stack where the error was raised before xpcall
+-- unwinds them.
+function __gi_msgh(msg)
+   local frames = {}
+   local level = 2
+   while true do
+      local info = debug.getinfo(level, "Sln")
+      if info == nil then
+         break
+      end
+      table.insert(frames, info)
+      level = level + 1
+   end
+   return {msg = msg, frames = frames}
+end
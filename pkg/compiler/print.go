@@ -0,0 +1,107 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/types"
+	golua "github.com/glycerine/golua/lua"
+	"github.com/glycerine/luar"
+)
+
+// the REPL's value printer lives in print.lua; here
+// the translator hands it the Go types of the values,
+// and the REPL's :print command sets it up.
+
+// printTypeString gives t as fmt's %#v writes types:
+// main.pt, []interface {}, map[string]*main.pt.
+func printTypeString(t types.Type) string {
+	s := types.TypeString(t, func(p *types.Package) string {
+		return p.Name()
+	})
+	s = strings.Replace(s, "interface{", "interface {", -1)
+	s = strings.Replace(s, "struct{", "struct {", -1)
+	return s
+}
+
+// translateAnsTypes, after `__gijit_ans := []interface{}{...}`,
+// which the REPL writes for `= expr`, tells the printer
+// the Go types of the values, which the conversion to
+// interface{} would otherwise lose.
+func (c *funcContext) translateAnsTypes(rhs ast.Expr) {
+	lit, ok := rhs.(*ast.CompositeLit)
+	if !ok {
+		return
+	}
+	ts := make([]string, len(lit.Elts))
+	for i, elt := range lit.Elts {
+		ts[i] = c.ansTypeString(elt)
+	}
+	c.Printf("__gijit_ansTypes = {%s};", strings.Join(ts, ", "))
+}
+
+// ansTypeString gives the Go type of x as a Lua string
+// for __gijit_ansTypes; "" if not known.
+func (c *funcContext) ansTypeString(x ast.Expr) string {
+	t := c.p.TypeOf(x)
+	if t == nil || types.Identical(t, types.Typ[types.UntypedNil]) {
+		return `""`
+	}
+	return encodeString(printTypeString(types.Default(t)))
+}
+
+func registerPrintHelpers(vm *golua.State) {
+	vm.Register("__gi_goSprint", goSprint)
+}
+
+// goSprint prints the Go value that luar proxies at
+// stack position 2, with %#v if the boolean at 1 is
+// true, else with %v.
+func goSprint(L *golua.State) int {
+	verb := "%v"
+	if L.ToBoolean(1) {
+		verb = "%#v"
+	}
+	var v interface{}
+	err := luar.LuaToGo(L, 2, &v)
+	if err != nil {
+		L.PushString(fmt.Sprintf("?(%v)", err))
+		return 1
+	}
+	L.PushString(fmt.Sprintf(verb, v))
+	return 1
+}
+
+// SetPrintMode switches the REPL's value printer
+// between "go", which writes values as %#v does, in
+// Go syntax; and "compact", which writes them as %v
+// does.
+func SetPrintMode(vm *golua.State, mode string) error {
+	switch mode {
+	case "go", "compact":
+	default:
+		return fmt.Errorf("print mode must be go or compact, not '%s'", mode)
+	}
+	return vm.DoString(fmt.Sprintf(`__gi_printMode = %q`, mode))
+}
+
+// SetPrintLimit cuts off the printing of any value
+// at n bytes.
+func SetPrintLimit(vm *golua.State, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("print limit must be positive, not %v", n)
+	}
+	return vm.DoString(fmt.Sprintf(`__gi_printLimit = %d`, n))
+}
+
+// PrintSettings describes the printer's mode and limit.
+func PrintSettings(vm *golua.State) string {
+	top := vm.GetTop()
+	defer vm.SetTop(top)
+	err := vm.DoString(`return __gi_printMode .. ", limit " .. tostring(__gi_printLimit) .. " bytes"`)
+	if err != nil {
+		return err.Error()
+	}
+	return vm.ToString(-1)
+}
@@ -0,0 +1,506 @@
+-- the REPL's value printer.
+--
+-- Values are shown the way fmt shows them, either
+-- with %#v, in Go syntax (the default), or with %v,
+-- compactly; see :print. The Lua value alone does not
+-- say which Go type it has: 3LL may be an int or an
+-- int64, and a struct and a pointer to it are the
+-- same table. So the translator tells us the static
+-- Go type of each result, as a string such as
+-- "[]*main.pt", and we follow it down into the value.
+-- Where it runs out, at an interface, or at a named
+-- type that is not a struct, the runtime type objects
+-- take over: the metatable of a struct, the props of
+-- slices, arrays and maps, and the field list of
+-- each struct type.
+
+-- "go" for %#v, "compact" for %v.
+__gi_printMode = "go"
+
+-- output longer than this many bytes is cut off.
+__gi_printLimit = 4096
+
+local basicNames = {
+   bool=true, string=true,
+   int=true, int8=true, int16=true, int32=true, int64=true,
+   uint=true, uint8=true, uint16=true, uint32=true, uint64=true, uintptr=true,
+   float32=true, float64=true, complex64=true, complex128=true,
+   byte=true, rune=true,
+}
+
+local unsignedNames = {
+   uint=true, uint8=true, uint16=true, uint32=true, uint64=true, uintptr=true,
+   byte=true,
+}
+
+-- the translator's names for the element types
+-- of slices, arrays and maps.
+local kindNames = {
+   emptyInterface = "interface {}",
+}
+
+-- splitType takes apart the Go type string ts, giving
+-- its kind; and the element type, and for maps the
+-- key type.
+local function splitType(ts)
+   if ts == nil or ts == "" then
+      return nil
+   end
+   if string.sub(ts, 1, 1) == "*" then
+      return "ptr", string.sub(ts, 2)
+   end
+   if string.sub(ts, 1, 2) == "[]" then
+      return "slice", string.sub(ts, 3)
+   end
+   local n, elem = string.match(ts, "^%[(%d+)%](.*)$")
+   if n ~= nil then
+      return "array", elem
+   end
+   if string.sub(ts, 1, 4) == "map[" then
+      local depth = 0
+      for i = 4, #ts do
+         local c = string.sub(ts, i, i)
+         if c == "[" then
+            depth = depth + 1
+         elseif c == "]" then
+            depth = depth - 1
+            if depth == 0 then
+               return "map", string.sub(ts, i+1), string.sub(ts, 5, i-1)
+            end
+         end
+      end
+   end
+   if string.sub(ts, 1, 9) == "interface" or ts == "error" then
+      return "iface"
+   end
+   if string.sub(ts, 1, 4) == "func" or string.sub(ts, 1, 4) == "chan" or
+   string.sub(ts, 1, 6) == "<-chan" then
+      return "ref"
+   end
+   if basicNames[ts] then
+      return "basic"
+   end
+   return "named"
+end
+
+-- address gives the address of the table, function or
+-- userdata x, without calling its __tostring.
+local function address(x)
+   local s
+   if type(x) == "table" then
+      local mt = getmetatable(x)
+      setmetatable(x, nil)
+      s = tostring(x)
+      setmetatable(x, mt)
+   else
+      s = tostring(x)
+   end
+   return string.match(s, "0x%x+") or s
+end
+
+-- goQuote quotes s as strconv.Quote does, mostly.
+local function goQuote(s)
+   return '"' .. string.gsub(s, '[%c"\\]', function(c)
+      if c == '"' then return '\\"'
+      elseif c == "\\" then return "\\\\"
+      elseif c == "\n" then return "\\n"
+      elseif c == "\t" then return "\\t"
+      elseif c == "\r" then return "\\r"
+      end
+      return string.format("\\x%02x", string.byte(c))
+   end) .. '"'
+end
+
+local i64 = ffi.typeof("int64_t")
+local u64 = ffi.typeof("uint64_t")
+
+local function isInt64(x)
+   return type(x) == "cdata" and (ffi.istype(i64, x) or ffi.istype(u64, x))
+end
+
+local function isComplex(x)
+   return type(x) == "cdata" and (ffi.istype(complex128, x) or ffi.istype(complex64, x))
+end
+
+-- intString gives the integer x, a Lua number or an
+-- int64/uint64 cdata, in decimal; or in hex, as %#v
+-- shows unsigned integers.
+local function intString(x, hex)
+   if type(x) == "number" then
+      if hex then
+         return string.format("0x%x", x)
+      end
+      return string.format("%d", x)
+   end
+   if hex then
+      return "0x" .. string.gsub(bit.tohex(x, 16), "^0+(.)", "%1")
+   end
+   return (string.gsub(tostring(x), "U?LL$", ""))
+end
+
+-- structType gives the runtime type object of the
+-- struct x, or nil if x is not a struct.
+local function structType(x)
+   local mt = getmetatable(x)
+   local typ = type(mt) == "table" and mt[__gi_PropsKey]
+   if typ and typ.__fields ~= nil then
+      return typ
+   end
+   return nil
+end
+
+-- dynamicType gives the Go type string for the
+-- value x, whose static type is an interface.
+local function dynamicType(x)
+   local tx = type(x)
+   if tx == "boolean" then
+      return "bool"
+   elseif tx == "string" then
+      return "string"
+   elseif tx == "number" then
+      return "float64"
+   elseif tx == "cdata" then
+      if ffi.istype(u64, x) then
+         return "uint64"
+      elseif ffi.istype(i64, x) then
+         return "int"
+      elseif ffi.istype(complex64, x) then
+         return "complex64"
+      elseif ffi.istype(complex128, x) then
+         return "complex128"
+      end
+   elseif tx == "table" then
+      local typ = structType(x)
+      if typ then
+         return typ.__str
+      end
+   end
+   return nil
+end
+
+-- the writer collects the output, up to the limit.
+local function newWriter(goSyntax, limit)
+   local w = {parts = {}, n = 0, limit = limit, full = false, goSyntax = goSyntax, seen = {}}
+   function w.put(s)
+      if w.full then
+         return
+      end
+      w.parts[#w.parts+1] = s
+      w.n = w.n + #s
+      if w.n > w.limit then
+         w.full = true
+      end
+   end
+   return w
+end
+
+local render
+
+local function renderNilOf(w, ts)
+   if w.goSyntax then
+      if string.sub(ts, 1, 1) == "*" or string.sub(ts, 1, 4) == "func" then
+         ts = "(" .. ts .. ")"
+      end
+      w.put(ts .. "(nil)")
+   else
+      local kind = splitType(ts)
+      if kind == "slice" then
+         w.put("[]")
+      elseif kind == "map" then
+         w.put("map[]")
+      else
+         w.put("<nil>")
+      end
+   end
+end
+
+local function renderStruct(w, x, typ)
+   local sep = " "
+   if w.goSyntax then
+      w.put(typ.__str)
+      sep = ", "
+   end
+   w.put("{")
+   for i, f in ipairs(typ.__fields) do
+      if w.full then
+         break
+      end
+      if i > 1 then
+         w.put(sep)
+      end
+      if w.goSyntax then
+         w.put(f.__name .. ":")
+      end
+      local ts = f.__typstr
+      local v = x[f.__prop]
+      local ft = f.__typ
+      if type(ft) == "table" and v ~= nil and v == rawget(ft, "__nil") then
+         -- a nil pointer or slice
+         renderNilOf(w, ts)
+      else
+         render(w, v, ts, false)
+      end
+   end
+   w.put("}")
+end
+
+-- renderElems writes the n elements of a slice or
+-- array, get(i) giving the i-th.
+local function renderElems(w, ts, n, get, elem)
+   if w.goSyntax then
+      w.put(ts .. "{")
+   else
+      w.put("[")
+   end
+   for i = 0, n-1 do
+      if w.full then
+         break
+      end
+      if i > 0 then
+         w.put(w.goSyntax and ", " or " ")
+      end
+      render(w, get(i), elem, false)
+   end
+   w.put(w.goSyntax and "}" or "]")
+end
+
+-- keyLess orders map keys as fmt does: numbers by
+-- value, strings, false before true, and anything
+-- else by its printed form.
+local function keyLess(a, b)
+   local ta, tb = type(a), type(b)
+   local na = ta == "number" or isInt64(a)
+   local nb = tb == "number" or isInt64(b)
+   if na and nb then
+      return a < b
+   elseif ta == "string" and tb == "string" then
+      return a < b
+   elseif ta == "boolean" and tb == "boolean" then
+      return (not a) and b
+   end
+   local da, db = dynamicType(a) or ta, dynamicType(b) or tb
+   if da ~= db then
+      return da < db
+   end
+   return __gi_sprint(a, nil, false) < __gi_sprint(b, nil, false)
+end
+
+local function renderMap(w, x, ts, key, elem)
+   local props = rawget(x, _giPrivateMapProps)
+   if ts == nil then
+      key = kindNames[props.keyType] or props.keyType
+      elem = kindNames[props.valType] or props.valType
+      ts = "map[" .. key .. "]" .. elem
+   end
+   local keys, vals = {}, {}
+   local hasNil = false
+   for k, v in pairs(x) do
+      if k == __gi_nilMapKey then
+         -- a nil key sorts first, as in fmt.
+         hasNil = true
+      else
+         keys[#keys+1] = k
+      end
+      vals[k] = v
+   end
+   table.sort(keys, keyLess)
+   if hasNil then
+      table.insert(keys, 1, __gi_nilMapKey)
+   end
+   w.put(w.goSyntax and (ts .. "{") or "map[")
+   for i, k in ipairs(keys) do
+      if w.full then
+         break
+      end
+      if i > 1 then
+         w.put(w.goSyntax and ", " or " ")
+      end
+      if k == __gi_nilMapKey then
+         render(w, nil, key, false)
+      else
+         render(w, k, key, false)
+      end
+      w.put(":")
+      render(w, vals[k], elem, false)
+   end
+   w.put(w.goSyntax and "}" or "]")
+end
+
+-- isNilPointer reports whether x is the nil of a
+-- pointer type.
+local function isNilPointer(x)
+   if type(x) ~= "table" then
+      return false
+   end
+   if rawget(x, "__val") == x then
+      -- the nil pointer to a struct
+      return true
+   end
+   local props = rawget(x, __gi_PropsKey)
+   return getmetatable(x) == __gi_PrivatePointer_MT and props.__get == __gi_throwNilPointerError
+end
+
+-- renderPointer writes a pointer: at the top, as &
+-- and what it points to; further in, as its address,
+-- just as fmt does.
+local function renderPointer(w, x, ts, top)
+   local isProxy = getmetatable(x) == __gi_PrivatePointer_MT
+   local elemTs = ts and string.sub(ts, 2)
+   if top then
+      w.put("&")
+      if isProxy then
+         render(w, rawget(x, __gi_PropsKey).__get(), elemTs, false)
+      else
+         render(w, x, elemTs, false)
+      end
+      return
+   end
+   if w.goSyntax then
+      if ts == nil then
+         ts = "*" .. (dynamicType(x) or "")
+      end
+      w.put("(" .. ts .. ")(" .. address(x) .. ")")
+   else
+      w.put(address(x))
+   end
+end
+
+-- render writes the Go value x, of Go type ts; ts
+-- is nil when the type is not known.
+render = function(w, x, ts, top)
+   if w.full then
+      return
+   end
+   local kind, elem, key = splitType(ts)
+   if kind == "iface" then
+      if x == nil then
+         if top or not w.goSyntax then
+            w.put("<nil>")
+         else
+            w.put(ts .. "(nil)")
+         end
+         return
+      end
+      -- the dynamic type takes over.
+      ts = dynamicType(x)
+      kind, elem, key = splitType(ts)
+   end
+
+   local tx = type(x)
+   if kind == "ptr" then
+      if x == nil or x == false or isNilPointer(x) then
+         renderNilOf(w, ts)
+         return
+      end
+      renderPointer(w, x, ts, top)
+      return
+   end
+   if (kind == "map" or kind == "slice") and (x == nil or x == false) then
+      renderNilOf(w, ts)
+      return
+   end
+
+   if x == nil then
+      w.put(w.goSyntax and not top and "interface {}(nil)" or "<nil>")
+   elseif tx == "boolean" then
+      w.put(tostring(x))
+   elseif tx == "string" then
+      w.put(w.goSyntax and goQuote(x) or x)
+   elseif tx == "number" then
+      if ts == "float32" or ts == "float64" or ts == nil or kind ~= "basic" then
+         w.put(__gi_formatFloat(x, ts == "float32", false))
+      else
+         w.put(intString(x, w.goSyntax and unsignedNames[ts]))
+      end
+   elseif isInt64(x) then
+      w.put(intString(x, w.goSyntax and (unsignedNames[ts] or (ts == nil and ffi.istype(u64, x)))))
+   elseif isComplex(x) then
+      w.put(tostring(x))
+   elseif tx == "userdata" or string.find(tx, "<", 1, true) then
+      -- a Go value, proxied by luar, whose type()
+      -- gives, say, "table<[]int>".
+      w.put(__gi_goSprint(w.goSyntax, x))
+   elseif tx == "function" then
+      if w.goSyntax and ts ~= nil then
+         w.put("(" .. ts .. ")(" .. address(x) .. ")")
+      else
+         w.put(address(x))
+      end
+   elseif tx == "table" then
+      if w.seen[x] then
+         -- a cycle, through an interface.
+         renderPointer(w, x, nil, false)
+         return
+      end
+      w.seen[x] = true
+      local typ = structType(x)
+      local sprops = rawget(x, _giPrivateSliceProps)
+      local aprops = rawget(x, _giPrivateArrayProps)
+      if typ ~= nil then
+         renderStruct(w, x, typ)
+      elseif sprops ~= nil then
+         if ts == nil then
+            elem = kindNames[sprops.typeKind] or sprops.typeKind
+            ts = "[]" .. elem
+         end
+         local raw = rawget(x, _giPrivateRaw)
+         renderElems(w, ts, sprops.len, function(i) return raw[sprops.beg + i] end, elem)
+      elseif aprops ~= nil then
+         if ts == nil then
+            elem = string.gsub(aprops.typeKind, "^__gi_kind_", "")
+            ts = "[" .. aprops.len .. "]" .. elem
+         end
+         local raw = rawget(x, _giPrivateRaw)
+         renderElems(w, ts, aprops.len, function(i) return raw[i] end, elem)
+      elseif rawget(x, _giPrivateMapProps) ~= nil then
+         renderMap(w, x, ts, key, elem)
+      elseif getmetatable(x) == __gi_PrivatePointer_MT then
+         renderPointer(w, x, ts, top)
+      else
+         w.put(tostring(x))
+      end
+      w.seen[x] = nil
+   else
+      w.put(tostring(x))
+   end
+end
+
+-- __gi_sprint gives x, of the Go type named by the
+-- string ts, as fmt would print it: with %#v if
+-- goSyntax, else with %v. ts may be nil, when the
+-- type is not known. Output longer than limit is
+-- cut off with "...".
+function __gi_sprint(x, ts, goSyntax, limit)
+   local w = newWriter(goSyntax, limit or __gi_printLimit)
+   render(w, x, ts, true)
+   local s = table.concat(w.parts)
+   if w.full then
+      s = string.sub(s, 1, w.limit) .. "..."
+   end
+   return s
+end
+
+-- __gijit_ansTypes holds the Go types of the values
+-- that the next __gijit_printQuoted will show; the
+-- translator sets it for `= expr`.
+__gijit_ansTypes = nil
+
+-- __gijit_printQuoted shows the results of `= expr`
+-- at the REPL, one per line.
+function __gijit_printQuoted(...)
+   local types = __gijit_ansTypes or {}
+   __gijit_ansTypes = nil
+   local n = select('#', ...)
+   if #types > n then
+      n = #types
+   end
+   local goSyntax = __gi_printMode ~= "compact"
+   local a = {...}
+   for i = 1, n do
+      local ts = types[i]
+      if ts == "" then
+         ts = nil
+      end
+      print(__gi_sprint(a[i], ts, goSyntax))
+   end
+end
@@ -0,0 +1,134 @@
+package compiler
+
+import (
+	"fmt"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test630PrintValuesInGoSyntax(t *testing.T) {
+
+	cv.Convey(`= expr prints its values as fmt's %#v would, following the static Go types: nested structs, pointers, slices, maps with sorted keys, and unsigned ints in hex; :print compact switches to %v`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) {
+			LuaRunAndReport(vm, string(inc.Tr([]byte(src))))
+		}
+		// catch what the REPL prints.
+		LuaRunAndReport(vm, `__printed = {}; print = function(s) table.insert(__printed, s) end`)
+		printed := func(src string) []string {
+			LuaRunAndReport(vm, `__printed = {}`)
+			run(src)
+			var lines []string
+			for i := 1; ; i++ {
+				LuaRunAndReport(vm, fmt.Sprintf(`__line = __printed[%d] or false`, i))
+				vm.GetGlobal("__line")
+				if !vm.IsString(-1) {
+					vm.Pop(1)
+					break
+				}
+				lines = append(lines, vm.ToString(-1))
+				vm.Pop(1)
+			}
+			return lines
+		}
+
+		run(`type pt struct { X, Y int; Name string }`)
+		run(`type box struct { P *pt; Q *pt; F float64; I interface{}; Arr [2]uint8 }`)
+		run(`a := pt{1, 2, "hi\n"}`)
+		run(`b := box{P: &a, F: 3, I: 7, Arr: [2]uint8{3, 4}}`)
+
+		cv.So(printed(`= a, &a`), cv.ShouldResemble, []string{
+			`main.pt{X:1, Y:2, Name:"hi\n"}`,
+			`&main.pt{X:1, Y:2, Name:"hi\n"}`,
+		})
+		bs := printed(`= b`)
+		cv.So(len(bs), cv.ShouldEqual, 1)
+		cv.So(bs[0], cv.ShouldStartWith, `main.box{P:(*main.pt)(0x`)
+		cv.So(bs[0], cv.ShouldEndWith, `), Q:(*main.pt)(nil), F:3, I:7, Arr:[2]uint8{0x3, 0x4}}`)
+
+		cv.So(printed(`= 3, 3.0, float32(0.1), uint8(3), int64(-5), "s", true`), cv.ShouldResemble, []string{
+			`3`, `3`, `0.1`, `0x3`, `-5`, `"s"`, `true`,
+		})
+		cv.So(printed(`= []int{1, 2}, map[int]bool{3: true, -1: false}, []interface{}{1, "a"}`), cv.ShouldResemble, []string{
+			`[]int{1, 2}`,
+			`map[int]bool{-1:false, 3:true}`,
+			`[]interface {}{1, "a"}`,
+		})
+		run(`m := map[string]int{"z": 1, "a": 2, "m": 3}`)
+		cv.So(printed(`= m`), cv.ShouldResemble, []string{`map[string]int{"a":2, "m":3, "z":1}`})
+
+		// a cycle through an interface stops at the pointer.
+		run(`type node struct { V int; Next interface{} }`)
+		run(`n := &node{V: 1}`)
+		run(`n.Next = n`)
+		cv.So(printed(`= n`)[0], cv.ShouldStartWith, `&main.node{V:1, Next:(*main.node)(0x`)
+
+		panicOn(SetPrintMode(vm, "compact"))
+		cv.So(printed(`= a, m, []string{"x", "y"}`), cv.ShouldResemble, []string{
+			"{1 2 hi\n}",
+			`map[a:2 m:3 z:1]`,
+			`[x y]`,
+		})
+
+		// very large values are cut off.
+		panicOn(SetPrintLimit(vm, 20))
+		cv.So(printed(`= make([]int, 1000)`), cv.ShouldResemble, []string{`[0 0 0 0 0 0 0 0 0 0...`})
+
+		cv.So(SetPrintMode(vm, "fancy"), cv.ShouldNotBeNil)
+		cv.So(PrintSettings(vm), cv.ShouldEqual, "compact, limit 20 bytes")
+	})
+}
+
+func Test631BareExpressionsPrintLikeEqualsExpr(t *testing.T) {
+
+	cv.Convey(`an expression typed alone at the prompt is printed by its Go type, just as = expr prints it`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		run := func(src string) {
+			LuaRunAndReport(vm, string(inc.Tr([]byte(src))))
+		}
+		LuaRunAndReport(vm, `__printed = {}; print = function(s) table.insert(__printed, s) end`)
+		printed := func(src string) []string {
+			LuaRunAndReport(vm, `__printed = {}`)
+			run(src)
+			var lines []string
+			for i := 1; ; i++ {
+				LuaRunAndReport(vm, fmt.Sprintf(`__line = __printed[%d] or false`, i))
+				vm.GetGlobal("__line")
+				if !vm.IsString(-1) {
+					vm.Pop(1)
+					break
+				}
+				lines = append(lines, vm.ToString(-1))
+				vm.Pop(1)
+			}
+			return lines
+		}
+
+		run(`type pt struct { X, Y int }`)
+		run(`x := 3`)
+		run(`p := &pt{1, 2}`)
+		run(`m := map[string]int{"a": 1}`)
+
+		cv.So(printed(`x`), cv.ShouldResemble, []string{`3`})
+		cv.So(printed(`x + 1`), cv.ShouldResemble, []string{`4`})
+		cv.So(printed(`p`), cv.ShouldResemble, []string{`&main.pt{X:1, Y:2}`})
+		cv.So(printed(`*p`), cv.ShouldResemble, []string{`main.pt{X:1, Y:2}`})
+		cv.So(printed(`m`), cv.ShouldResemble, []string{`map[string]int{"a":1}`})
+		cv.So(printed(`len(m)`), cv.ShouldResemble, []string{`1`})
+
+		panicOn(SetPrintMode(vm, "compact"))
+		cv.So(printed(`p`), cv.ShouldResemble, []string{`&{1 2}`})
+		cv.So(printed(`m`), cv.ShouldResemble, []string{`map[a:1]`})
+	})
+}
@@ -0,0 +1,274 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/parser"
+	golua "github.com/glycerine/golua/lua"
+)
+
+// benchmarks and profiles, for the REPL's :bench
+// and :profile. The Lua side is in profile.lua.
+
+// BenchResult is what Bench measured.
+type BenchResult struct {
+	N int           // runs timed
+	T time.Duration // the time they took
+
+	// allocations made by the Lua vm during those
+	// runs, and their bytes.
+	Allocs uint64
+	Bytes  uint64
+}
+
+// NsPerOp is the time per run.
+func (b BenchResult) NsPerOp() int64 {
+	if b.N <= 0 {
+		return 0
+	}
+	return b.T.Nanoseconds() / int64(b.N)
+}
+
+// String reads as a line of go test -bench -benchmem.
+func (b BenchResult) String() string {
+	if b.N <= 0 {
+		return "no runs"
+	}
+	ns := float64(b.T.Nanoseconds()) / float64(b.N)
+	nsop := fmt.Sprintf("%10.0f ns/op", ns)
+	if ns < 100 {
+		nsop = fmt.Sprintf("%10.2f ns/op", ns)
+	}
+	n := uint64(b.N)
+	return fmt.Sprintf("%8d\t%s\t%8d B/op\t%8d allocs/op", b.N, nsop, b.Bytes/n, b.Allocs/n)
+}
+
+// Bench times stmt, a Go statement or expression
+// in the current package, as go test -bench would:
+// it runs stmt over and over, first to warm up,
+// so that the JIT compiles it, and then for growing
+// counts of runs, until they take benchtime. What
+// stmt declares lasts only for a single run.
+func (ic *IncrState) Bench(stmt string, benchtime time.Duration) (res BenchResult, err error) {
+	body := stmt
+	if e, perr := parser.ParseExpr(stmt); perr == nil {
+		if _, isCall := e.(*ast.CallExpr); !isCall {
+			body = "_ = " + stmt
+		}
+	}
+	translation, err := translateAndCatchPanic(ic, []byte("__gijit_bench := func() {\n"+body+"\n}"))
+	if err != nil {
+		return res, err
+	}
+	vm := ic.vm
+	top := vm.GetTop()
+	defer vm.SetTop(top)
+	err = LoadChunk(vm, translation+"\nreturn __gijit_bench", ic.LastChunk())
+	if err != nil {
+		return res, err
+	}
+	err = CallChunk(vm, 0, 1)
+	if err != nil {
+		return res, ic.MapLuaError(err)
+	}
+	f := vm.GetTop()
+
+	// run calls stmt n times.
+	run := func(n int) (r BenchResult, err error) {
+		r.N = n
+		r.Allocs, r.Bytes = vm.CountAllocs(func() {
+			vm.GetGlobal("__gi_benchRun")
+			vm.PushValue(f)
+			vm.PushInteger(int64(n))
+			t0 := time.Now()
+			err = CallChunk(vm, 2, 0)
+			r.T = time.Since(t0)
+		})
+		if err != nil {
+			err = ic.MapLuaError(err)
+		}
+		return
+	}
+
+	// warm up.
+	var warm time.Duration
+	for n := 1; n <= 1000 && warm < benchtime/10; n *= 10 {
+		r, err := run(n)
+		if err != nil {
+			return res, err
+		}
+		warm += r.T
+	}
+
+	// grow n as testing.B does.
+	n := 1
+	for {
+		res, err = run(n)
+		if err != nil || res.T >= benchtime || n >= maxBenchN {
+			return res, err
+		}
+		n = nextBenchN(n, res.NsPerOp(), benchtime)
+	}
+}
+
+// maxBenchN caps the runs of a :bench, as testing.B
+// caps b.N.
+const maxBenchN = 1e9
+
+// nextBenchN gives how many times to run next, after
+// last runs took ns each: enough to fill benchtime,
+// and a little more; but at most 100 times last, and
+// never over maxBenchN.
+func nextBenchN(last int, ns int64, benchtime time.Duration) int {
+	var n int
+	if ns <= 0 {
+		n = last * 100
+	} else {
+		n = int(benchtime.Nanoseconds() / ns)
+	}
+	n += n / 5
+	if n > 100*last {
+		n = 100 * last
+	}
+	if n <= last {
+		n = last + 1
+	}
+	if n > maxBenchN {
+		n = maxBenchN
+	}
+	return n
+}
+
+// StartProfile starts LuaJIT's profiler, which will
+// sample whatever Lua runs, every interval, until
+// StopProfile.
+func (ic *IncrState) StartProfile(interval time.Duration) error {
+	ms := int(interval / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	return ic.vm.DoString(fmt.Sprintf("__gi_profileStart(%d)", ms))
+}
+
+// StopProfile stops the profiler, and reports where
+// its samples fell, by Go function and line, most
+// frequent first, in at most top lines. A sample
+// taken in the runtime, or in Go called from the
+// script, counts against the Go line that called
+// it.
+func (ic *IncrState) StopProfile(top int) (string, error) {
+	vm := ic.vm
+	err := vm.DoString("__gi_profileStop()")
+	if err != nil {
+		return "", err
+	}
+	stacks := luaStringCounts(vm, "__gi_profileSamples")
+
+	type spot struct {
+		fn, where, text string
+	}
+	counts := make(map[spot]int)
+	total := 0
+	for stack, n := range stacks {
+		total += n
+		var sp spot
+		found := false
+		frames := strings.Split(stack, ";")
+		for _, fr := range frames {
+			loc := strings.SplitN(fr, "|", 2)[0]
+			if fn, where, text, ok := ic.goFrameOf(loc); ok {
+				sp = spot{fn, where, text}
+				found = true
+				break
+			}
+		}
+		if !found {
+			loc := strings.SplitN(frames[0], "|", 2)[0]
+			sp = spot{fn: "(runtime)", where: path.Base(loc)}
+		}
+		counts[sp] += n
+	}
+	if total == 0 {
+		return "profile: no samples.\n", nil
+	}
+
+	spots := make([]spot, 0, len(counts))
+	for sp := range counts {
+		spots = append(spots, sp)
+	}
+	sort.Slice(spots, func(i, j int) bool {
+		a, b := spots[i], spots[j]
+		if counts[a] != counts[b] {
+			return counts[a] > counts[b]
+		}
+		return a.where < b.where
+	})
+	if top > 0 && len(spots) > top {
+		spots = spots[:top]
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "profile: %d samples\n", total)
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "samples\tflat%%\t\n")
+	for _, sp := range spots {
+		text := sp.text
+		if len(text) > 48 {
+			text = text[:45] + "..."
+		}
+		line := strings.TrimRight(fmt.Sprintf("  %s  %s  %s", sp.fn, sp.where, text), " ")
+		fmt.Fprintf(w, "%d\t%.1f%%\t%s\n", counts[sp], 100*float64(counts[sp])/float64(total), line)
+	}
+	w.Flush()
+	return buf.String(), nil
+}
+
+// goFrameOf turns a profiler location in translated
+// code, __gijit_chunk_N:line, into the Go function,
+// file:line and source text it came from.
+func (ic *IncrState) goFrameOf(loc string) (fn, where, text string, ok bool) {
+	sub := luaChunkRegex.FindStringSubmatch(loc)
+	if sub == nil {
+		return
+	}
+	n, _ := strconv.Atoi(sub[1])
+	line, _ := strconv.Atoi(sub[2])
+	if n >= len(ic.luaMaps) {
+		return
+	}
+	pos, fn, text, ok := ic.luaMaps[n].GoFrame(line)
+	if !ok {
+		return
+	}
+	if fn == "" {
+		fn = "(top level)"
+	}
+	return fn, goPosString(pos), text, true
+}
+
+// luaStringCounts reads the Lua table named global,
+// from string to number, into a map.
+func luaStringCounts(vm *golua.State, global string) map[string]int {
+	m := make(map[string]int)
+	vm.GetGlobal(global)
+	defer vm.Pop(1)
+	if !vm.IsTable(-1) {
+		return m
+	}
+	vm.PushNil()
+	for vm.Next(-2) != 0 {
+		if vm.Type(-2) == golua.LUA_TSTRING {
+			m[vm.ToString(-2)] += int(vm.ToNumber(-1))
+		}
+		vm.Pop(1)
+	}
+	return m
+}
@@ -0,0 +1,31 @@
+-- :profile and :bench, at the REPL. profile.go
+-- drives these, and maps what they find back to Go.
+
+local profile = require("jit.profile")
+
+-- __gi_profileSamples counts the samples taken
+-- at each stack that the profiler saw. The frames
+-- of a stack, innermost first, are separated by
+-- ';'; each frame is chunk:line|function.
+__gi_profileSamples = nil
+
+function __gi_profileStart(intervalMs)
+   local samples = {}
+   __gi_profileSamples = samples
+   profile.start("li" .. intervalMs, function(thread, n, vmstate)
+      local stack = profile.dumpstack(thread, "pl|fZ;", 30)
+      samples[stack] = (samples[stack] or 0) + n
+   end)
+end
+
+function __gi_profileStop()
+   profile.stop()
+end
+
+-- __gi_benchRun calls f n times.
+function __gi_benchRun(f, n)
+   for i = 1, n do
+      __gi_poll()
+      f()
+   end
+end
@@ -0,0 +1,68 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test640BenchAndProfile(t *testing.T) {
+
+	cv.Convey(`:bench times a call or statement, counting the Lua allocations per run; :profile reports the hot spots by Go function and line`, t, func() {
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		// as the REPL does, naming the chunks, so the
+		// profiler can map them back to Go.
+		run := func(src string) {
+			lua := string(inc.Tr([]byte(src)))
+			panicOn(LoadChunk(vm, lua, inc.LastChunk()))
+			panicOn(vm.Call(0, 0))
+		}
+		run(`func sum(n int) int { s := 0; for i := 0; i < n; i++ { s += i }; return s }`)
+
+		res, err := inc.Bench(`sum(1000)`, 50*time.Millisecond)
+		panicOn(err)
+		cv.So(res.N, cv.ShouldBeGreaterThan, 1)
+		cv.So(res.T, cv.ShouldBeGreaterThanOrEqualTo, 50*time.Millisecond)
+		cv.So(res.NsPerOp(), cv.ShouldBeGreaterThan, 0)
+
+		res, err = inc.Bench(`xs := make([]int, 100); _ = xs`, 50*time.Millisecond)
+		panicOn(err)
+		cv.So(res.Allocs/uint64(res.N), cv.ShouldBeGreaterThan, 0)
+		cv.So(res.Bytes/uint64(res.N), cv.ShouldBeGreaterThanOrEqualTo, 100)
+		cv.So(res.String(), cv.ShouldContainSubstring, "allocs/op")
+
+		_, err = inc.Bench(`nosuch()`, time.Millisecond)
+		cv.So(err, cv.ShouldNotBeNil)
+
+		panicOn(inc.StartProfile(time.Millisecond))
+		run(`func spin() int { t := 0; for j := 0; j < 1000; j++ { t += sum(100000) }; return t }`)
+		run(`total := spin()`)
+		report, err := inc.StopProfile(5)
+		panicOn(err)
+		lines := strings.Split(report, "\n")
+		cv.So(lines[0], cv.ShouldStartWith, "profile: ")
+		cv.So(lines[2], cv.ShouldContainSubstring, " sum  repl:1  func sum(n int) int {")
+	})
+}
+
+func Test641BenchNeverRunsOverAMaxN(t *testing.T) {
+
+	cv.Convey(`:bench grows n as testing.B does, at most 100-fold a step, and never past 1e9 runs, however cheap the statement`, t, func() {
+
+		cv.So(nextBenchN(1, 1000, time.Second), cv.ShouldEqual, 100)
+		cv.So(nextBenchN(1000, 1000, time.Second), cv.ShouldEqual, 100000)
+		cv.So(nextBenchN(100000, 1000, time.Second), cv.ShouldEqual, 1200000)
+
+		// a statement too cheap to time would go from
+		// 1e8 to 1e10 runs.
+		cv.So(nextBenchN(1e8, 0, time.Second), cv.ShouldEqual, maxBenchN)
+		cv.So(nextBenchN(1e8, 1, time.Second), cv.ShouldEqual, maxBenchN)
+	})
+}
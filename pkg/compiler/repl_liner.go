@@ -34,6 +34,14 @@ func NewPrompter(prompt string) *Prompter {
 	return p
 }
 
+// SetCompleter installs f to give the completions
+// when tab is pressed. The candidates are listed,
+// as bash does, on a second tab.
+func (p *Prompter) SetCompleter(f liner.WordCompleter) {
+	p.prompter.SetWordCompleter(f)
+	p.prompter.SetTabCompletionStyle(liner.TabPrints)
+}
+
 func (p *Prompter) Close() {
 	defer p.prompter.Close()
 }
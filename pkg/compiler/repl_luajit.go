@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
@@ -51,6 +52,12 @@ type Repl struct {
 	prevSrc      string
 	prompterLine string
 	reader       *bufio.Reader
+
+	// Ctrl-C, or the :timeout, stops an evaluation.
+	timeout time.Duration
+	stop    interrupter
+
+	profiling bool
 }
 
 func NewRepl(cfg *GIConfig) *Repl {
@@ -64,6 +71,7 @@ func NewRepl(cfg *GIConfig) *Repl {
 	inc := NewIncrState(vm, vmCfg)
 
 	r := &Repl{cfg: cfg, vm: vm, inc: inc}
+	r.stop.vm = vm
 	r.home = os.Getenv("HOME")
 	if r.home != "" {
 		r.histFn = r.home + string(os.PathSeparator) + ".gijit.hist"
@@ -95,6 +103,7 @@ func NewRepl(cfg *GIConfig) *Repl {
 		for i := range r.history {
 			r.prompter.prompter.AppendHistory(r.history[i])
 		}
+		r.prompter.SetCompleter(r.complete)
 	}
 
 	r.prompt = r.goPrompt
@@ -200,6 +209,110 @@ func (r *Repl) Read() (src string, err error) {
 		}
 		return "", nil
 	}
+	if low == ":ls" {
+		fmt.Print(r.inc.Ls())
+		return "", nil
+	}
+	if strings.HasPrefix(low, ":type ") || strings.HasPrefix(low, ":info ") {
+		arg := strings.TrimSpace(string(cmd[len(":type "):]))
+		var res string
+		if low[1] == 't' {
+			res, err = r.inc.TypeOfExpr(arg)
+		} else {
+			res, err = r.inc.Info(arg)
+		}
+		if err != nil {
+			fmt.Printf("%s\n", err.Error())
+			return "", nil
+		}
+		fmt.Printf("%s\n", strings.TrimRight(res, "\n"))
+		return "", nil
+	}
+	if low == ":print" || strings.HasPrefix(low, ":print ") {
+		r.printSetting(strings.Fields(low[len(":print"):]))
+		return "", nil
+	}
+	if low == ":timeout" || strings.HasPrefix(low, ":timeout ") {
+		arg := strings.TrimSpace(low[len(":timeout"):])
+		switch arg {
+		case "":
+		case "off":
+			r.timeout = 0
+		default:
+			d, err := time.ParseDuration(arg)
+			if err != nil || d < 0 {
+				fmt.Printf("bad :timeout '%s'; use, for example, :timeout 5s, or :timeout off.\n", arg)
+				return "", nil
+			}
+			r.timeout = d
+		}
+		if r.timeout == 0 {
+			fmt.Printf("no time limit; ctrl-c stops an evaluation.\n")
+		} else {
+			fmt.Printf("time limit: %v\n", r.timeout)
+		}
+		return "", nil
+	}
+	if strings.HasPrefix(low, ":bench ") {
+		stmt := strings.TrimSpace(string(cmd[len(":bench "):]))
+		var res BenchResult
+		err := r.stop.run(r.timeout, true, func() (err error) {
+			res, err = r.inc.Bench(stmt, time.Second)
+			return
+		})
+		if ce, ok := err.(*CompileError); ok {
+			fmt.Print(ce.Caret())
+			return "", nil
+		}
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return "", nil
+		}
+		fmt.Printf("%s\n", res)
+		return "", nil
+	}
+	if low == ":profile" || strings.HasPrefix(low, ":profile ") {
+		r.profileSetting(strings.TrimSpace(low[len(":profile"):]))
+		return "", nil
+	}
+	if strings.HasPrefix(low, ":export") {
+		fn := strings.TrimSpace(string(cmd[len(":export"):]))
+		if fn == "" {
+			fn = "main.go"
+		}
+		err := r.export(fn)
+		if err != nil {
+			fmt.Printf("%s\n", err.Error())
+			return "", nil
+		}
+		fmt.Printf("exported session to '%s'.\n", fn)
+		return "", nil
+	}
+	if strings.HasPrefix(low, ":package") {
+		name := strings.TrimSpace(string(cmd[len(":package"):]))
+		if name == "" {
+			fmt.Printf("package %s\n", r.inc.CurPkg.key)
+			return "", nil
+		}
+		created, err := r.inc.SwitchPackage(name)
+		if err != nil {
+			fmt.Printf("%s\n", err.Error())
+			return "", nil
+		}
+		if created {
+			fmt.Printf("package %s (new; import \"%s\" to use it from other packages)\n", name, name)
+		} else {
+			fmt.Printf("package %s\n", name)
+		}
+		r.goPrompt = "gi> "
+		if name != "main" {
+			r.goPrompt = "gi " + name + "> "
+		}
+		if !r.cfg.RawLua {
+			r.prompt = r.goPrompt
+		}
+		return "", nil
+	}
 	switch low {
 	case ":ast":
 		r.inc.PrintAST = true
@@ -282,7 +395,9 @@ func (r *Repl) Read() (src string, err error) {
 		err = LuaDoFiles(r.vm, files)
 		if err != nil {
 			fmt.Printf("error during prelude reload: '%v'", err)
+			return "", nil
 		}
+		registerInterruptPoll(r.vm)
 		return "", nil
 	case ":help", ":?":
 		fmt.Printf(`
@@ -308,6 +423,21 @@ these special commands.
  :rm 3-4         Remove commands 3-4 from history.
  :do <path>      Run dofile(path) on a .lua file.
  :source <path>  Re-play Go code from a file.
+ :package foo    Create, or switch to, package foo.
+ :package        Show the current package.
+ :export main.go Write the session out as a Go program.
+ :print go       Print values in Go syntax, as %%#v (default).
+ :print compact  Print values compactly, as %%v.
+ :print limit 80 Cut off printed values after 80 bytes.
+ :timeout 5s     Stop any evaluation that runs longer than 5s.
+ :timeout off    No time limit (default); ctrl-c still stops.
+ :bench f(10)    Time a call or statement, as go test -bench does.
+ :profile on     Start sampling where the time goes.
+ :profile off    Stop, and show the hot spots by Go function and line.
+ :ls             List what is defined in the current package.
+ :type <expr>    Show the type of expr, without running it.
+ :info T         Show the fields and methods of type T.
+ tab             Complete names, fields, methods and :commands.
  = 3 + 4         The '=' turns gijit into a calculator.
  import "fmt"    Import the binary, pre-compiled package.
  ctrl-d to exit  History is saved in ~/.gitit.hist
@@ -368,7 +498,7 @@ these special commands.
 
 func (r *Repl) Eval(src string) error {
 
-	var use string
+	var use, chunk string
 	isContinuation := len(r.prevSrc) > 0
 	if !r.cfg.RawLua {
 		if isContinuation {
@@ -393,6 +523,10 @@ func (r *Repl) Eval(src string) error {
 
 		r.prompt = r.goPrompt
 		translation, err := translateAndCatchPanic(r.inc, []byte(src))
+		if ce, ok := err.(*CompileError); ok {
+			fmt.Print(ce.Caret())
+			return err
+		}
 		if err != nil {
 			fmt.Printf("oops: '%v' on input '%s'\n", err, strings.TrimSpace(src))
 			translation = "\n"
@@ -404,6 +538,7 @@ func (r *Repl) Eval(src string) error {
 			p("got translation of line from Go into lua: '%s'\n", strings.TrimSpace(string(translation)))
 		}
 		use = translation
+		chunk = r.inc.LastChunk()
 
 	} else {
 		// :r/raw mode
@@ -431,19 +566,42 @@ func (r *Repl) Eval(src string) error {
 	}
 	r.t0 = time.Now()
 	// 	loadstring: returns 0 if there are no errors or 1 in case of errors.
-	interr := r.vm.LoadString(use)
-	if interr != 0 {
-		fmt.Printf("error from Lua vm.LoadString(): supplied lua with: '%s'\nlua stack:\n", use[:len(use)-1])
-		DumpLuaStack(r.vm)
-		r.vm.Pop(1)
+	err := LoadChunk(r.vm, use, chunk)
+	if err != nil {
+		fmt.Printf("error from Lua vm.LoadString(): '%v'. supplied lua with: '%s'\n", err, use[:len(use)-1])
 		return nil
 	}
-	err := r.vm.Call(0, 0)
-	if err != nil {
-		fmt.Printf("error from Lua vm.Call(0,0): '%v'. supplied lua with: '%s'\nlua stack:\n", err, use[:len(use)-1])
-		DumpLuaStack(r.vm)
-		r.vm.Pop(1)
+	called := false
+	err = r.stop.run(r.timeout, true, func() error {
+		err := CallChunk(r.vm, 0, 0)
+		if err != nil {
+			return err
+		}
+		called = true
+		if !r.cfg.RawLua {
+			r.inc.CommitSession()
+		}
+		// let any goroutines started by this input run
+		// until they finish or block.
+		return LuaRunGoroutines(r.vm)
+	})
+	switch {
+	case err == ErrInterrupted || err == ErrTimeout:
+		// what ran before the interrupt stays done.
+		fmt.Printf("%v\n", err)
+		if !called {
+			return nil
+		}
+	case err != nil && !called:
+		if r.cfg.RawLua {
+			fmt.Printf("error from Lua vm.Call(0,0): '%v'\n", err)
+		} else {
+			fmt.Printf("%v\n", r.inc.MapLuaError(err))
+			p("supplied lua with: '%s'", use[:len(use)-1])
+		}
 		return nil
+	case err != nil:
+		fmt.Printf("error from goroutine scheduler: '%v'\n", r.inc.MapLuaError(err))
 	}
 	r.t1 = time.Now()
 	// jea debug:
@@ -454,3 +612,87 @@ func (r *Repl) Eval(src string) error {
 
 	return nil
 }
+
+// complete gives the tab completions at pos in line,
+// for liner.
+func (r *Repl) complete(line string, pos int) (head string, completions []string, tail string) {
+	if r.cfg.RawLua && !strings.HasPrefix(line, ":") {
+		rs := []rune(line)
+		return string(rs[:pos]), nil, string(rs[pos:])
+	}
+	return r.inc.Complete(r.prevSrc, line, pos)
+}
+
+// profileSetting carries out :profile on and
+// :profile off; with no arg, it says which is in force.
+func (r *Repl) profileSetting(arg string) {
+	switch arg {
+	case "on":
+		if r.profiling {
+			fmt.Printf("already profiling; :profile off shows the results.\n")
+			return
+		}
+		err := r.inc.StartProfile(time.Millisecond)
+		if err != nil {
+			fmt.Printf("could not start the profiler: %v\n", err)
+			return
+		}
+		r.profiling = true
+		fmt.Printf("profiling; :profile off shows the results.\n")
+	case "off":
+		if !r.profiling {
+			fmt.Printf("not profiling; :profile on starts.\n")
+			return
+		}
+		r.profiling = false
+		report, err := r.inc.StopProfile(20)
+		if err != nil {
+			fmt.Printf("could not stop the profiler: %v\n", err)
+			return
+		}
+		fmt.Print(report)
+	case "":
+		if r.profiling {
+			fmt.Printf("profiling is on.\n")
+		} else {
+			fmt.Printf("profiling is off.\n")
+		}
+	default:
+		fmt.Printf("use :profile on, or :profile off.\n")
+	}
+}
+
+// printSetting carries out :print go, :print compact,
+// and :print limit 1000; with no args, it shows the
+// settings.
+func (r *Repl) printSetting(args []string) {
+	var err error
+	switch {
+	case len(args) == 0:
+	case len(args) == 1:
+		err = SetPrintMode(r.vm, args[0])
+	case len(args) == 2 && args[0] == "limit":
+		var n int
+		n, err = strconv.Atoi(args[1])
+		if err == nil {
+			err = SetPrintLimit(r.vm, n)
+		}
+	default:
+		err = fmt.Errorf("use :print go, :print compact, or :print limit <bytes>")
+	}
+	if err != nil {
+		fmt.Printf("%s\n", err.Error())
+		return
+	}
+	fmt.Printf("printing values: %s\n", PrintSettings(r.vm))
+}
+
+// export writes the session in package main to fn,
+// as a Go program.
+func (r *Repl) export(fn string) error {
+	by, err := r.inc.ExportMain()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, by, 0644)
+}
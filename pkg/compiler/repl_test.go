@@ -168,6 +168,7 @@ func Test009NumericalForLoop(t *testing.T) {
 		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `
   		i = 0LL;
   		while (true) do
+  			__gi_poll();
   			if (not (i < 10LL)) then break; end
             i = i + 2LL;
   			i = i + (1LL);
@@ -180,6 +181,7 @@ func Test009NumericalForLoop(t *testing.T) {
   	hmm = function() 
   		local i = 0LL;
   		while (true) do
+  			__gi_poll();
   			if (not (i < a)) then break; end
   			print(i);
   			i = i + (1LL);
@@ -213,9 +215,9 @@ func Test011MapAndRangeForLoop(t *testing.T) {
 
 		code := `a:=make(map[int]int); a[1]=10; a[2]=20; func hmm() { for k, v := range a { println(k," ",v) } }`
 		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `
-a = _gi_NewMap("int", "int", {});
-a["1LL"] = 10LL;
-a["2LL"] = 20LL;
+a = _gi_NewMap("int", "int", __gi_kind_int, {});
+a[1LL] = 10LL;
+a[2LL] = 20LL;
 hmm = function() for k, v in pairs(a) do print(k, " ", v);  end end;`)
 	})
 }
@@ -328,11 +330,11 @@ func Test016MapCreation(t *testing.T) {
 
 		// create using make
 		code := `y := make(map[int]string)`
-		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `y=_gi_NewMap("int", "string", {});`)
+		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `y=_gi_NewMap("int", "string", __gi_kind_int, {});`)
 
 		// create with literal
 		code = `x := map[int]string{3:"hello", 4:"gophers"}`
-		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `x=_gi_NewMap("int", "string", {["3LL"]="hello", ["4LL"]="gophers"});`)
+		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `x=_gi_NewMap("int", "string", __gi_kind_int, {{3LL, "hello"}, {4LL, "gophers"}});`)
 
 	})
 }
@@ -346,7 +348,7 @@ func Test017DeleteFromMap(t *testing.T) {
 	cv.Convey(`delete from a map, x := map[int]string{3:"hello", 4:"gophers"}, with delete(x, 3) should remove the key 3 with value "hello"`, t, func() {
 
 		code := `x := map[int]string{3:"hello", 4:"gophers"}`
-		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `x=_gi_NewMap("int", "string", {["3LL"]="hello", ["4LL"]="gophers"});`)
+		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `x=_gi_NewMap("int", "string", __gi_kind_int, {{3LL, "hello"}, {4LL, "gophers"}});`)
 		code = `delete(x, 3)`
 		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `x("delete",3LL);`)
 	})
@@ -363,7 +365,7 @@ func Test018ReadFromMap(t *testing.T) {
 		inc := NewIncrState(vm, nil)
 
 		srcs := []string{`x := map[int]string{3:"hello", 4:"gophers"}`, "x3 := x[3]"}
-		expect := []string{`x=_gi_NewMap("int", "string", {["3LL"]="hello", ["4LL"]="gophers"});`, `x3 = x('get',"3LL", "");`}
+		expect := []string{`x=_gi_NewMap("int", "string", __gi_kind_int, {{3LL, "hello"}, {4LL, "gophers"}});`, `x3 = x('get', 3LL, "");`}
 		for i, src := range srcs {
 			translation := inc.Tr([]byte(src))
 			//pp("go:'%s'  -->  '%s' in lua\n", src, translation)
@@ -415,6 +417,7 @@ func Test019TopLevelScope(t *testing.T) {
         j = 5LL;
   		i = 0LL;
   		while (true) do
+  			__gi_poll();
   			if (not (i < 3LL)) then break; end
             j = j + (1LL);
   			i = i + (1LL);
@@ -536,11 +539,11 @@ func Test025ComplexNumbers(t *testing.T) {
 	defer vm.Close()
 	inc := NewIncrState(vm, nil)
 
-	cv.Convey("a := 6.67428e-11i should compile, since luajit has builtin support for complex number syntax", t, func() {
+	cv.Convey("a := 6.67428e-11i should compile to a prelude complex128", t, func() {
 
 		code := `a := 6.67428e-11i`
 		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `
-	a = 0+6.67428e-11i;`)
+	a = complex128(0, 6.67428e-11);`)
 	})
 }
 
@@ -925,7 +928,7 @@ func Test042LenAtRepl(t *testing.T) {
 	cv.Convey(`a := []int{3}; len(a)' at the repl, len(a) should give us 1, so it should get wrapped in a print()`, t, func() {
 
 		code := `a := []int{3}; len(a)`
-		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `a = _gi_NewSlice("int",{[0]=3LL}, 0LL); print(#a);`)
+		cv.So(string(inc.Tr([]byte(code))), cv.ShouldMatchModuloWhiteSpace, `a = _gi_NewSlice("int",{[0]=3LL}, 0LL); __gijit_ansTypes = {"int"}; __gijit_printQuoted(#a);`)
 	})
 }
 
@@ -949,8 +952,8 @@ m := 1%a
 		cv.So(string(translation), cv.ShouldMatchModuloWhiteSpace,
 			`
 	a = 0LL;
-    b = __integerByZeroCheck(1LL / a);
-    m = __integerByZeroCheck(1LL % a);
+    b = __gi_div(1LL, a);
+    m = __gi_rem(1LL, a);
 `)
 
 		codeWithCatch := `
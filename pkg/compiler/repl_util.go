@@ -17,6 +17,10 @@ func translateAndCatchPanic(inc *IncrState, src []byte) (translation string, err
 	defer func() {
 		recov := recover()
 		if recov != nil {
+			if ce, ok := recov.(*CompileError); ok {
+				err = ce
+				return
+			}
 			msg := fmt.Sprintf("problem detected during Go static type checking: '%v'", recov)
 			if verb.Verbose {
 				msg += fmt.Sprintf("\n%s\n", string(debug.Stack()))
@@ -146,6 +150,9 @@ func sourceGoFiles(files []string) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
+		// so errors point into f, rather than into
+		// the concatenation.
+		fmt.Fprintf(&buf, "//line %s:1\n", f)
 		_, err = io.Copy(&buf, bytes.NewBuffer(by))
 		if err != nil {
 			return nil, err
@@ -1,11 +1,17 @@
 package shadow_fmt
 
-import "fmt"
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/glycerine/luar"
+)
 
 var Pkg = make(map[string]interface{})
 func init() {
     Pkg["Errorf"] = fmt.Errorf
     Pkg["Formatter"] = GijitShadow_InterfaceConvertTo2_Formatter
+    luar.RegisterAdapter(reflect.TypeOf((*fmt.Formatter)(nil)).Elem(), GijitShadow_NewAdapter_Formatter)
     Pkg["Fprint"] = fmt.Fprint
     Pkg["Fprintf"] = fmt.Fprintf
     Pkg["Fprintln"] = fmt.Fprintln
@@ -13,14 +19,17 @@ func init() {
     Pkg["Fscanf"] = fmt.Fscanf
     Pkg["Fscanln"] = fmt.Fscanln
     Pkg["GoStringer"] = GijitShadow_InterfaceConvertTo2_GoStringer
+    luar.RegisterAdapter(reflect.TypeOf((*fmt.GoStringer)(nil)).Elem(), GijitShadow_NewAdapter_GoStringer)
     Pkg["Print"] = fmt.Print
     Pkg["Printf"] = fmt.Printf
     Pkg["Println"] = fmt.Println
     Pkg["Scan"] = fmt.Scan
     Pkg["ScanState"] = GijitShadow_InterfaceConvertTo2_ScanState
+    luar.RegisterAdapter(reflect.TypeOf((*fmt.ScanState)(nil)).Elem(), GijitShadow_NewAdapter_ScanState)
     Pkg["Scanf"] = fmt.Scanf
     Pkg["Scanln"] = fmt.Scanln
     Pkg["Scanner"] = GijitShadow_InterfaceConvertTo2_Scanner
+    luar.RegisterAdapter(reflect.TypeOf((*fmt.Scanner)(nil)).Elem(), GijitShadow_NewAdapter_Scanner)
     Pkg["Sprint"] = fmt.Sprint
     Pkg["Sprintf"] = fmt.Sprintf
     Pkg["Sprintln"] = fmt.Sprintln
@@ -28,7 +37,9 @@ func init() {
     Pkg["Sscanf"] = fmt.Sscanf
     Pkg["Sscanln"] = fmt.Sscanln
     Pkg["State"] = GijitShadow_InterfaceConvertTo2_State
+    luar.RegisterAdapter(reflect.TypeOf((*fmt.State)(nil)).Elem(), GijitShadow_NewAdapter_State)
     Pkg["Stringer"] = GijitShadow_InterfaceConvertTo2_Stringer
+    luar.RegisterAdapter(reflect.TypeOf((*fmt.Stringer)(nil)).Elem(), GijitShadow_NewAdapter_Stringer)
 
 }
 func GijitShadow_InterfaceConvertTo2_Formatter(x interface{}) (y fmt.Formatter, b bool) {
@@ -41,6 +52,26 @@ func GijitShadow_InterfaceConvertTo1_Formatter(x interface{}) fmt.Formatter {
 }
 
 
+// GijitShadow_Adapter_Formatter implements fmt.Formatter by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_Formatter struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_Formatter(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_Formatter{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_Formatter) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_Formatter) Format(p0 fmt.State, p1 rune) {
+	if err := a.obj.CallMethod("Format", []interface{}{}, p0, p1); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_GoStringer(x interface{}) (y fmt.GoStringer, b bool) {
 	y, b = x.(fmt.GoStringer)
 	return
@@ -51,6 +82,26 @@ func GijitShadow_InterfaceConvertTo1_GoStringer(x interface{}) fmt.GoStringer {
 }
 
 
+// GijitShadow_Adapter_GoStringer implements fmt.GoStringer by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_GoStringer struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_GoStringer(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_GoStringer{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_GoStringer) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_GoStringer) GoString() (r0 string) {
+	if err := a.obj.CallMethod("GoString", []interface{}{&r0}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ScanState(x interface{}) (y fmt.ScanState, b bool) {
 	y, b = x.(fmt.ScanState)
 	return
@@ -61,6 +112,61 @@ func GijitShadow_InterfaceConvertTo1_ScanState(x interface{}) fmt.ScanState {
 }
 
 
+// GijitShadow_Adapter_ScanState implements fmt.ScanState by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_ScanState struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_ScanState(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_ScanState{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_ScanState) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_ScanState) Read(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Read", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ScanState) ReadRune() (r0 rune, r1 int, r2 error) {
+	if err := a.obj.CallMethod("ReadRune", []interface{}{&r0, &r1, &r2}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ScanState) SkipSpace() {
+	if err := a.obj.CallMethod("SkipSpace", []interface{}{}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ScanState) Token(p0 bool, p1 func(rune) bool) (r0 []byte, r1 error) {
+	if err := a.obj.CallMethod("Token", []interface{}{&r0, &r1}, p0, p1); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ScanState) UnreadRune() (r0 error) {
+	if err := a.obj.CallMethod("UnreadRune", []interface{}{&r0}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ScanState) Width() (r0 int, r1 bool) {
+	if err := a.obj.CallMethod("Width", []interface{}{&r0, &r1}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_Scanner(x interface{}) (y fmt.Scanner, b bool) {
 	y, b = x.(fmt.Scanner)
 	return
@@ -71,6 +177,26 @@ func GijitShadow_InterfaceConvertTo1_Scanner(x interface{}) fmt.Scanner {
 }
 
 
+// GijitShadow_Adapter_Scanner implements fmt.Scanner by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_Scanner struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_Scanner(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_Scanner{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_Scanner) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_Scanner) Scan(p0 fmt.ScanState, p1 rune) (r0 error) {
+	if err := a.obj.CallMethod("Scan", []interface{}{&r0}, p0, p1); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_State(x interface{}) (y fmt.State, b bool) {
 	y, b = x.(fmt.State)
 	return
@@ -81,6 +207,47 @@ func GijitShadow_InterfaceConvertTo1_State(x interface{}) fmt.State {
 }
 
 
+// GijitShadow_Adapter_State implements fmt.State by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_State struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_State(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_State{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_State) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_State) Flag(p0 int) (r0 bool) {
+	if err := a.obj.CallMethod("Flag", []interface{}{&r0}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_State) Precision() (r0 int, r1 bool) {
+	if err := a.obj.CallMethod("Precision", []interface{}{&r0, &r1}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_State) Width() (r0 int, r1 bool) {
+	if err := a.obj.CallMethod("Width", []interface{}{&r0, &r1}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_State) Write(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Write", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_Stringer(x interface{}) (y fmt.Stringer, b bool) {
 	y, b = x.(fmt.Stringer)
 	return
@@ -90,3 +257,23 @@ func GijitShadow_InterfaceConvertTo1_Stringer(x interface{}) fmt.Stringer {
 	return x.(fmt.Stringer)
 }
 
+
+// GijitShadow_Adapter_Stringer implements fmt.Stringer by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_Stringer struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_Stringer(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_Stringer{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_Stringer) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_Stringer) String() (r0 string) {
+	if err := a.obj.CallMethod("String", []interface{}{&r0}); err != nil {
+		panic(err)
+	}
+	return
+}
+
@@ -1,13 +1,22 @@
 package shadow_io
 
-import "io"
+import (
+	"io"
+	"reflect"
+
+	"github.com/glycerine/luar"
+)
 
 var Pkg = make(map[string]interface{})
 func init() {
     Pkg["ByteReader"] = GijitShadow_InterfaceConvertTo2_ByteReader
+    luar.RegisterAdapter(reflect.TypeOf((*io.ByteReader)(nil)).Elem(), GijitShadow_NewAdapter_ByteReader)
     Pkg["ByteScanner"] = GijitShadow_InterfaceConvertTo2_ByteScanner
+    luar.RegisterAdapter(reflect.TypeOf((*io.ByteScanner)(nil)).Elem(), GijitShadow_NewAdapter_ByteScanner)
     Pkg["ByteWriter"] = GijitShadow_InterfaceConvertTo2_ByteWriter
+    luar.RegisterAdapter(reflect.TypeOf((*io.ByteWriter)(nil)).Elem(), GijitShadow_NewAdapter_ByteWriter)
     Pkg["Closer"] = GijitShadow_InterfaceConvertTo2_Closer
+    luar.RegisterAdapter(reflect.TypeOf((*io.Closer)(nil)).Elem(), GijitShadow_NewAdapter_Closer)
     Pkg["Copy"] = io.Copy
     Pkg["CopyBuffer"] = io.CopyBuffer
     Pkg["CopyN"] = io.CopyN
@@ -24,27 +33,43 @@ func init() {
     Pkg["Pipe"] = io.Pipe
     Pkg["ReadAtLeast"] = io.ReadAtLeast
     Pkg["ReadCloser"] = GijitShadow_InterfaceConvertTo2_ReadCloser
+    luar.RegisterAdapter(reflect.TypeOf((*io.ReadCloser)(nil)).Elem(), GijitShadow_NewAdapter_ReadCloser)
     Pkg["ReadFull"] = io.ReadFull
     Pkg["ReadSeeker"] = GijitShadow_InterfaceConvertTo2_ReadSeeker
+    luar.RegisterAdapter(reflect.TypeOf((*io.ReadSeeker)(nil)).Elem(), GijitShadow_NewAdapter_ReadSeeker)
     Pkg["ReadWriteCloser"] = GijitShadow_InterfaceConvertTo2_ReadWriteCloser
+    luar.RegisterAdapter(reflect.TypeOf((*io.ReadWriteCloser)(nil)).Elem(), GijitShadow_NewAdapter_ReadWriteCloser)
     Pkg["ReadWriteSeeker"] = GijitShadow_InterfaceConvertTo2_ReadWriteSeeker
+    luar.RegisterAdapter(reflect.TypeOf((*io.ReadWriteSeeker)(nil)).Elem(), GijitShadow_NewAdapter_ReadWriteSeeker)
     Pkg["ReadWriter"] = GijitShadow_InterfaceConvertTo2_ReadWriter
+    luar.RegisterAdapter(reflect.TypeOf((*io.ReadWriter)(nil)).Elem(), GijitShadow_NewAdapter_ReadWriter)
     Pkg["Reader"] = GijitShadow_InterfaceConvertTo2_Reader
+    luar.RegisterAdapter(reflect.TypeOf((*io.Reader)(nil)).Elem(), GijitShadow_NewAdapter_Reader)
     Pkg["ReaderAt"] = GijitShadow_InterfaceConvertTo2_ReaderAt
+    luar.RegisterAdapter(reflect.TypeOf((*io.ReaderAt)(nil)).Elem(), GijitShadow_NewAdapter_ReaderAt)
     Pkg["ReaderFrom"] = GijitShadow_InterfaceConvertTo2_ReaderFrom
+    luar.RegisterAdapter(reflect.TypeOf((*io.ReaderFrom)(nil)).Elem(), GijitShadow_NewAdapter_ReaderFrom)
     Pkg["RuneReader"] = GijitShadow_InterfaceConvertTo2_RuneReader
+    luar.RegisterAdapter(reflect.TypeOf((*io.RuneReader)(nil)).Elem(), GijitShadow_NewAdapter_RuneReader)
     Pkg["RuneScanner"] = GijitShadow_InterfaceConvertTo2_RuneScanner
+    luar.RegisterAdapter(reflect.TypeOf((*io.RuneScanner)(nil)).Elem(), GijitShadow_NewAdapter_RuneScanner)
     Pkg["SeekCurrent"] = io.SeekCurrent
     Pkg["SeekEnd"] = io.SeekEnd
     Pkg["SeekStart"] = io.SeekStart
     Pkg["Seeker"] = GijitShadow_InterfaceConvertTo2_Seeker
+    luar.RegisterAdapter(reflect.TypeOf((*io.Seeker)(nil)).Elem(), GijitShadow_NewAdapter_Seeker)
     Pkg["TeeReader"] = io.TeeReader
     Pkg["WriteCloser"] = GijitShadow_InterfaceConvertTo2_WriteCloser
+    luar.RegisterAdapter(reflect.TypeOf((*io.WriteCloser)(nil)).Elem(), GijitShadow_NewAdapter_WriteCloser)
     Pkg["WriteSeeker"] = GijitShadow_InterfaceConvertTo2_WriteSeeker
+    luar.RegisterAdapter(reflect.TypeOf((*io.WriteSeeker)(nil)).Elem(), GijitShadow_NewAdapter_WriteSeeker)
     Pkg["WriteString"] = io.WriteString
     Pkg["Writer"] = GijitShadow_InterfaceConvertTo2_Writer
+    luar.RegisterAdapter(reflect.TypeOf((*io.Writer)(nil)).Elem(), GijitShadow_NewAdapter_Writer)
     Pkg["WriterAt"] = GijitShadow_InterfaceConvertTo2_WriterAt
+    luar.RegisterAdapter(reflect.TypeOf((*io.WriterAt)(nil)).Elem(), GijitShadow_NewAdapter_WriterAt)
     Pkg["WriterTo"] = GijitShadow_InterfaceConvertTo2_WriterTo
+    luar.RegisterAdapter(reflect.TypeOf((*io.WriterTo)(nil)).Elem(), GijitShadow_NewAdapter_WriterTo)
 
 }
 func GijitShadow_InterfaceConvertTo2_ByteReader(x interface{}) (y io.ByteReader, b bool) {
@@ -57,6 +82,26 @@ func GijitShadow_InterfaceConvertTo1_ByteReader(x interface{}) io.ByteReader {
 }
 
 
+// GijitShadow_Adapter_ByteReader implements io.ByteReader by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_ByteReader struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_ByteReader(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_ByteReader{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_ByteReader) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_ByteReader) ReadByte() (r0 byte, r1 error) {
+	if err := a.obj.CallMethod("ReadByte", []interface{}{&r0, &r1}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ByteScanner(x interface{}) (y io.ByteScanner, b bool) {
 	y, b = x.(io.ByteScanner)
 	return
@@ -67,6 +112,33 @@ func GijitShadow_InterfaceConvertTo1_ByteScanner(x interface{}) io.ByteScanner {
 }
 
 
+// GijitShadow_Adapter_ByteScanner implements io.ByteScanner by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_ByteScanner struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_ByteScanner(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_ByteScanner{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_ByteScanner) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_ByteScanner) ReadByte() (r0 byte, r1 error) {
+	if err := a.obj.CallMethod("ReadByte", []interface{}{&r0, &r1}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ByteScanner) UnreadByte() (r0 error) {
+	if err := a.obj.CallMethod("UnreadByte", []interface{}{&r0}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ByteWriter(x interface{}) (y io.ByteWriter, b bool) {
 	y, b = x.(io.ByteWriter)
 	return
@@ -77,6 +149,26 @@ func GijitShadow_InterfaceConvertTo1_ByteWriter(x interface{}) io.ByteWriter {
 }
 
 
+// GijitShadow_Adapter_ByteWriter implements io.ByteWriter by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_ByteWriter struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_ByteWriter(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_ByteWriter{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_ByteWriter) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_ByteWriter) WriteByte(p0 byte) (r0 error) {
+	if err := a.obj.CallMethod("WriteByte", []interface{}{&r0}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_Closer(x interface{}) (y io.Closer, b bool) {
 	y, b = x.(io.Closer)
 	return
@@ -87,6 +179,26 @@ func GijitShadow_InterfaceConvertTo1_Closer(x interface{}) io.Closer {
 }
 
 
+// GijitShadow_Adapter_Closer implements io.Closer by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_Closer struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_Closer(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_Closer{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_Closer) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_Closer) Close() (r0 error) {
+	if err := a.obj.CallMethod("Close", []interface{}{&r0}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_NewStruct_LimitedReader() *io.LimitedReader {
 	return &io.LimitedReader{}
 }
@@ -112,6 +224,33 @@ func GijitShadow_InterfaceConvertTo1_ReadCloser(x interface{}) io.ReadCloser {
 }
 
 
+// GijitShadow_Adapter_ReadCloser implements io.ReadCloser by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_ReadCloser struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_ReadCloser(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_ReadCloser{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_ReadCloser) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_ReadCloser) Close() (r0 error) {
+	if err := a.obj.CallMethod("Close", []interface{}{&r0}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadCloser) Read(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Read", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReadSeeker(x interface{}) (y io.ReadSeeker, b bool) {
 	y, b = x.(io.ReadSeeker)
 	return
@@ -122,6 +261,33 @@ func GijitShadow_InterfaceConvertTo1_ReadSeeker(x interface{}) io.ReadSeeker {
 }
 
 
+// GijitShadow_Adapter_ReadSeeker implements io.ReadSeeker by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_ReadSeeker struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_ReadSeeker(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_ReadSeeker{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_ReadSeeker) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_ReadSeeker) Read(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Read", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadSeeker) Seek(p0 int64, p1 int) (r0 int64, r1 error) {
+	if err := a.obj.CallMethod("Seek", []interface{}{&r0, &r1}, p0, p1); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReadWriteCloser(x interface{}) (y io.ReadWriteCloser, b bool) {
 	y, b = x.(io.ReadWriteCloser)
 	return
@@ -132,6 +298,40 @@ func GijitShadow_InterfaceConvertTo1_ReadWriteCloser(x interface{}) io.ReadWrite
 }
 
 
+// GijitShadow_Adapter_ReadWriteCloser implements io.ReadWriteCloser by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_ReadWriteCloser struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_ReadWriteCloser(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_ReadWriteCloser{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_ReadWriteCloser) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_ReadWriteCloser) Close() (r0 error) {
+	if err := a.obj.CallMethod("Close", []interface{}{&r0}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadWriteCloser) Read(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Read", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadWriteCloser) Write(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Write", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReadWriteSeeker(x interface{}) (y io.ReadWriteSeeker, b bool) {
 	y, b = x.(io.ReadWriteSeeker)
 	return
@@ -142,6 +342,40 @@ func GijitShadow_InterfaceConvertTo1_ReadWriteSeeker(x interface{}) io.ReadWrite
 }
 
 
+// GijitShadow_Adapter_ReadWriteSeeker implements io.ReadWriteSeeker by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_ReadWriteSeeker struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_ReadWriteSeeker(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_ReadWriteSeeker{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_ReadWriteSeeker) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_ReadWriteSeeker) Read(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Read", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadWriteSeeker) Seek(p0 int64, p1 int) (r0 int64, r1 error) {
+	if err := a.obj.CallMethod("Seek", []interface{}{&r0, &r1}, p0, p1); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadWriteSeeker) Write(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Write", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReadWriter(x interface{}) (y io.ReadWriter, b bool) {
 	y, b = x.(io.ReadWriter)
 	return
@@ -152,6 +386,33 @@ func GijitShadow_InterfaceConvertTo1_ReadWriter(x interface{}) io.ReadWriter {
 }
 
 
+// GijitShadow_Adapter_ReadWriter implements io.ReadWriter by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_ReadWriter struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_ReadWriter(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_ReadWriter{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_ReadWriter) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_ReadWriter) Read(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Read", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_ReadWriter) Write(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Write", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_Reader(x interface{}) (y io.Reader, b bool) {
 	y, b = x.(io.Reader)
 	return
@@ -162,6 +423,26 @@ func GijitShadow_InterfaceConvertTo1_Reader(x interface{}) io.Reader {
 }
 
 
+// GijitShadow_Adapter_Reader implements io.Reader by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_Reader struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_Reader(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_Reader{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_Reader) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_Reader) Read(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Read", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReaderAt(x interface{}) (y io.ReaderAt, b bool) {
 	y, b = x.(io.ReaderAt)
 	return
@@ -172,6 +453,26 @@ func GijitShadow_InterfaceConvertTo1_ReaderAt(x interface{}) io.ReaderAt {
 }
 
 
+// GijitShadow_Adapter_ReaderAt implements io.ReaderAt by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_ReaderAt struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_ReaderAt(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_ReaderAt{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_ReaderAt) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_ReaderAt) ReadAt(p0 []byte, p1 int64) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("ReadAt", []interface{}{&r0, &r1}, p0, p1); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_ReaderFrom(x interface{}) (y io.ReaderFrom, b bool) {
 	y, b = x.(io.ReaderFrom)
 	return
@@ -182,6 +483,26 @@ func GijitShadow_InterfaceConvertTo1_ReaderFrom(x interface{}) io.ReaderFrom {
 }
 
 
+// GijitShadow_Adapter_ReaderFrom implements io.ReaderFrom by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_ReaderFrom struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_ReaderFrom(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_ReaderFrom{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_ReaderFrom) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_ReaderFrom) ReadFrom(p0 io.Reader) (r0 int64, r1 error) {
+	if err := a.obj.CallMethod("ReadFrom", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_RuneReader(x interface{}) (y io.RuneReader, b bool) {
 	y, b = x.(io.RuneReader)
 	return
@@ -192,6 +513,26 @@ func GijitShadow_InterfaceConvertTo1_RuneReader(x interface{}) io.RuneReader {
 }
 
 
+// GijitShadow_Adapter_RuneReader implements io.RuneReader by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_RuneReader struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_RuneReader(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_RuneReader{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_RuneReader) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_RuneReader) ReadRune() (r0 rune, r1 int, r2 error) {
+	if err := a.obj.CallMethod("ReadRune", []interface{}{&r0, &r1, &r2}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_RuneScanner(x interface{}) (y io.RuneScanner, b bool) {
 	y, b = x.(io.RuneScanner)
 	return
@@ -202,6 +543,33 @@ func GijitShadow_InterfaceConvertTo1_RuneScanner(x interface{}) io.RuneScanner {
 }
 
 
+// GijitShadow_Adapter_RuneScanner implements io.RuneScanner by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_RuneScanner struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_RuneScanner(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_RuneScanner{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_RuneScanner) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_RuneScanner) ReadRune() (r0 rune, r1 int, r2 error) {
+	if err := a.obj.CallMethod("ReadRune", []interface{}{&r0, &r1, &r2}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_RuneScanner) UnreadRune() (r0 error) {
+	if err := a.obj.CallMethod("UnreadRune", []interface{}{&r0}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_NewStruct_SectionReader() *io.SectionReader {
 	return &io.SectionReader{}
 }
@@ -217,6 +585,26 @@ func GijitShadow_InterfaceConvertTo1_Seeker(x interface{}) io.Seeker {
 }
 
 
+// GijitShadow_Adapter_Seeker implements io.Seeker by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_Seeker struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_Seeker(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_Seeker{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_Seeker) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_Seeker) Seek(p0 int64, p1 int) (r0 int64, r1 error) {
+	if err := a.obj.CallMethod("Seek", []interface{}{&r0, &r1}, p0, p1); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_WriteCloser(x interface{}) (y io.WriteCloser, b bool) {
 	y, b = x.(io.WriteCloser)
 	return
@@ -227,6 +615,33 @@ func GijitShadow_InterfaceConvertTo1_WriteCloser(x interface{}) io.WriteCloser {
 }
 
 
+// GijitShadow_Adapter_WriteCloser implements io.WriteCloser by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_WriteCloser struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_WriteCloser(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_WriteCloser{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_WriteCloser) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_WriteCloser) Close() (r0 error) {
+	if err := a.obj.CallMethod("Close", []interface{}{&r0}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_WriteCloser) Write(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Write", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_WriteSeeker(x interface{}) (y io.WriteSeeker, b bool) {
 	y, b = x.(io.WriteSeeker)
 	return
@@ -237,6 +652,33 @@ func GijitShadow_InterfaceConvertTo1_WriteSeeker(x interface{}) io.WriteSeeker {
 }
 
 
+// GijitShadow_Adapter_WriteSeeker implements io.WriteSeeker by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_WriteSeeker struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_WriteSeeker(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_WriteSeeker{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_WriteSeeker) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_WriteSeeker) Seek(p0 int64, p1 int) (r0 int64, r1 error) {
+	if err := a.obj.CallMethod("Seek", []interface{}{&r0, &r1}, p0, p1); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_WriteSeeker) Write(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Write", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_Writer(x interface{}) (y io.Writer, b bool) {
 	y, b = x.(io.Writer)
 	return
@@ -247,6 +689,26 @@ func GijitShadow_InterfaceConvertTo1_Writer(x interface{}) io.Writer {
 }
 
 
+// GijitShadow_Adapter_Writer implements io.Writer by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_Writer struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_Writer(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_Writer{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_Writer) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_Writer) Write(p0 []byte) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("Write", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_WriterAt(x interface{}) (y io.WriterAt, b bool) {
 	y, b = x.(io.WriterAt)
 	return
@@ -257,6 +719,26 @@ func GijitShadow_InterfaceConvertTo1_WriterAt(x interface{}) io.WriterAt {
 }
 
 
+// GijitShadow_Adapter_WriterAt implements io.WriterAt by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_WriterAt struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_WriterAt(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_WriterAt{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_WriterAt) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_WriterAt) WriteAt(p0 []byte, p1 int64) (r0 int, r1 error) {
+	if err := a.obj.CallMethod("WriteAt", []interface{}{&r0, &r1}, p0, p1); err != nil {
+		panic(err)
+	}
+	return
+}
+
+
 func GijitShadow_InterfaceConvertTo2_WriterTo(x interface{}) (y io.WriterTo, b bool) {
 	y, b = x.(io.WriterTo)
 	return
@@ -266,3 +748,23 @@ func GijitShadow_InterfaceConvertTo1_WriterTo(x interface{}) io.WriterTo {
 	return x.(io.WriterTo)
 }
 
+
+// GijitShadow_Adapter_WriterTo implements io.WriterTo by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_WriterTo struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_WriterTo(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_WriterTo{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_WriterTo) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_WriterTo) WriteTo(p0 io.Writer) (r0 int64, r1 error) {
+	if err := a.obj.CallMethod("WriteTo", []interface{}{&r0, &r1}, p0); err != nil {
+		panic(err)
+	}
+	return
+}
+
@@ -0,0 +1,76 @@
+package shadow_sort
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/glycerine/luar"
+)
+
+var Pkg = make(map[string]interface{})
+func init() {
+    Pkg["Find"] = sort.Find
+    Pkg["Float64s"] = sort.Float64s
+    Pkg["Float64sAreSorted"] = sort.Float64sAreSorted
+    Pkg["Interface"] = GijitShadow_InterfaceConvertTo2_Interface
+    luar.RegisterAdapter(reflect.TypeOf((*sort.Interface)(nil)).Elem(), GijitShadow_NewAdapter_Interface)
+    Pkg["Ints"] = sort.Ints
+    Pkg["IntsAreSorted"] = sort.IntsAreSorted
+    Pkg["IsSorted"] = sort.IsSorted
+    Pkg["Reverse"] = sort.Reverse
+    Pkg["Search"] = sort.Search
+    Pkg["SearchFloat64s"] = sort.SearchFloat64s
+    Pkg["SearchInts"] = sort.SearchInts
+    Pkg["SearchStrings"] = sort.SearchStrings
+    Pkg["Slice"] = sort.Slice
+    Pkg["SliceIsSorted"] = sort.SliceIsSorted
+    Pkg["SliceStable"] = sort.SliceStable
+    Pkg["Sort"] = sort.Sort
+    Pkg["Stable"] = sort.Stable
+    Pkg["Strings"] = sort.Strings
+    Pkg["StringsAreSorted"] = sort.StringsAreSorted
+
+}
+func GijitShadow_InterfaceConvertTo2_Interface(x interface{}) (y sort.Interface, b bool) {
+	y, b = x.(sort.Interface)
+	return
+}
+
+func GijitShadow_InterfaceConvertTo1_Interface(x interface{}) sort.Interface {
+	return x.(sort.Interface)
+}
+
+
+// GijitShadow_Adapter_Interface implements sort.Interface by calling
+// the methods of a value defined at the REPL.
+type GijitShadow_Adapter_Interface struct {
+	obj *luar.LuaObject
+}
+
+func GijitShadow_NewAdapter_Interface(obj *luar.LuaObject) interface{} {
+	return &GijitShadow_Adapter_Interface{obj: obj}
+}
+
+func (a *GijitShadow_Adapter_Interface) AdaptedLuaObject() *luar.LuaObject { return a.obj }
+
+func (a *GijitShadow_Adapter_Interface) Len() (r0 int) {
+	if err := a.obj.CallMethod("Len", []interface{}{&r0}); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_Interface) Less(p0 int, p1 int) (r0 bool) {
+	if err := a.obj.CallMethod("Less", []interface{}{&r0}, p0, p1); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (a *GijitShadow_Adapter_Interface) Swap(p0 int, p1 int) {
+	if err := a.obj.CallMethod("Swap", []interface{}{}, p0, p1); err != nil {
+		panic(err)
+	}
+	return
+}
+
@@ -0,0 +1,105 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gijit/gi/pkg/types"
+	"github.com/glycerine/luar"
+)
+
+// A ShadowPackage makes a compiled Go package callable
+// from the REPL. Pkg maps each exported name to its
+// value, in the form gen-gijit-shadow-import writes
+// into *.genimp.go files. Types describes the package
+// to the type checker; when it is nil we read the
+// compiled package with the binary importer instead.
+type ShadowPackage struct {
+	Path  string
+	Pkg   map[string]interface{}
+	Types *types.Package
+}
+
+var shadowRegistry = struct {
+	mu sync.Mutex
+	m  map[string]*ShadowPackage
+}{m: make(map[string]*ShadowPackage)}
+
+// RegisterShadowPackage makes `import "path"` at the
+// REPL bind to pkg, without having to add a case to
+// GiImportFunc and rebuild gi. Registrations take
+// precedence over the built in shadows, and
+// re-registering a path replaces it. Embedders
+// typically call this from an init(), or from main
+// before starting the REPL; see gen-gijit-shadow-import
+// -bundle.
+//
+// tpkg may be nil, in which case the type information
+// comes from the compiled package on disk.
+func RegisterShadowPackage(path string, pkg map[string]interface{}, tpkg *types.Package) {
+	if path == "" {
+		panic("RegisterShadowPackage: empty path")
+	}
+	shadowRegistry.mu.Lock()
+	shadowRegistry.m[path] = &ShadowPackage{
+		Path:  path,
+		Pkg:   pkg,
+		Types: tpkg,
+	}
+	shadowRegistry.mu.Unlock()
+}
+
+// RegisterShadowSet registers each of the packages in
+// set, as RegisterShadowPackage does.
+func RegisterShadowSet(set []*ShadowPackage) {
+	for _, sp := range set {
+		RegisterShadowPackage(sp.Path, sp.Pkg, sp.Types)
+	}
+}
+
+// LookupShadowPackage returns the registration for
+// path, or nil if there is none.
+func LookupShadowPackage(path string) *ShadowPackage {
+	shadowRegistry.mu.Lock()
+	defer shadowRegistry.mu.Unlock()
+	return shadowRegistry.m[path]
+}
+
+// RegisteredShadowPackages lists the registered
+// import paths, sorted.
+func RegisteredShadowPackages() (paths []string) {
+	shadowRegistry.mu.Lock()
+	for path := range shadowRegistry.m {
+		paths = append(paths, path)
+	}
+	shadowRegistry.mu.Unlock()
+	sort.Strings(paths)
+	return
+}
+
+// importRegisteredShadow binds a registered shadow
+// package into the vm under its package name, and
+// tells the type checker about it.
+func (ic *IncrState) importRegisteredShadow(sp *ShadowPackage) (*Archive, error) {
+	if sp.Types == nil {
+		arch, err := ic.ActuallyImportPackage(sp.Path, "", sp.Path)
+		if err != nil {
+			return nil, fmt.Errorf("shadow package '%s' was registered without types, and the importer could not load them: %v", sp.Path, err)
+		}
+		luar.Register(ic.vm, arch.Name, sp.Pkg)
+		return arch, nil
+	}
+
+	luar.Register(ic.vm, sp.Types.Name(), sp.Pkg)
+	ic.CurPkg.importContext.Packages[sp.Path] = sp.Types
+	err := ic.declareNativeStructs(sp.Types)
+	if err != nil {
+		return nil, err
+	}
+	return &Archive{
+		Name:       sp.Types.Name(),
+		ImportPath: sp.Path,
+		Pkg:        sp.Types,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package compiler
+
+import (
+	"bytes"
+	goparser "go/parser"
+	gotoken "go/token"
+	"testing"
+
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test520RegisteredShadowPackageIsImportable(t *testing.T) {
+
+	cv.Convey(`RegisterShadowPackage makes a native Go package importable at the REPL, without a case in GiImportFunc`, t, func() {
+
+		path := "example.com/registered/widgets"
+		tpkg := types.NewPackage(path, "widgets")
+		nt := types.Typ[types.Int]
+		sig := types.NewSignature(nil,
+			types.NewTuple(types.NewVar(token.NoPos, tpkg, "x", nt)),
+			types.NewTuple(types.NewVar(token.NoPos, tpkg, "", nt)), false)
+		tpkg.Scope().Insert(types.NewFunc(token.NoPos, tpkg, "Triple", sig))
+		tpkg.MarkComplete()
+
+		RegisterShadowPackage(path, map[string]interface{}{
+			"Triple": func(x int) int { return 3 * x },
+		}, tpkg)
+		cv.So(LookupShadowPackage(path), cv.ShouldNotBeNil)
+		cv.So(RegisteredShadowPackages(), cv.ShouldContain, path)
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		translation := inc.Tr([]byte(`
+import "example.com/registered/widgets"
+a := widgets.Triple(14)
+`))
+		pp("translation='%s'", string(translation))
+		LuaRunAndReport(vm, string(translation))
+		LuaMustInt64(vm, "a", 42)
+	})
+}
+
+func Test521GenShadowBundleIsValidGo(t *testing.T) {
+
+	cv.Convey(`gen-gijit-shadow-import -bundle writes a main that registers each shadow`, t, func() {
+
+		var buf bytes.Buffer
+		err := GenShadowBundle(&buf, []string{"strings", "example.com/a/b"}, "github.com/gijit/gi/pkg/compiler/shadow")
+		panicOn(err)
+		src := buf.String()
+
+		_, err = goparser.ParseFile(gotoken.NewFileSet(), "main.go", src, 0)
+		cv.So(err, cv.ShouldBeNil)
+		cv.So(src, cv.ShouldContainSubstring, `shadow1 "github.com/gijit/gi/pkg/compiler/shadow/example.com/a/b"`)
+		cv.So(src, cv.ShouldContainSubstring, `compiler.RegisterShadowPackage("strings", shadow0.Pkg, nil)`)
+		cv.So(src, cv.ShouldContainSubstring, `cfg.LuajitMain()`)
+	})
+}
@@ -7,14 +7,104 @@ _giPrivateRaw = _giPrivateRaw or {}
 _giPrivateSliceProps = _giPrivateSliceProps or {}
 _giGo = _giGo or {}
 
+local ffi = require("ffi")
+
+-- Numeric slices and arrays keep their elements in Go
+-- memory, which we index through an FFI pointer, so
+-- that Go functions are handed the same memory, with
+-- no copy; see gomem.go. Such a slice's props record
+-- the goaddr and the rawcap of that memory. Other
+-- element types stay in Lua tables.
+__gi_goBackedCtypes = {
+   float64 = "double",
+   float32 = "float",
+   int     = "int64_t",
+   int64   = "int64_t",
+   uint    = "uint64_t",
+   uint64  = "uint64_t",
+}
+local goBackedKindNames = {[2]="int", [6]="int64", [7]="uint", [11]="uint64", [13]="float32", [14]="float64"}
+
+-- __gi_goBackedKind returns the name of the element
+-- kind that typeKind describes, if those elements can
+-- live in Go memory; else nil. typeKind is a kind name,
+-- a kind number, or a type: an element type, or the
+-- slice or array type itself.
+function __gi_goBackedKind(typeKind)
+   if __gi_goSliceAlloc == nil then
+      return nil
+   end
+   local k = typeKind
+   if type(k) == "table" then
+      if k.__kind == __gi_kind_Slice or k.__kind == __gi_kind_Array then
+         k = k.__elem
+      end
+      k = k.__kind
+   end
+   if type(k) == "number" then
+      k = goBackedKindNames[k]
+   elseif type(k) == "string" then
+      -- array literals name it "__gi_kind_float64".
+      k = string.gsub(k, "^__gi_kind_", "")
+   end
+   if __gi_goBackedCtypes[k] ~= nil then
+      return k
+   end
+   return nil
+end
+
+-- __gi_goAlloc returns a pointer to n zeroed elements
+-- of kind in Go memory, and its address. Go keeps the
+-- memory until the pointer is collected.
+function __gi_goAlloc(kind, n)
+   local addr = __gi_goSliceAlloc(kind, n)
+   return __gi_goPointer(kind, addr), addr
+end
+
+-- __gi_goPointer casts addr, pinned by Go, to a pointer
+-- to kind, that unpins it when collected.
+function __gi_goPointer(kind, addr)
+   local p = ffi.cast(__gi_goBackedCtypes[kind] .. "*", addr)
+   return ffi.gc(p, function() __gi_goSliceRelease(addr) end)
+end
+
+-- __gi_goWrapSlice returns a slice over len of the cap
+-- elements of kind at addr, which Go has pinned for us;
+-- luar hands Go slices to REPL funcs this way.
+function __gi_goWrapSlice(kind, addr, len, cap, zeroVal)
+   return __gi_goBackedSlice(kind, __gi_goPointer(kind, addr), addr, cap, zeroVal, 0, len)
+end
+
+-- __gi_goBackedSlice returns a slice over raw, a pointer
+-- to rawcap elements at goaddr.
+function __gi_goBackedSlice(typeKind, raw, goaddr, rawcap, zeroVal, beg, len, cap)
+   local proxy = {}
+   proxy[_giPrivateRaw] = raw
+   proxy["Typeof"]="_gi_Slice"
+   cap = cap or rawcap - beg
+   proxy[_giPrivateSliceProps] = {beg=beg, len=len, cap=cap, endx=beg+len, typeKind=typeKind, zeroVal=zeroVal, goaddr=goaddr, rawcap=rawcap}
+   setmetatable(proxy, _giPrivateSliceMt)
+   return proxy
+end
+
 _giPrivateSliceMt = {
 
     __newindex = function(t, k, v)
        --print("newindex called for key", k, " val=", v)
+       -- an int64 cdata key would be a distinct table key
+       -- from the number it holds.
+       k = tonumber(k)
        local props = rawget(t, _giPrivateSliceProps)
        local len = props.len
        local beg = props.beg
        local raw = rawget(t, _giPrivateRaw)
+       if props.goaddr ~= nil then
+          if k < 0 or k >= len then
+             error("out of bounds access " .. tostring(k))
+          end
+          raw[k+beg] = v
+          return
+       end
        
        --print("newindex called for key", k, " with b=", b, " len at start is ", len)
        if raw[k+beg] == nil then
@@ -39,9 +129,16 @@ _giPrivateSliceMt = {
   --
     __index = function(t, k)
        --print("_gi_Slice: __index called for key", k)       
+       k = tonumber(k)
        local props = rawget(t, _giPrivateSliceProps)
        local raw = rawget(t, _giPrivateRaw)       
        local beg = props.beg
+       if props.goaddr ~= nil then
+          if k < 0 or k >= props.len then
+             error("out of bounds access " .. tostring(k))
+          end
+          return raw[beg+k]
+       end
        local rawlen = #raw
        if raw[0] ~= nil then
           rawlen = rawlen + 1
@@ -104,6 +201,20 @@ _giPrivateSliceMt = {
            if v then return k,v end
        end
 
+       local props = rawget(t, _giPrivateSliceProps)
+       if props.goaddr ~= nil then
+          -- elements in Go memory go in order, from 0.
+          local raw = rawget(t, _giPrivateRaw)
+          local beg = props.beg
+          local len = props.len
+          return function(t, k)
+             k = k + 1
+             if k < len then
+                return k, raw[beg+k]
+             end
+          end, t, -1
+       end
+
        -- Return an iterator function, the table, starting point
        return stateless_iter, t, nil
     end,
@@ -128,21 +239,36 @@ function _gi_NewSlice(typeKind, x, zeroVal, beg, endx, cap)
 
    local raw = x
    local xlen = #x
+   local goaddr, rawcap
    
    if arrProp ~= nil then
       --print("_gi_NewSlice sees x is an array")
       raw = rawget(x, _giPrivateRaw)
+      goaddr, rawcap = arrProp.goaddr, arrProp.len
       -- xlen is correct
    elseif slcProp ~= nil then
       --print("_gi_NewSlice sees x is a slice")
       raw = rawget(x, _giPrivateRaw)
+      goaddr, rawcap = slcProp.goaddr, slcProp.rawcap
       -- xlen is correct
    else
       --print("_gi_NewSlice sees x is not an array or slice. Hmm: raw input table")
       -- #x misses the [0] value, if present.
       if x[0] ~= nil then
          xlen = xlen + 1
-      end      
+      end
+      local kind = __gi_goBackedKind(typeKind)
+      if kind ~= nil then
+         -- move the elements into Go memory.
+         raw, goaddr = __gi_goAlloc(kind, xlen)
+         rawcap = xlen
+         for i = 0, xlen-1 do
+            local v = x[i]
+            if v ~= nil then
+               raw[i] = v
+            end
+         end
+      end
    end
    
    --print("_gi_NewSlice: xlen is ", xlen)
@@ -155,12 +281,17 @@ function _gi_NewSlice(typeKind, x, zeroVal, beg, endx, cap)
    --proxy[_giGo] = __lua2go(x)
 
    beg = beg or 0
+   local len
    if endx == nil then
       len = xlen - beg
       endx = beg + len
    else
       len = endx - beg 
    end
+
+   if goaddr ~= nil then
+      return __gi_goBackedSlice(typeKind, raw, goaddr, rawcap, zeroVal, beg, len, cap)
+   end
    
    --print("_gi_NewSlice: beg=", beg, " endx=",endx," len of the new slice is ", len)
    
@@ -196,6 +327,15 @@ function _gi_UnpackSliceRaw(t)
       return unpack(t) 
    end
 
+   local props = rawget(t, _giPrivateSliceProps)
+   if props ~= nil and props.goaddr ~= nil then
+      local vals = {}
+      for i = 0, props.len-1 do
+         vals[i+1] = raw[props.beg+i]
+      end
+      return unpack(vals, 1, props.len)
+   end
+
    --print("jea debug, _gi_UnpackSliceRaw in slice.lua, raw is:")
    --st(raw)
 
@@ -243,6 +383,10 @@ function append(t, ...)
       error "could not get raw table from slice, internal error?"
    end
 
+   if props.goaddr ~= nil then
+      return __gi_goAppend(t, props, raw, ...)
+   end
+
    -- make copy
    local proxy = {}
    proxy["Typeof"]="_gi_Slice"
@@ -278,6 +422,35 @@ function append(t, ...)
 
 end
 
+-- __gi_goAppend is append for a slice in Go memory. As
+-- in Go, the new elements go in place when the capacity
+-- allows, and otherwise into a new array, of twice the
+-- length.
+function __gi_goAppend(t, props, raw, ...)
+   local n = select("#", ...)
+   local beg = props.beg
+   local len = props.len
+   local newlen = len + n
+   local goaddr = props.goaddr
+   local rawcap = props.rawcap
+   if newlen > props.cap then
+      local kind = __gi_goBackedKind(props.typeKind)
+      rawcap = 2 * len
+      if rawcap < newlen then
+         rawcap = newlen
+      end
+      local raw2
+      raw2, goaddr = __gi_goAlloc(kind, rawcap)
+      ffi.copy(raw2, raw + beg, len * ffi.sizeof(__gi_goBackedCtypes[kind]))
+      raw = raw2
+      beg = 0
+   end
+   for i = 1, n do
+      raw[beg+len+i-1] = (select(i, ...))
+   end
+   return __gi_goBackedSlice(props.typeKind, raw, goaddr, rawcap, props.zeroVal, beg, newlen)
+end
+
 function appendSlice(...)
    --print("appendSlice called")
    return append(...)
@@ -296,6 +469,7 @@ function __copySlice(dest, src)
 
    local begDest = propsDest.beg
    local begSrc  = propsSrc.beg
+   -- the raws are plain tables, or pointers into Go memory.
    local rawDest = rawget(dest, _giPrivateRaw)
    local rawSrc  = rawget(src,  _giPrivateRaw)
 
@@ -313,46 +487,59 @@ function __copySlice(dest, src)
    if begSrc > begDest then
       --print("src.beg > dest.beg, copying forward, step=+1")
       for i = 0, len-1 do
-         rawset(rawDest, i+begDest, rawget(rawSrc, i+begSrc))
+         rawDest[i+begDest] = rawSrc[i+begSrc]
       end
    else
       --print("src.beg <= dest.beg, copying backward, step=-1")
       for i = len-1, 0, -1 do
-         rawset(rawDest, i+begDest,  rawget(rawSrc, i+begSrc))
+         rawDest[i+begDest] = rawSrc[i+begSrc]
       end
    end
    --print("done with __copySlice, returning len=", len)
    return int(len)
 end
 
-function __subslice(a, beg, endx)
+function __subslice(a, beg, endx, max)
    --print("top of __subslice, beg=",beg, " endx=", endx)
    
    local arrProp = rawget(a, _giPrivateArrayProps)
    local slcProp = rawget(a, _giPrivateSliceProps)
 
-   local raw = a
-   if arrProp ~= nil then
-      --print("__subslice sees x is an array")
-      raw = rawget(a, _giPrivateRaw)
-   elseif slcProp ~= nil then
-      --print("__subslice sees x is a slice")
-      raw = rawget(a, _giPrivateRaw)
-   else
+   local props = slcProp or arrProp
+   if props == nil then
       --print("__subslice sees x is not an array or slice. Hmm?")
       error("must have slice or array in __subslice")
    end
-
-   local props = slcProp or arrProp
    local typeKind = props.typeKind
 
-   return _gi_NewSlice(typeKind, raw, props.zeroVal, beg, endx)
+   -- beg and endx are relative to a, which may itself
+   -- start part way into raw.
+   local base = props.beg or 0
+   beg = tonumber(beg) or 0
+   if endx == nil then
+      endx = props.len
+   else
+      endx = tonumber(endx)
+   end
+   local cap
+   if max ~= nil then
+      cap = tonumber(max) - beg
+   end
+
+   return _gi_NewSlice(typeKind, a, props.zeroVal, base+beg, base+endx, cap)
 end
 
 function __gi_makeSlice(typeKind, zeroVal, len, cap)
    --print("__gi_makeSlice() called, typeKind=", typeKind, " zeroVal= ",zeroVal," len=", len, " cap=", cap)
-   raw = {}
-   cap = cap or len
+   len = tonumber(len)
+   cap = tonumber(cap) or len
+   local kind = __gi_goBackedKind(typeKind)
+   if kind ~= nil then
+      -- Go memory starts out zeroed.
+      local raw, goaddr = __gi_goAlloc(kind, cap)
+      return __gi_goBackedSlice(typeKind, raw, goaddr, cap, zeroVal, 0, len)
+   end
+   local raw = {}
    for i = 0, cap-1 do
       raw[i] = zeroVal
    end
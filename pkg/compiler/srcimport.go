@@ -0,0 +1,137 @@
+package compiler
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/gostd/build"
+	"github.com/gijit/gi/pkg/parser"
+	"github.com/gijit/gi/pkg/token"
+	"github.com/gijit/gi/pkg/types"
+)
+
+// ImportSourcePackage is the fallback for imports that
+// have no shadow package: we locate the package with
+// ic.BuildContext (build.Default if nil), parse its
+// .go files, and translate them to Lua through
+// IncrementallyCompile, just as if they had been
+// typed in after `:package`. The resulting Lua runs
+// right away, in its own package table, which other
+// packages reach by import path (see pkgTableVar), so
+// a/util and b/util don't clash.
+//
+// Results are kept in ic.pkgMap under the import path,
+// so each package is only translated once.
+func (ic *IncrState) ImportSourcePackage(path string) (arch *Archive, err error) {
+
+	bctx := ic.BuildContext
+	if bctx == nil {
+		bctx = &build.Default
+	}
+	bp, err := bctx.Import(path, ".", 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not find source for package '%s': %v", path, err)
+	}
+	if len(bp.CgoFiles) > 0 {
+		return nil, &ImportCError{path}
+	}
+	if len(bp.GoFiles) == 0 {
+		return nil, fmt.Errorf("package '%s' has no Go files in '%s'", path, bp.Dir)
+	}
+	pp("ImportSourcePackage: path='%s', dir='%s', files=%v", path, bp.Dir, bp.GoFiles)
+
+	pk := ic.newPkg(path)
+	pk.pack.Name = bp.Name
+	pk.pack.Dir = bp.Dir
+
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(pk.fileSet, filepath.Join(bp.Dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	// imports made by the package itself are
+	// resolved relative to it, so it must be the
+	// current package while we compile it.
+	prev := ic.CurPkg
+	ic.pkgMap[path] = pk
+	ic.CurPkg = pk
+	defer func() {
+		ic.CurPkg = prev
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+		if err != nil {
+			delete(ic.pkgMap, path)
+			arch = nil
+		}
+	}()
+
+	pk.Arch, err = IncrementallyCompile(nil, path, files, pk.fileSet, pk.importContext, ic.minify)
+	if err != nil {
+		return nil, err
+	}
+	pk.Arch.Pkg.SetName(bp.Name)
+	pk.Arch.Name = bp.Name
+
+	lua, m := ic.filterLua(pk.envPrefix(), pk.Arch.NewCodeText, pk.fileSet)
+	pk.Arch.NewCodeText = nil
+	m.nameFuncs(pk.fileSet, files)
+
+	err = LoadChunk(ic.vm, string(lua), m.Chunk)
+	if err == nil {
+		err = CallChunk(ic.vm, 0, 0)
+		if err != nil {
+			err = ic.MapLuaError(err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading translated package '%s': %v", path, err)
+	}
+	prev.importContext.Packages[path] = pk.Arch.Pkg
+	prev.importContext.PkgTables[path] = true
+	return pk.Arch, nil
+}
+
+// importLuaPackage imports package lua. Calls on it
+// are translated straight to Lua (see
+// translateLuaObjectCall), so its source is only type
+// checked, never run. The types are kept, so that
+// every package at the REPL sees the same lua.Object.
+func (ic *IncrState) importLuaPackage(path string) (*Archive, error) {
+	if ic.luaPkg == nil {
+		bctx := ic.BuildContext
+		if bctx == nil {
+			bctx = &build.Default
+		}
+		bp, err := bctx.Import(path, ".", 0)
+		if err != nil {
+			return nil, fmt.Errorf("could not find source for package '%s': %v", path, err)
+		}
+		fset := token.NewFileSet()
+		var files []*ast.File
+		for _, name := range bp.GoFiles {
+			f, err := parser.ParseFile(fset, filepath.Join(bp.Dir, name), nil, 0)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+		}
+		config := &types.Config{Sizes: sizes64}
+		pkg, _, err := config.Check(nil, nil, path, fset, files, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("package '%s' does not type check: %v", path, err)
+		}
+		ic.luaPkg = pkg
+	}
+	ic.CurPkg.importContext.Packages[path] = ic.luaPkg
+	return &Archive{
+		Name:       ic.luaPkg.Name(),
+		ImportPath: path,
+		Pkg:        ic.luaPkg,
+	}, nil
+}
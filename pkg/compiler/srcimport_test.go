@@ -0,0 +1,112 @@
+package compiler
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gijit/gi/pkg/gostd/build"
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test510ImportPackageFromSource(t *testing.T) {
+
+	cv.Convey(`a package with no shadow is translated from its Go source, along with what it imports, and only once`, t, func() {
+
+		gopath, err := filepath.Abs("testdata/srcimport")
+		panicOn(err)
+		bctx := build.Default
+		bctx.GOPATH = gopath
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		inc.BuildContext = &bctx
+
+		translation := inc.Tr([]byte(`
+import "example.com/greet"
+a := greet.Greet("gi")
+b := greet.Greet("go")
+n := greet.Calls()
+`))
+		pp("translation='%s'", string(translation))
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustString(vm, "a", "hello, gi!")
+		LuaMustString(vm, "b", "hello, go!")
+		LuaMustInt64(vm, "n", 2)
+
+		greetPkg := inc.pkgMap["example.com/greet"]
+		cv.So(greetPkg, cv.ShouldNotBeNil)
+		cv.So(inc.pkgMap["example.com/counter"], cv.ShouldNotBeNil)
+
+		// importing again re-uses the cached package, state and all.
+		translation = inc.Tr([]byte(`
+import "example.com/greet"
+n2 := greet.Calls()
+`))
+		LuaRunAndReport(vm, string(translation))
+		LuaMustInt64(vm, "n2", 2)
+		cv.So(inc.pkgMap["example.com/greet"], cv.ShouldEqual, greetPkg)
+	})
+}
+
+func Test511ImportMissingSourcePackage(t *testing.T) {
+
+	cv.Convey(`importing a package that is neither shadowed nor found as source is an error`, t, func() {
+
+		gopath, err := filepath.Abs("testdata/srcimport")
+		panicOn(err)
+		bctx := build.Default
+		bctx.GOPATH = gopath
+		bctx.GOROOT = gopath
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		inc.BuildContext = &bctx
+
+		_, err = inc.GiImportFunc("example.com/nosuch")
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldContainSubstring, "could not find source")
+		_, present := inc.pkgMap["example.com/nosuch"]
+		cv.So(present, cv.ShouldBeFalse)
+	})
+}
+
+func Test512SourcePackagesWithTheSameName(t *testing.T) {
+
+	cv.Convey(`two source packages with the same name, at different paths, each keep their own functions and types`, t, func() {
+
+		gopath, err := filepath.Abs("testdata/srcimport")
+		panicOn(err)
+		bctx := build.Default
+		bctx.GOPATH = gopath
+
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+
+		inc := NewIncrState(vm, nil)
+		inc.BuildContext = &bctx
+
+		translation := inc.Tr([]byte(`
+import autil "example.com/a/util"
+import butil "example.com/b/util"
+wa := autil.Which()
+wb := butil.Which()
+na := autil.Box{N: 3}.Get()
+sb := butil.Box{S: "x"}.Get()
+`))
+		pp("translation='%s'", string(translation))
+		LuaRunAndReport(vm, string(translation))
+
+		LuaMustString(vm, "wa", "a")
+		LuaMustString(vm, "wb", "b")
+		LuaMustInt64(vm, "na", 3)
+		LuaMustString(vm, "sb", "x")
+	})
+}
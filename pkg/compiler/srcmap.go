@@ -0,0 +1,356 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gijit/gi/pkg/ast"
+	"github.com/gijit/gi/pkg/token"
+	golua "github.com/glycerine/golua/lua"
+)
+
+// Each chunk of Lua that we translate gets a name,
+// __gijit_chunk_N, that LuaJIT puts into its error
+// messages and tracebacks as __gijit_chunk_N:line.
+// A LuaLineMap remembers which Go line each line
+// of the chunk came from, so that we can rewrite
+// those back into Go file:line form.
+type LuaLineMap struct {
+	Chunk string
+
+	// lines[i] is the Go position of Lua line i+1.
+	lines []token.Position
+
+	// funcs[i] names the Go function that Lua line
+	// i+1 belongs to, if any; see nameFuncs.
+	funcs []string
+
+	// src is the Go that was translated, when it
+	// was typed at the REPL rather than read from
+	// files.
+	src []byte
+}
+
+const luaChunkPrefix = "__gijit_chunk_"
+
+var luaChunkRegex = regexp.MustCompile(luaChunkPrefix + `(\d+):(\d+)`)
+
+// mapping is the SourceMapFilter callback; the
+// translator marks the start of each statement.
+func (m *LuaLineMap) mapping(generatedLine, generatedColumn int, originalPos token.Position) {
+	if !originalPos.IsValid() {
+		return
+	}
+	for len(m.lines) < generatedLine {
+		m.lines = append(m.lines, token.Position{})
+	}
+	if !m.lines[generatedLine-1].IsValid() {
+		m.lines[generatedLine-1] = originalPos
+	}
+}
+
+// GoPosition gives the Go position that Lua line
+// luaLine came from: that of the closest statement
+// starting on or before it.
+func (m *LuaLineMap) GoPosition(luaLine int) (pos token.Position, ok bool) {
+	if luaLine > len(m.lines) {
+		luaLine = len(m.lines)
+	}
+	for i := luaLine - 1; i >= 0; i-- {
+		if m.lines[i].IsValid() {
+			return m.lines[i], true
+		}
+	}
+	return
+}
+
+// nameFuncs records which Go function declared in
+// files each line of the chunk came from, named as
+// pprof would, less the package: f, T.M or (*T).M.
+// Function literals count as part of the function
+// around them.
+func (m *LuaLineMap) nameFuncs(fset *token.FileSet, files []*ast.File) {
+	type span struct {
+		beg, end token.Position
+		name     string
+	}
+	var spans []span
+	for _, f := range files {
+		for _, d := range f.Nodes {
+			fd, ok := d.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			spans = append(spans, span{fset.Position(fd.Pos()), fset.Position(fd.End()), funcDeclName(fd)})
+		}
+	}
+	m.funcs = make([]string, len(m.lines))
+	for i, pos := range m.lines {
+		for _, sp := range spans {
+			if pos.IsValid() && pos.Filename == sp.beg.Filename &&
+				pos.Offset >= sp.beg.Offset && pos.Offset < sp.end.Offset {
+				m.funcs[i] = sp.name
+				break
+			}
+		}
+	}
+}
+
+// funcDeclName gives f, T.M or (*T).M.
+func funcDeclName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name
+	}
+	switch t := fd.Recv.List[0].Type.(type) {
+	case *ast.StarExpr:
+		if id, ok := t.X.(*ast.Ident); ok {
+			return "(*" + id.Name + ")." + fd.Name.Name
+		}
+	case *ast.Ident:
+		return t.Name + "." + fd.Name.Name
+	}
+	return fd.Name.Name
+}
+
+// GoFrame gives the Go position of Lua line luaLine,
+// as GoPosition does; and the function it is in, if
+// known, and the text of the Go line, if kept.
+func (m *LuaLineMap) GoFrame(luaLine int) (pos token.Position, fn, text string, ok bool) {
+	pos, ok = m.GoPosition(luaLine)
+	if !ok {
+		return
+	}
+	if luaLine > len(m.lines) {
+		luaLine = len(m.lines)
+	}
+	for i := luaLine - 1; i >= 0 && i < len(m.funcs); i-- {
+		if m.lines[i].IsValid() {
+			fn = m.funcs[i]
+			break
+		}
+	}
+	if m.src != nil {
+		lines := strings.Split(string(m.src), "\n")
+		if pos.Line >= 1 && pos.Line <= len(lines) {
+			text = strings.TrimSpace(lines[pos.Line-1])
+		}
+	}
+	return
+}
+
+// filterLua strips the position markers from the
+// translated code, returning the Lua text and its
+// line map, registered under a new chunk name.
+func (ic *IncrState) filterLua(prefix string, code [][]byte, fset *token.FileSet) (lua []byte, m *LuaLineMap) {
+	m = &LuaLineMap{
+		Chunk: luaChunkPrefix + strconv.Itoa(len(ic.luaMaps)),
+	}
+	ic.luaMaps = append(ic.luaMaps, m)
+
+	var res bytes.Buffer
+	filter := &SourceMapFilter{
+		Writer:          &res,
+		MappingCallback: m.mapping,
+		fileSet:         fset,
+	}
+	filter.Write([]byte(prefix))
+	for _, d := range code {
+		filter.Write(d)
+	}
+	return res.Bytes(), m
+}
+
+// LastChunk names the Lua chunk made by the most
+// recent Tr; pass it to LoadChunk.
+func (ic *IncrState) LastChunk() string {
+	if len(ic.luaMaps) == 0 {
+		return ""
+	}
+	return ic.luaMaps[len(ic.luaMaps)-1].Chunk
+}
+
+// LoadChunk is vm.LoadString, but names the chunk,
+// so that errors raised while it runs can be
+// traced back to Go. On success, the loaded function
+// is left on top of the stack.
+func LoadChunk(vm *golua.State, lua, chunk string) error {
+	if chunk == "" {
+		if vm.LoadString(lua) != 0 {
+			msg := vm.ToString(-1)
+			vm.Pop(1)
+			return fmt.Errorf("%s", msg)
+		}
+		return nil
+	}
+	vm.GetGlobal("loadstring")
+	vm.PushString(lua)
+	vm.PushString("@" + chunk)
+	err := vm.Call(2, 2)
+	if err != nil {
+		vm.Pop(1)
+		return err
+	}
+	if vm.IsNil(-2) {
+		msg := vm.ToString(-1)
+		vm.Pop(2)
+		return fmt.Errorf("%s", msg)
+	}
+	vm.Pop(1)
+	return nil
+}
+
+// MapLuaText rewrites each __gijit_chunk_N:line in s
+// into the Go file:line it was translated from. Input
+// typed at the REPL has no file, and shows as repl.
+func (ic *IncrState) MapLuaText(s string) string {
+	return luaChunkRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		if pos, ok := ic.goPositionOf(ref); ok {
+			return pos
+		}
+		return ref
+	})
+}
+
+// goPositionOf turns __gijit_chunk_N:line into a Go
+// file:line.
+func (ic *IncrState) goPositionOf(ref string) (string, bool) {
+	sub := luaChunkRegex.FindStringSubmatch(ref)
+	if sub == nil {
+		return "", false
+	}
+	n, _ := strconv.Atoi(sub[1])
+	line, _ := strconv.Atoi(sub[2])
+	if n >= len(ic.luaMaps) {
+		return "", false
+	}
+	pos, ok := ic.luaMaps[n].GoPosition(line)
+	if !ok {
+		return "", false
+	}
+	return goPosString(pos), true
+}
+
+// goPosString writes pos as file:line. Input typed
+// at the REPL has no file, and shows as repl.
+func goPosString(pos token.Position) string {
+	file := pos.Filename
+	if file == "" {
+		file = "repl"
+	}
+	return fmt.Sprintf("%s:%d", file, pos.Line)
+}
+
+// LuaRunError is a Lua error raised by code run with
+// CallChunk, with the frames of the stack where it was
+// raised, innermost first.
+type LuaRunError struct {
+	Msg    string
+	Frames []golua.LuaStackEntry
+}
+
+func (e *LuaRunError) Error() string {
+	return e.Msg
+}
+
+// CallChunk is vm.Call(nargs, nres), for code loaded
+// by LoadChunk: but errors come back as *LuaRunError,
+// their frames noted by __gi_msgh while the stack is
+// still there, rather than after pcall has unwound it.
+// Unlike vm.Call, nothing is left on the stack on error.
+func CallChunk(vm *golua.State, nargs, nres int) error {
+	fn := vm.GetTop() - nargs
+	vm.GetGlobal("xpcall")
+	vm.Insert(fn)
+	vm.GetGlobal("__gi_msgh")
+	vm.Insert(fn + 2)
+	// room for ok and the handler's result, at least.
+	want := nres + 1
+	if want < 2 {
+		want = 2
+	}
+	err := vm.Call(nargs+2, want)
+	if err != nil {
+		vm.SetTop(fn - 1)
+		return err
+	}
+	if vm.ToBoolean(fn) {
+		vm.Remove(fn)
+		vm.SetTop(fn - 1 + nres)
+		return nil
+	}
+	defer vm.SetTop(fn - 1)
+	if !vm.IsTable(fn + 1) {
+		// the handler itself failed.
+		return &LuaRunError{Msg: vm.ToString(fn + 1)}
+	}
+	e := &LuaRunError{}
+	vm.GetField(fn+1, "msg")
+	e.Msg = vm.ToString(-1)
+	vm.Pop(1)
+	vm.GetField(fn+1, "frames")
+	for i := 1; ; i++ {
+		vm.RawGeti(-1, i)
+		if vm.IsNil(-1) {
+			vm.Pop(1)
+			break
+		}
+		var f golua.LuaStackEntry
+		vm.GetField(-1, "short_src")
+		f.ShortSource = vm.ToString(-1)
+		vm.GetField(-2, "currentline")
+		f.CurrentLine = vm.ToInteger(-1)
+		vm.GetField(-3, "name")
+		f.Name = vm.ToString(-1)
+		vm.Pop(4)
+		e.Frames = append(e.Frames, f)
+	}
+	return e
+}
+
+var luaPosPrefixRegex = regexp.MustCompile(`^[^\s:]+:\d+: `)
+
+// MapLuaError rewrites the message of a Lua error
+// into Go terms, and appends the Go frames of its
+// traceback. An error raised inside the prelude is
+// reported at the innermost Go line instead. Other
+// errors are returned unchanged.
+func (ic *IncrState) MapLuaError(err error) error {
+	var msg string
+	var stack []golua.LuaStackEntry
+	switch e := err.(type) {
+	case *LuaRunError:
+		msg, stack = e.Msg, e.Frames
+	case *golua.LuaError:
+		msg, stack = e.Error(), e.StackTrace()
+	default:
+		return err
+	}
+	var frames []string
+	for _, e := range stack {
+		if !strings.HasPrefix(e.ShortSource, luaChunkPrefix) {
+			continue
+		}
+		ref := fmt.Sprintf("%s:%d", e.ShortSource, e.CurrentLine)
+		pos, ok := ic.goPositionOf(ref)
+		if !ok {
+			continue
+		}
+		if len(frames) == 0 && !luaChunkRegex.MatchString(msg) {
+			if loc := luaPosPrefixRegex.FindString(msg); loc != "" {
+				msg = pos + ": " + msg[len(loc):]
+			}
+		}
+		if e.Name != "" {
+			pos += " (" + e.Name + ")"
+		}
+		frames = append(frames, "\t"+pos)
+	}
+	msg = ic.MapLuaText(msg)
+	if len(frames) > 0 {
+		msg += "\ngo traceback:\n" + strings.Join(frames, "\n")
+	}
+	return fmt.Errorf("%s", msg)
+}
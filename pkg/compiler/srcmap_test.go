@@ -0,0 +1,91 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cv "github.com/glycerine/goconvey/convey"
+)
+
+func Test550LuaRuntimeErrorsPointAtGoLines(t *testing.T) {
+
+	cv.Convey(`a runtime error in translated code is reported at the Go lines of the snippets that were typed, including a traceback through earlier snippets`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval(`
+a := 1
+func f(i int) int {
+  s := []int{1, 2}
+  v := s[i]
+  return v
+}
+`)
+		panicOn(err)
+
+		_, err = in.Eval("x := 0\nx = f(7)\n")
+		cv.So(err, cv.ShouldNotBeNil)
+		msg := err.Error()
+		cv.So(msg, cv.ShouldStartWith, "repl:5: index out of range\n")
+		cv.So(msg, cv.ShouldContainSubstring, "go traceback:\n\trepl:5 (f)\n\trepl:2")
+		cv.So(msg, cv.ShouldNotContainSubstring, luaChunkPrefix)
+	})
+}
+
+func Test551SourcedFilesMapToTheirOwnNames(t *testing.T) {
+
+	cv.Convey(`code read by :source is mapped back to the file and line it came from`, t, func() {
+
+		dir, err := ioutil.TempDir("", "gi-srcmap")
+		panicOn(err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "boom.go")
+		panicOn(ioutil.WriteFile(path, []byte(`package main
+
+func boom() {
+	var s []int
+	println(s[3])
+}
+`), 0644))
+
+		src, err := sourceGoFiles([]string{path})
+		panicOn(err)
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err = in.Eval(string(src))
+		panicOn(err)
+
+		_, err = in.Eval("\n\nboom()")
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldContainSubstring, "\t"+path+":5 (boom)\n\trepl:3")
+	})
+}
+
+func Test552EachRuntimeErrorHasItsOwnTraceback(t *testing.T) {
+
+	cv.Convey(`a second runtime error is reported with its own message and frames, not those of the first; an error raised in the prelude is reported at the Go line that called it, and a returned index keeps its function's frame`, t, func() {
+
+		in := newTestInterpreter()
+		defer in.Close()
+
+		_, err := in.Eval("func g(i int) int {\n  s := []int{1}\n  return s[i]\n}")
+		panicOn(err)
+
+		_, err = in.Eval("x := g(5)")
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldEqual, "repl:3: index out of range\ngo traceback:\n\trepl:3 (g)\n\trepl:1")
+
+		_, err = in.Eval("y := 0")
+		panicOn(err)
+
+		_, err = in.Eval("\nz := 3 / y")
+		cv.So(err, cv.ShouldNotBeNil)
+		cv.So(err.Error(), cv.ShouldEqual, "repl:2: integer divide by zero\ngo traceback:\n\trepl:2")
+	})
+}
@@ -66,8 +66,10 @@ func (c *funcContext) translateStmt(stmt ast.Stmt, label *types.Label) {
 
 		prevFlowData := c.flowDatas[nil]
 		data := &flowData{
-			postStmt:  prevFlowData.postStmt,  // for "continue" of outer loop
-			beginCase: prevFlowData.beginCase, // same
+			postStmt:      prevFlowData.postStmt,      // for "continue" of outer loop
+			beginCase:     prevFlowData.beginCase,     // same
+			continueLabel: prevFlowData.continueLabel, // same
+			breakLabel:    c.gensym("break"),
 		}
 		c.flowDatas[nil] = data
 		c.flowDatas[label] = data
@@ -87,19 +89,10 @@ func (c *funcContext) translateStmt(stmt ast.Stmt, label *types.Label) {
 			return
 		}
 
-		if label != nil || analysis.HasBreak(clause) {
-			if label != nil {
-				c.Printf("%s:", label.Name())
-			}
-			c.Printf("switch (0) { default:")
-			c.Indent(func() {
-				c.translateStmtList(clause.Body)
-			})
-			c.Printf("}")
-			return
-		}
-
 		c.translateStmtList(clause.Body)
+		if data.breakUsed {
+			c.Printf("::%s::", data.breakLabel)
+		}
 
 	case *ast.TypeSwitchStmt:
 		if s.Init != nil {
@@ -286,7 +279,6 @@ func (c *funcContext) translateStmt(stmt ast.Stmt, label *types.Label) {
 					},
 				},
 			}
-			c.Flattened[forStmt] = true
 			c.translateStmt(forStmt, label)
 
 		default:
@@ -304,12 +296,38 @@ func (c *funcContext) translateStmt(stmt ast.Stmt, label *types.Label) {
 		}
 		switch s.Tok {
 		case token.BREAK:
-			c.PrintCond(data.endCase == 0, fmt.Sprintf("break%s;", normalLabel), fmt.Sprintf("$s = %d; continue%s;", data.endCase, blockingLabel))
+			if data.endCase != 0 {
+				c.Printf("/* break%s; */ $s = %d; continue%s;", normalLabel, data.endCase, blockingLabel)
+				break
+			}
+			// jea: a Lua break leaves the innermost loop,
+			// so switches, selects and labeled breaks jump
+			// past their statement instead.
+			if data.isLoop && s.Label == nil {
+				c.Printf("break;")
+				break
+			}
+			data.breakUsed = true
+			c.Printf("goto %s;", data.breakLabel)
 		case token.CONTINUE:
-			data.postStmt()
-			c.PrintCond(data.beginCase == 0, fmt.Sprintf("continue%s;", normalLabel), fmt.Sprintf("$s = %d; continue%s;", data.beginCase, blockingLabel))
+			if data.beginCase != 0 {
+				data.postStmt()
+				c.Printf("/* continue%s; */ $s = %d; continue%s;", normalLabel, data.beginCase, blockingLabel)
+				break
+			}
+			// the loop runs its post statement after the label.
+			c.Printf("goto %s;", data.continueLabel)
 		case token.GOTO:
-			c.PrintCond(false, "goto "+s.Label.Name, fmt.Sprintf("$s = %d; continue;", c.labelCase(c.p.Uses[s.Label].(*types.Label))))
+			if label, ok := c.p.Uses[s.Label].(*types.Label); ok && label.Pos() < s.Pos() {
+				// a backward goto makes a loop, which an
+				// interrupt must be able to stop; as in
+				// translateLoopingStmt. The poll goes here
+				// and not after the label, lest it stop a
+				// forward goto from reaching a label that
+				// ends its block.
+				c.Printf("__gi_poll();")
+			}
+			c.Printf("goto %s;", luaLabelName(s.Label.Name))
 		case token.FALLTHROUGH:
 			// handled in CaseClause
 		default:
@@ -426,6 +444,9 @@ __defer_func(%s)
 			}
 			//fmt.Printf("about to translate assign...\n")
 			c.Printf("%s", c.translateAssign(lhs, s.Rhs[0], s.Tok == token.DEFINE))
+			if id, ok := lhs.(*ast.Ident); ok && id.Name == "__gijit_ans" {
+				c.translateAnsTypes(s.Rhs[0])
+			}
 
 		case len(s.Lhs) > 1 && len(s.Rhs) == 1:
 			/*	_tuple = <output of c.translateExpr(s.Rhs[0])>
@@ -516,6 +537,12 @@ __defer_func(%s)
 
 	case *ast.ExprStmt:
 		pp("calling c.translateExpr with s.X = '%#v'", s.X)
+		if un, ok := astutil.RemoveParens(s.X).(*ast.UnaryExpr); ok && un.Op == token.ARROW {
+			// Lua won't accept a parenthesized expression
+			// as a statement, so call __gi_recv directly.
+			c.Printf("__gi_recv(%s);", c.translateExpr(un.X, nil))
+			return
+		}
 		expr := c.translateExpr(s.X, nil)
 		if expr != nil && expr.String() != "" {
 			c.Printf("%s;", expr)
@@ -524,41 +551,62 @@ __defer_func(%s)
 	case *ast.LabeledStmt:
 		label := c.p.Defs[s.Label].(*types.Label)
 		if c.GotoLabel[label] {
-			c.PrintCond(false, s.Label.Name+":", fmt.Sprintf("case %d:", c.labelCase(label)))
+			c.Printf("::%s::", luaLabelName(s.Label.Name))
 		}
 		c.translateStmt(s.Stmt, label)
 
 	case *ast.GoStmt:
-		c.Printf("$go(%s, [%s]);", c.translateExpr(s.Call.Fun, nil), strings.Join(c.translateArgs(c.p.TypeOf(s.Call.Fun).Underlying().(*types.Signature), s.Call.Args, s.Call.Ellipsis.IsValid()), ", "))
+		// jea: like defer, evaluate the function value,
+		// or the method's receiver, and the arguments now,
+		// at the go statement, then hand a closure over
+		// those values to the goroutine scheduler in goroutines.lua.
+		var vars, args []string
+		fun := c.goStmtFun(s.Call.Fun, &vars, &args)
+		callArgs := make([]ast.Expr, len(s.Call.Args))
+		for i, arg := range s.Call.Args {
+			v := c.newVariable("_arg")
+			vars = append(vars, v)
+			args = append(args, c.translateExpr(arg, nil).String())
+			callArgs[i] = c.newIdent(v, c.p.TypeOf(arg))
+		}
+		call := c.translateExpr(&ast.CallExpr{
+			Fun:      fun,
+			Args:     callArgs,
+			Ellipsis: s.Call.Ellipsis,
+		}, nil)
+
+		goArgs := ""
+		if len(args) > 0 {
+			goArgs = ", " + strings.Join(args, ", ")
+		}
+		c.Printf("__go(function(%s) %s; end%s);", strings.Join(vars, ", "), call, goArgs)
 
 	case *ast.SendStmt:
 		chanType := c.p.TypeOf(s.Chan).Underlying().(*types.Chan)
-		call := &ast.CallExpr{
-			Fun:  c.newIdent("$send", types.NewSignature(nil, types.NewTuple(types.NewVar(0, nil, "", chanType), types.NewVar(0, nil, "", chanType.Elem())), nil, false)),
-			Args: []ast.Expr{s.Chan, c.newIdent(c.translateImplicitConversionWithCloning(s.Value, chanType.Elem()).String(), chanType.Elem())},
-		}
-		c.Blocking[call] = true
-		c.translateStmt(&ast.ExprStmt{X: call}, label)
+		c.Printf("__gi_send(%s, %s);", c.translateExpr(s.Chan, nil), c.translateImplicitConversionWithCloning(s.Value, chanType.Elem()))
 
 	case *ast.SelectStmt:
+		// jea: __gi_select in goroutines.lua takes a table
+		// of cases and returns the 0-based index of the
+		// chosen case, followed by the (value, ok) received
+		// if that was a receive case. We capture all three
+		// in a table: _selection[1] is the index,
+		// _selection[2] the value, and _selection[3] the ok.
 		selectionVar := c.newVariable("_selection")
-		var channels []string
+		var comms []string
 		var caseClauses []*ast.CaseClause
-		flattened := false
-		hasDefault := false
 		for i, cc := range s.Body.List {
 			clause := cc.(*ast.CommClause)
 			switch comm := clause.Comm.(type) {
 			case nil:
-				channels = append(channels, "[]")
-				hasDefault = true
+				comms = append(comms, `{"default"}`)
 			case *ast.ExprStmt:
-				channels = append(channels, c.formatExpr("[%e]", astutil.RemoveParens(comm.X).(*ast.UnaryExpr).X).String())
+				comms = append(comms, c.formatExpr(`{"recv", %e}`, astutil.RemoveParens(comm.X).(*ast.UnaryExpr).X).String())
 			case *ast.AssignStmt:
-				channels = append(channels, c.formatExpr("[%e]", astutil.RemoveParens(comm.Rhs[0]).(*ast.UnaryExpr).X).String())
+				comms = append(comms, c.formatExpr(`{"recv", %e}`, astutil.RemoveParens(comm.Rhs[0]).(*ast.UnaryExpr).X).String())
 			case *ast.SendStmt:
 				chanType := c.p.TypeOf(comm.Chan).Underlying().(*types.Chan)
-				channels = append(channels, c.formatExpr("[%e, %s]", comm.Chan, c.translateImplicitConversionWithCloning(comm.Value, chanType.Elem())).String())
+				comms = append(comms, c.formatExpr(`{"send", %e, %s}`, comm.Chan, c.translateImplicitConversionWithCloning(comm.Value, chanType.Elem())).String())
 			default:
 				panic(fmt.Sprintf("unhandled: %T", comm))
 			}
@@ -570,9 +618,9 @@ __defer_func(%s)
 			if assign, ok := clause.Comm.(*ast.AssignStmt); ok {
 				switch rhsType := c.p.TypeOf(assign.Rhs[0]).(type) {
 				case *types.Tuple:
-					bodyPrefix = []ast.Stmt{&ast.AssignStmt{Lhs: assign.Lhs, Rhs: []ast.Expr{c.newIdent(selectionVar+"[1]", rhsType)}, Tok: assign.Tok}}
+					bodyPrefix = []ast.Stmt{&ast.AssignStmt{Lhs: assign.Lhs, Rhs: []ast.Expr{c.newIdent(selectionVar+"[2], "+selectionVar+"[3]", rhsType)}, Tok: assign.Tok}}
 				default:
-					bodyPrefix = []ast.Stmt{&ast.AssignStmt{Lhs: assign.Lhs, Rhs: []ast.Expr{c.newIdent(selectionVar+"[1][0]", rhsType)}, Tok: assign.Tok}}
+					bodyPrefix = []ast.Stmt{&ast.AssignStmt{Lhs: assign.Lhs, Rhs: []ast.Expr{c.newIdent(selectionVar+"[2]", rhsType)}, Tok: assign.Tok}}
 				}
 			}
 
@@ -580,22 +628,15 @@ __defer_func(%s)
 				List: []ast.Expr{indexLit},
 				Body: append(bodyPrefix, clause.Body...),
 			})
-
-			flattened = flattened || c.Flattened[clause]
 		}
 
-		selectCall := c.setType(&ast.CallExpr{
-			Fun:  c.newIdent("$select", types.NewSignature(nil, types.NewTuple(types.NewVar(0, nil, "", types.NewInterface(nil, nil))), types.NewTuple(types.NewVar(0, nil, "", types.Typ[types.Int])), false)),
-			Args: []ast.Expr{c.newIdent(fmt.Sprintf("[%s]", strings.Join(channels, ", ")), types.NewInterface(nil, nil))},
-		}, types.Typ[types.Int])
-		c.Blocking[selectCall] = !hasDefault
-		c.Printf("%s = %s;", selectionVar, c.translateExpr(selectCall, nil))
+		c.Printf("%s = {__gi_select({%s})};", selectionVar, strings.Join(comms, ", "))
 
 		if len(caseClauses) != 0 {
 			translateCond := func(cond ast.Expr, desiredType types.Type) *expression {
-				return c.formatExpr("%s[0] === %e", selectionVar, cond)
+				return c.formatExpr("%s[1] == %e", selectionVar, cond)
 			}
-			c.translateBranchingStmt(caseClauses, nil, true, translateCond, label, flattened)
+			c.translateBranchingStmt(caseClauses, nil, true, translateCond, label, false)
 		}
 
 	case *ast.EmptyStmt:
@@ -619,34 +660,24 @@ func (c *funcContext) translateBranchingStmt(caseClauses []*ast.CaseClause, defa
 		c.caseCounter = endCase + 1
 	}
 
-	hasBreak := false
 	if canBreak {
 		prevFlowData := c.flowDatas[nil]
 		data := &flowData{
-			postStmt:  prevFlowData.postStmt,  // for "continue" of outer loop
-			beginCase: prevFlowData.beginCase, // same
-			endCase:   endCase,
+			postStmt:      prevFlowData.postStmt,      // for "continue" of outer loop
+			beginCase:     prevFlowData.beginCase,     // same
+			continueLabel: prevFlowData.continueLabel, // same
+			endCase:       endCase,
+			breakLabel:    c.gensym("break"),
 		}
 		c.flowDatas[nil] = data
 		c.flowDatas[label] = data
 		defer func() {
 			delete(c.flowDatas, label)
 			c.flowDatas[nil] = prevFlowData
-		}()
-
-		for _, child := range caseClauses {
-			if analysis.HasBreak(child) {
-				hasBreak = true
-				break
+			if data.breakUsed {
+				c.Printf("::%s::", data.breakLabel)
 			}
-		}
-		if defaultClause != nil && analysis.HasBreak(defaultClause) {
-			hasBreak = true
-		}
-	}
-
-	if label != nil && !flatten {
-		c.Printf("%s:", label.Name())
+		}()
 	}
 
 	condStrs := make([]string, len(caseClauses))
@@ -666,11 +697,6 @@ func (c *funcContext) translateBranchingStmt(caseClauses []*ast.CaseClause, defa
 	}
 
 	prefix := ""
-	suffix := ""
-	if label != nil || hasBreak {
-		prefix = "switch (0) { default: "
-		suffix = " }"
-	}
 
 	for i, clause := range caseClauses {
 		c.SetPos(clause.Pos())
@@ -692,18 +718,22 @@ func (c *funcContext) translateBranchingStmt(caseClauses []*ast.CaseClause, defa
 		})
 	}
 
-	c.PrintCond(!flatten, " end "+suffix, fmt.Sprintf("case %d:", endCase))
+	c.PrintCond(!flatten, " end ", fmt.Sprintf("case %d:", endCase))
 }
 
 func (c *funcContext) translateLoopingStmt(cond func() string, body *ast.BlockStmt, bodyPrefix, post func(), label *types.Label, flatten bool) {
 	prevFlowData := c.flowDatas[nil]
 	data := &flowData{
-		postStmt: post,
+		postStmt:   post,
+		isLoop:     true,
+		breakLabel: c.gensym("break"),
 	}
 	if flatten {
 		data.beginCase = c.caseCounter
 		data.endCase = c.caseCounter + 1
 		c.caseCounter += 2
+	} else if analysis.HasContinue(body, label, c.p.Info.Info) {
+		data.continueLabel = c.gensym("continue")
 	}
 	c.flowDatas[nil] = data
 	c.flowDatas[label] = data
@@ -712,11 +742,10 @@ func (c *funcContext) translateLoopingStmt(cond func() string, body *ast.BlockSt
 		c.flowDatas[nil] = prevFlowData
 	}()
 
-	if !flatten && label != nil {
-		c.Printf("%s:", label.Name())
-	}
 	c.PrintCond(!flatten, "while (true) do", fmt.Sprintf("case %d:", data.beginCase))
 	c.Indent(func() {
+		// so that an interrupt can stop the loop.
+		c.Printf("__gi_poll();")
 		condStr := cond()
 		if condStr != "true" {
 			c.PrintCond(!flatten, fmt.Sprintf("if (not (%s)) then break; end", condStr), fmt.Sprintf("if(not (%s)) then $s = %d; continue; end ", condStr, data.endCase))
@@ -725,24 +754,53 @@ func (c *funcContext) translateLoopingStmt(cond func() string, body *ast.BlockSt
 		prevEV := c.p.escapingVars
 		c.handleEscapingVars(body)
 
-		if bodyPrefix != nil {
-			bodyPrefix()
-		}
-		c.translateStmtList(body.List)
-		isTerminated := false
-		if len(body.List) != 0 {
-			switch body.List[len(body.List)-1].(type) {
-			case *ast.ReturnStmt, *ast.BranchStmt:
-				isTerminated = true
-			}
-		}
-		if !isTerminated {
+		c.translateLoopBody(data, body, bodyPrefix)
+		if !isTerminated(body) || data.continueLabel != "" {
 			post()
 		}
 
 		c.p.escapingVars = prevEV
 	})
 	c.PrintCond(!flatten, " end ", fmt.Sprintf("$s = %d; continue; case %d:", data.beginCase, data.endCase))
+	if data.breakUsed {
+		c.Printf("::%s::", data.breakLabel)
+	}
+}
+
+// translateLoopBody writes the body of a loop. If the
+// body has a continue, it goes in a do block, followed
+// by the label the continue jumps to: Lua will not
+// goto a label in the scope of a local declared after
+// the goto, and the do block closes those scopes.
+func (c *funcContext) translateLoopBody(data *flowData, body *ast.BlockStmt, bodyPrefix func()) {
+	if data.continueLabel == "" {
+		if bodyPrefix != nil {
+			bodyPrefix()
+		}
+		c.translateStmtList(body.List)
+		return
+	}
+	c.Printf("do")
+	c.Indent(func() {
+		if bodyPrefix != nil {
+			bodyPrefix()
+		}
+		c.translateStmtList(body.List)
+	})
+	c.Printf("end")
+	c.Printf("::%s::", data.continueLabel)
+}
+
+// isTerminated reports whether body ends by leaving
+// the loop iteration, so needs no post statement.
+func isTerminated(body *ast.BlockStmt) bool {
+	if len(body.List) != 0 {
+		switch body.List[len(body.List)-1].(type) {
+		case *ast.ReturnStmt, *ast.BranchStmt:
+			return true
+		}
+	}
+	return false
 }
 
 // jea: modified copy of the above translateLoopingStmt
@@ -750,12 +808,16 @@ func (c *funcContext) translateForRangeStmt(s *ast.RangeStmt, body *ast.BlockStm
 
 	prevFlowData := c.flowDatas[nil]
 	data := &flowData{
-		postStmt: post,
+		postStmt:   post,
+		isLoop:     true,
+		breakLabel: c.gensym("break"),
 	}
 	if flatten {
 		data.beginCase = c.caseCounter
 		data.endCase = c.caseCounter + 1
 		c.caseCounter += 2
+	} else if analysis.HasContinue(body, label, c.p.Info.Info) {
+		data.continueLabel = c.gensym("continue")
 	}
 	c.flowDatas[nil] = data
 	c.flowDatas[label] = data
@@ -764,33 +826,27 @@ func (c *funcContext) translateForRangeStmt(s *ast.RangeStmt, body *ast.BlockStm
 		c.flowDatas[nil] = prevFlowData
 	}()
 
-	if !flatten && label != nil {
-		c.Printf("%s:", label.Name())
-	}
 	key := nameHelper(s.Key)
 	value := nameHelper(s.Value)
 	c.Printf("for %s, %s in pairs(%s) do ", key, value, c.translateExpr(s.X, nil))
+	if m, isMap := c.p.TypeOf(s.X).Underlying().(*types.Map); isMap && key != "_" && types.IsInterface(m.Key()) {
+		// the nil interface key comes as __gi_nilMapKey; see map.lua.
+		c.Printf("if %s == __gi_nilMapKey then %s = nil end;", key, key)
+	}
 
 	prevEV := c.p.escapingVars
 	c.handleEscapingVars(body)
 
-	if bodyPrefix != nil {
-		bodyPrefix()
-	}
-	c.translateStmtList(body.List)
-	isTerminated := false
-	if len(body.List) != 0 {
-		switch body.List[len(body.List)-1].(type) {
-		case *ast.ReturnStmt, *ast.BranchStmt:
-			isTerminated = true
-		}
-	}
-	if !isTerminated && post != nil {
+	c.translateLoopBody(data, body, bodyPrefix)
+	if (!isTerminated(body) || data.continueLabel != "") && post != nil {
 		post()
 	}
 
 	c.p.escapingVars = prevEV
 	c.Printf(" end ")
+	if data.breakUsed {
+		c.Printf("::%s::", data.breakLabel)
+	}
 }
 
 // body helper
@@ -862,13 +918,9 @@ func (c *funcContext) translateAssign(lhs, rhs ast.Expr, define bool) string {
 			if typesutil.IsJsObject(c.p.TypeOf(l.Index)) {
 				c.p.errList = append(c.p.errList, types.Error{Fset: c.p.fileSet, Pos: l.Index.Pos(), Msg: "cannot use js.Object as map key"})
 			}
-			// jea: map assignment in lua:
-			dq := ``
-			switch t.Key().(type) {
-			case *types.Basic:
-				dq = `"`
-			}
-			return fmt.Sprintf(`%s[%s%s%s] = %s;`, c.translateExpr(l.X, nil), dq, c.translateImplicitConversionWithCloning(l.Index, t.Key()), dq, c.translateImplicitConversionWithCloning(rhs, t.Elem()))
+			// jea: map assignment in lua; the map's
+			// __newindex hashes the key. See map.lua.
+			return fmt.Sprintf(`%s[%s] = %s;`, c.translateExpr(l.X, nil), c.translateImplicitConversionWithCloning(l.Index, t.Key()), c.translateImplicitConversionWithCloning(rhs, t.Elem()))
 			// jea replace next 2 lines with the above
 			//keyVar := c.newVariable("_key")
 			//return fmt.Sprintf(`%s = %s; (%s || $throwRuntimeError("assignment to entry in nil map"))[%s.keyFor(%s)] = { k: %s, v: %s };`, keyVar, c.translateImplicitConversionWithCloning(l.Index, t.Key()), c.translateExpr(l.X), c.typeName(t.Key()), keyVar, keyVar, c.translateImplicitConversionWithCloning(rhs, t.Elem()))
@@ -995,6 +1047,14 @@ func (c *funcContext) translateResultsPreJoin(results []ast.Expr) []string {
 		}
 		v := c.translateImplicitConversion(result, tuple.At(0).Type())
 		c.delayedOutput = nil
+		switch astutil.RemoveParens(result).(type) {
+		case *ast.CallExpr, *ast.IndexExpr:
+			// returned bare, a call would be a Lua tail
+			// call, and this function's frame would be
+			// missing from the traceback of any error it
+			// raised; range checks are calls too.
+			return []string{"(" + v.String() + ")"}
+		}
 		return []string{v.String()}
 	default:
 		if len(results) == 1 {
@@ -1042,3 +1102,56 @@ func (c *funcContext) labelCase(label *types.Label) int {
 	}
 	return labelCase
 }
+
+// goStmtFun returns the callee of a go statement, fun,
+// rewritten to read a variable that it adds to vars,
+// with its value, evaluated now, in vals: the function
+// value itself, or for a method call the receiver, so
+// that later assignments don't change what the
+// goroutine calls. Functions and builtins named
+// directly are returned unchanged.
+func (c *funcContext) goStmtFun(fun ast.Expr, vars, vals *[]string) ast.Expr {
+	switch f := astutil.RemoveParens(fun).(type) {
+	case *ast.Ident:
+		switch c.p.Uses[f].(type) {
+		case *types.Func, *types.Builtin:
+			return fun
+		}
+	case *ast.SelectorExpr:
+		sel, ok := c.p.SelectionOf(f)
+		if !ok {
+			// a qualified pkg.Func.
+			return fun
+		}
+		if sel.Kind() == types.MethodVal {
+			xt := c.p.TypeOf(f.X)
+			recv := c.translateExpr(f.X, nil).String()
+			methodRecv := sel.Obj().Type().(*types.Signature).Recv().Type()
+			if _, ptrRecv := methodRecv.(*types.Pointer); !ptrRecv {
+				// a value receiver is copied now, too.
+				switch t := xt.Underlying().(type) {
+				case *types.Pointer:
+					if _, isStruct := t.Elem().Underlying().(*types.Struct); isStruct {
+						recv = c.formatExpr("__gi_clone2(%e, %s)", f.X, c.typeName(t.Elem())).String()
+					}
+				case *types.Struct, *types.Array:
+					recv = c.translateImplicitConversionWithCloning(f.X, xt).String()
+				}
+			}
+			v := c.newVariable("_recv")
+			*vars = append(*vars, v)
+			*vals = append(*vals, recv)
+			recvSel := &ast.SelectorExpr{X: c.newIdent(v, xt), Sel: f.Sel}
+			c.p.additionalSelections[recvSel] = sel
+			c.setType(recvSel, c.p.TypeOf(f))
+			return recvSel
+		}
+	}
+	if tv, ok := c.p.Types[fun]; ok && tv.IsType() {
+		return fun
+	}
+	v := c.newVariable("_fun")
+	*vars = append(*vars, v)
+	*vals = append(*vals, c.translateExpr(fun, nil).String())
+	return c.newIdent(v, c.p.TypeOf(fun))
+}
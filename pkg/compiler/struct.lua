@@ -452,6 +452,7 @@ function __reg:RemoveMethod(si, siName, methodName)
    end
    -- delete from methoset, decrease nMethod count.
    methodset[methodName] = nil
+   __gi_methodGen = __gi_methodGen + 1
    local props = methodset[__gi_PropsKey]
    props.__nMethod = props.__nMethod -1
 end
@@ -492,30 +493,44 @@ function __reg:AddMethod(si, siName, methodName, method, incCount)
    
    -- add the method
    methodset[methodName] = method
+   __gi_methodGen = __gi_methodGen + 1
 
    --print("after addition, methodset is:")
    --__st(methodset, "methodset")   
 end
 
+-- __gi_methodVal returns the method value recvr.methodName,
+-- bound to recvr. For a promoted method, recvr is already
+-- the embedded field that declares it, and recvrType the
+-- outer type, so recvr's own methods are tried first.
 function __gi_methodVal(recvr, methodName, recvrType)
    --print("__gi_methodVal with methodName ", methodName, " recvrType=", recvrType)
 
-   -- try structs, then interfaces.
-   
-   local methodset = __reg.structs[recvrType]
-   if methodset == nil then
-      methodset = __reg.interfaces[recvrType]
-   end
-   
-   if methodset == nil then
-      error("error in __gi_methodVal: unregistered receiver type '"..recvrType.."'")
+   local method = nil
+   if type(recvr) == "table" then
+      method = recvr[methodName]
    end
    
-   local method = methodset[methodName]
    if method == nil then
-      error("error in __gi_methodVal: method '"..methodName .."' not found for type '"..recvrType.."'")
+      -- try structs, then interfaces.
+      
+      local methodset = __reg.structs[recvrType]
+      if methodset == nil then
+         methodset = __reg.interfaces[recvrType]
+      end
+      
+      if methodset == nil then
+         error("error in __gi_methodVal: unregistered receiver type '"..recvrType.."'")
+      end
+      
+      method = methodset[methodName]
+      if method == nil then
+         error("error in __gi_methodVal: method '"..methodName .."' not found for type '"..recvrType.."'")
+      end
+   end
+   return function(...)
+      return method(recvr, ...)
    end
-   return method
 end
 
 -- __gi_count_methods
@@ -544,6 +559,190 @@ function __gi_count_methods(vi)
    return n
 end
 
+-- embedding.
+--
+-- A struct's methodset holds only the methods declared
+-- on it. Those promoted from its embedded fields are
+-- found when first looked up, by __gi_promotedMethod,
+-- and found again whenever the REPL has since added or
+-- removed methods, or declared types: __gi_methodGen
+-- counts those changes.
+
+__gi_methodGen = 0
+
+-- __gi_embeddedType returns the struct or interface type
+-- that an embedded field of type ftyp, T or *T, brings
+-- its fields and methods from; or nil.
+function __gi_embeddedType(ftyp)
+   if type(ftyp) ~= "table" then
+      return nil
+   end
+   if ftyp.__kind == __gi_kind_Ptr then
+      ftyp = ftyp.__elem
+      if type(ftyp) ~= "table" then
+         return nil
+      end
+   end
+   if ftyp.__kind == __gi_kind_Struct or ftyp.__kind == __gi_kind_Interface then
+      return ftyp
+   end
+   return nil
+end
+
+-- __gi_hasEmbedded tells if the struct type typ has
+-- embedded fields.
+function __gi_hasEmbedded(typ)
+   for _, f in ipairs(rawget(typ, "__fields") or {}) do
+      if f.__anonymous then
+         return true
+      end
+   end
+   return false
+end
+
+-- __gi_findPromoted looks for the method name among
+-- the embedded fields of the struct type typ, by Go's
+-- rules: the shallowest depth at which any field or
+-- method has that name decides, and there must be
+-- exactly one there, and a method. It returns the
+-- field props leading from a typ value to the
+-- receiver, and the receiver's type; or nil.
+function __gi_findPromoted(typ, name)
+   local level = {{typ = typ, path = {}}}
+   local seen = {[typ] = true}
+   local depth = 0
+   while #level > 0 do
+      local found = nil
+      local n = 0
+      local nextLevel = {}
+      for _, e in ipairs(level) do
+         local t = e.typ
+         if depth > 0 then
+            if t.__kind == __gi_kind_Interface then
+               for _, m in ipairs(rawget(t, "__methods_desc") or {}) do
+                  if m.__prop == name then
+                     found, n = e, n + 1
+                  end
+               end
+            else
+               local mset = rawget(t, __gi_MethodsetKey)
+               if mset ~= nil and type(rawget(mset, name)) == "function" then
+                  found, n = e, n + 1
+               end
+            end
+         end
+         if t.__kind == __gi_kind_Struct then
+            for _, f in ipairs(rawget(t, "__fields") or {}) do
+               if f.__prop == name then
+                  -- a field hides the method, typ's own
+                  -- fields included.
+                  found, n = nil, n + 1
+               end
+            end
+            for _, f in ipairs(rawget(t, "__fields") or {}) do
+               local et = f.__anonymous and __gi_embeddedType(f.__typ)
+               if et and not seen[et] then
+                  local path = {unpack(e.path)}
+                  path[#path + 1] = f.__prop
+                  nextLevel[#nextLevel + 1] = {typ = et, path = path}
+               end
+            end
+         end
+      end
+      if n > 0 then
+         if n == 1 and found ~= nil then
+            return found.path, found.typ
+         end
+         -- hidden by a field, or ambiguous.
+         return nil
+      end
+      -- the same type twice at one depth is ambiguous,
+      -- so only mark types seen once the depth is done.
+      for _, e in ipairs(nextLevel) do
+         seen[e.typ] = true
+      end
+      level = nextLevel
+      depth = depth + 1
+   end
+   return nil
+end
+
+-- __gi_promotedMethod returns the method name that the
+-- struct type typ has through its embedded fields, as
+-- a function of a typ value; or nil.
+function __gi_promotedMethod(typ, name)
+   if type(name) ~= "string" or string.sub(name, 1, 2) == "__" then
+      return nil
+   end
+   local cache = rawget(typ, "__promoted")
+   if cache == nil or cache.gen ~= __gi_methodGen then
+      cache = {gen = __gi_methodGen, methods = {}}
+      rawset(typ, "__promoted", cache)
+   end
+   local m = cache.methods[name]
+   if m == nil then
+      m = false
+      local path = __gi_findPromoted(typ, name)
+      if path ~= nil then
+         local n = #path
+         m = function(self, ...)
+            local recv = self
+            for i = 1, n do
+               recv = recv[path[i]]
+            end
+            return recv[name](recv, ...)
+         end
+      end
+      cache.methods[name] = m
+   end
+   return m or nil
+end
+
+-- __gi_allMethodsDesc returns own, the __methods_desc
+-- of the struct type typ, with those of the methods
+-- promoted from its embedded fields added.
+function __gi_allMethodsDesc(typ, own)
+   own = own or {}
+   if not __gi_hasEmbedded(typ) then
+      return own
+   end
+   local all = {}
+   local have = {}
+   for _, m in ipairs(own) do
+      all[#all + 1] = m
+      have[m.__prop] = true
+   end
+   -- breadth first, so the shallowest desc of a name
+   -- is the one promoted.
+   local queue = {typ}
+   local seen = {[typ] = true}
+   local i = 1
+   while i <= #queue do
+      local t = queue[i]
+      i = i + 1
+      if t ~= typ then
+         for _, m in ipairs(rawget(t, "__methods_desc") or {}) do
+            if not have[m.__prop] then
+               have[m.__prop] = true
+               if __gi_promotedMethod(typ, m.__prop) ~= nil then
+                  all[#all + 1] = m
+               end
+            end
+         end
+      end
+      if t.__kind == __gi_kind_Struct then
+         for _, f in ipairs(rawget(t, "__fields") or {}) do
+            local et = f.__anonymous and __gi_embeddedType(f.__typ)
+            if et and not seen[et] then
+               seen[et] = true
+               queue[#queue + 1] = et
+            end
+         end
+      end
+   end
+   return all
+end
+
 -- face.lua merged into struct.lua, because we need _reg.
 -- Thus the sequencing of these declarations is significant.
 
@@ -637,6 +836,10 @@ function __gi_assertType(value, typ, returnTuple)
       ok = true;
 
       local  valueMethodSet = value.__methods_desc
+      local valueTyp = value[__gi_PropsKey]
+      if valueTyp ~= nil and valueTyp.__kind == __gi_kind_Struct then
+         valueMethodSet = __gi_allMethodsDesc(valueTyp, valueMethodSet)
+      end
       
       --local  valueMethodSet = value[__gi_MethodsetKey]
       --print("valueMethodSet is")
@@ -1102,9 +1305,8 @@ function __gi_NewType(size, kind, shortPkg, shortTypeName, str, named, pkgPath,
       
 
    elseif kind == __gi_kind_Chan then
-      typ = function(self, v)
-         --self.__val = v;
-      end
+      -- channel values themselves come from __gi_NewChan
+      -- in goroutines.lua.
       typ.__wrapped = true;
       typ.__keyFor = __gi_idKey;
       typ.__init = function(elem, sendOnly, recvOnly)
@@ -1138,6 +1340,7 @@ function __gi_NewType(size, kind, shortPkg, shortTypeName, str, named, pkgPath,
       typ.__init = function(methods)
          --print("in __init function for interface, is typ == self? -> "..tostring((typ == self)))
          typ.__methods_desc = methods;
+         __gi_methodGen = __gi_methodGen + 1
          --__st(methods, "methods")
          for i,m in pairs(methods) do
             __gi_ifaceNil[m.__prop] = __gi_throwNilPointerError;
@@ -1203,9 +1406,12 @@ function __gi_NewType(size, kind, shortPkg, shortTypeName, str, named, pkgPath,
             --print("pointer mt.__call about to return __gi_createNewPointer(...)")
 
             -- try to detect if we're getting setter and getter...
+            -- but a pointer to a struct is made from its fields,
+            -- the first two of which may well be funcs.
             if #dots >= 2 and
                type(dots[1]) == "function" and
-            type(dots[2]) == "function" then
+               type(dots[2]) == "function" and
+            not (typ.__elem ~= nil and typ.__elem.__kind == __gi_kind_Struct) then
                
                --print("two functions passed to ptr mt.__call(), so returning __gi_createNewPointer")
                local newptr = __gi_createNewPointer(...)
@@ -1335,6 +1541,18 @@ function __gi_NewType(size, kind, shortPkg, shortTypeName, str, named, pkgPath,
       typ.__init = function(pkgPath, fields)
          typ.__pkg = pkgPath;
          typ.__fields = fields;
+         __gi_methodGen = __gi_methodGen + 1
+         if __gi_hasEmbedded(typ) then
+            -- typ is the metatable of its methodset, so
+            -- this is where method lookups end up.
+            typ.__index = function(t, k)
+               local v = rawget(typ, k)
+               if v ~= nil then
+                  return v
+               end
+               return __gi_promotedMethod(typ, k)
+            end
+         end
          for i,fld in ipairs(fields) do
 
             --print("jea debug, fld =")
@@ -1364,7 +1582,9 @@ function __gi_NewType(size, kind, shortPkg, shortTypeName, str, named, pkgPath,
                
                -- switch
                local knd = 0
-               if type(fld.__typ) == "cdata" then
+               if type(fld.__typ) ~= "table" then
+                  -- basic types are ffi ctypes; and an
+                  -- interface field has no type object.
                   knd = __gi_kind_cdata
                else
                   knd = fld.__typ.__kind
@@ -1783,6 +2003,29 @@ function __sliceType(elem)
 end
 
 
+--
+
+__chanTypes = {};
+
+function __chanType(elem, sendOnly, recvOnly)
+   local str
+   if recvOnly then
+      str = "<-chan " .. __type2str(elem)
+   elseif sendOnly then
+      str = "chan<- " .. __type2str(elem)
+   else
+      str = "chan " .. __type2str(elem)
+   end
+   local typ = __chanTypes[str]
+   if typ ~= nil then
+      return typ
+   end
+   typ = __gi_NewType(8, __gi_kind_Chan, "", str, str, false, "", false, nil);
+   __chanTypes[str] = typ;
+   typ.__init(elem, sendOnly, recvOnly);
+   return typ;
+end
+
 --
 
 __equal = function(a, b, typ)
@@ -1879,3 +2122,27 @@ function __arrayType(elem, len, shortPkg, pkgPath)
    return typ;
 end
 
+
+-- __gi_NativeStruct declares, in types, the __type__ table
+-- of a package shadowed by native Go code, a type for its
+-- Go struct name, so that the REPL can write literals of
+-- it. props are the field names, in order. The values are
+-- plain tables; luar copies them into the Go struct when
+-- they are passed to Go.
+function __gi_NativeStruct(types, pkgName, pkgPath, name, props)
+   local typ = __gi_NewType(8, __gi_kind_Struct, pkgName, name, pkgName.."."..name, true, pkgPath, true, nil)
+   local fields = {}
+   for i, prop in ipairs(props) do
+      fields[i] = {__prop = prop, __name = prop, __anonymous = false, __exported = true}
+   end
+   typ.__init(pkgPath, fields)
+   typ.__constructor = function(self, ...)
+      if self == nil then self = {} end
+      local args = {...}
+      for i, prop in ipairs(props) do
+         self[prop] = args[i]
+      end
+      return self
+   end
+   types[name] = typ
+end
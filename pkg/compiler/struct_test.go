@@ -67,7 +67,7 @@ __type__Ragdoll = __gi_NewType(0, __gi_kind_Struct, "main", "Ragdoll", "main.Rag
 
 anon_ptrType = __ptrType(__type__Ragdoll); -- 'DELAYED' anon type printing.
 
-__type__Ragdoll.__init("", {{__prop= "Andy", __name= "Andy", __anonymous= false, __exported= true, __typ= anon_ptrType, __tag= ""}});
+__type__Ragdoll.__init("", {{__prop= "Andy", __name= "Andy", __anonymous= false, __exported= true, __typ= anon_ptrType, __tag= "", __typstr= "*main.Ragdoll"}});
 
 __type__Ragdoll.__constructor = function(self, ...) 
 		 if self == nil then self = {}; end
@@ -118,7 +118,7 @@ __type__Ragdoll = __gi_NewType(0, __gi_kind_Struct, "main", "Ragdoll", "main.Rag
 
 anon_ptrType = __ptrType(__type__Ragdoll); -- 'DELAYED' anon type printing.
 
-__type__Ragdoll.__init("", {{__prop= "Andy", __name= "Andy", __anonymous= false, __exported= true, __typ= anon_ptrType, __tag= ""}});
+__type__Ragdoll.__init("", {{__prop= "Andy", __name= "Andy", __anonymous= false, __exported= true, __typ= anon_ptrType, __tag= "", __typstr= "*main.Ragdoll"}});
 
 __type__Ragdoll.__constructor = function(self, ...) 
 		 if self == nil then self = {}; end
@@ -224,3 +224,80 @@ func Test123PointersInsideStructStartsNil(t *testing.T) {
 		cv.So(true, cv.ShouldBeTrue)
 	})
 }
+
+func Test124EmbeddedStructsPromoteFieldsAndMethods(t *testing.T) {
+
+	cv.Convey(`the fields and methods of embedded structs, and of pointers to them, are promoted through any number of levels; copies of the outer struct copy the embedded one; and method values bind their receiver`, t, func() {
+
+		code := `
+type A struct{ X int }
+func (a A) Get() int { return a.X }
+func (a *A) Inc() { a.X++ }
+
+type B struct {
+	A
+	Y int
+}
+type C struct {
+	*A
+	Z int
+}
+type D struct {
+	B
+}
+
+a := &A{3}
+c := C{a, 4}
+cx := c.X
+c.X = 7
+ax := a.X
+c.Inc()
+ax2 := a.X
+
+d := D{B{A{10}, 1}}
+dg := d.Get()
+d.Inc()
+dx := d.X
+dy := d.Y
+
+b := B{A{5}, 0}
+b2 := b
+b2.X = 9
+bx := b.X
+b2x := b2.X
+
+f := b.Get
+b.X = 6
+fb := f()
+g := d.Inc
+g()
+dx2 := d.X
+`
+		vm, err := NewLuaVmWithPrelude(nil)
+		panicOn(err)
+		defer vm.Close()
+		inc := NewIncrState(vm, nil)
+
+		translation := inc.Tr([]byte(code))
+		fmt.Printf("\n translation='%s'\n", translation)
+
+		LuaRunAndReport(vm, string(translation))
+
+		// through a pointer: c shares a's fields.
+		LuaMustInt64(vm, "cx", 3)
+		LuaMustInt64(vm, "ax", 7)
+		LuaMustInt64(vm, "ax2", 8)
+
+		// two levels down.
+		LuaMustInt64(vm, "dg", 10)
+		LuaMustInt64(vm, "dx", 11)
+		LuaMustInt64(vm, "dy", 1)
+
+		LuaMustInt64(vm, "bx", 5)
+		LuaMustInt64(vm, "b2x", 9)
+
+		// f copied b.A when it was made.
+		LuaMustInt64(vm, "fb", 5)
+		LuaMustInt64(vm, "dx2", 12)
+	})
+}
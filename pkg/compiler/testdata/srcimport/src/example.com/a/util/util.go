@@ -0,0 +1,7 @@
+package util
+
+type Box struct{ N int }
+
+func (b Box) Get() int { return b.N }
+
+func Which() string { return "a" }
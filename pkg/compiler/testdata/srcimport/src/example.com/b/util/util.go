@@ -0,0 +1,7 @@
+package util
+
+type Box struct{ S string }
+
+func (b Box) Get() string { return b.S }
+
+func Which() string { return "b" }
@@ -0,0 +1,10 @@
+package counter
+
+type Counter struct {
+	N int
+}
+
+func (c *Counter) Inc() int {
+	c.N++
+	return c.N
+}
@@ -0,0 +1,5 @@
+package greet
+
+func exclaim(s string) string {
+	return s + "!"
+}
@@ -0,0 +1,16 @@
+package greet
+
+import "example.com/counter"
+
+const Hello = "hello, "
+
+var calls counter.Counter
+
+func Greet(name string) string {
+	calls.Inc()
+	return Hello + exclaim(name)
+}
+
+func Calls() int {
+	return calls.N
+}
@@ -39,15 +39,32 @@ func NewIncrState(vm *luajit.State, vmCfg *VmConfig) *IncrState {
 		vm:     vm,
 		vmCfg:  vmCfg,
 	}
+
+	key := "main"
+	pk := ic.newPkg(key)
+
+	ic.pkgMap[key] = pk
+	ic.CurPkg = pk
+
+	ic.EnableImportsFromLua() // from Lua, use __gi_import("fmt");
+
+	return ic
+}
+
+// newPkg makes a new, empty, incrementally built
+// package. Each one gets its own type checker
+// and Archive, once something is compiled into it.
+func (ic *IncrState) newPkg(key string) *IncrPkg {
 	pack := &build.Package{
-		Name:       "main",
-		ImportPath: "main",
+		Name:       key,
+		ImportPath: key,
 		Dir:        ".",
 	}
 	fileSet := token.NewFileSet() // positions are relative to fileSet
 	importContext := &ImportContext{
-		Packages: make(map[string]*types.Package),
-		Import:   ic.GiImportFunc,
+		Packages:  make(map[string]*types.Package),
+		Import:    ic.GiImportFunc,
+		PkgTables: make(map[string]bool),
 		// from GopherJS:
 		/*
 			Import: func(path string) (*Archive, error) {
@@ -58,16 +75,60 @@ func NewIncrState(vm *luajit.State, vmCfg *VmConfig) *IncrState {
 			},
 		*/
 	}
+	return newIncrPkg(key, pack, fileSet, importContext, nil)
+}
 
-	key := "main"
-	pk := newIncrPkg(key, pack, fileSet, importContext, nil)
-
-	ic.pkgMap[key] = pk
+// SwitchPackage makes key the current package,
+// creating it first if need be. This is what
+// `:package foo` at the REPL does. Code typed
+// afterwards is compiled into foo, and the exported
+// parts of foo can be used from main after
+// `import "foo"`.
+func (ic *IncrState) SwitchPackage(key string) (created bool, err error) {
+	if !isPackageName(key) {
+		return false, fmt.Errorf("bad package name '%s'", key)
+	}
+	pk, ok := ic.pkgMap[key]
+	if !ok {
+		pk = ic.newPkg(key)
+		ic.pkgMap[key] = pk
+		created = true
+	}
 	ic.CurPkg = pk
+	return
+}
 
-	ic.EnableImportsFromLua() // from Lua, use __gi_import("fmt");
+// isPackageName reports whether key is usable as
+// the name of a package typed in at the REPL.
+func isPackageName(key string) bool {
+	if key == "" || key == "_" || reservedPackageNames[key] || strings.HasPrefix(key, "__") {
+		return false
+	}
+	for i, r := range key {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return !token.Lookup(key).IsKeyword()
+}
 
-	return ic
+// reservedPackageNames are the Lua libraries that the
+// runtime uses; a package of the same name at the REPL
+// would hide them from `import`. Names starting with
+// "__" belong to the runtime, too.
+var reservedPackageNames = map[string]bool{
+	"_G":        true,
+	"bit":       true,
+	"coroutine": true,
+	"debug":     true,
+	"ffi":       true,
+	"io":        true,
+	"jit":       true,
+	"math":      true,
+	"os":        true,
+	"package":   true,
+	"string":    true,
+	"table":     true,
 }
 
 // an incrementally built package,
@@ -80,6 +141,9 @@ type IncrPkg struct {
 	fileSet       *token.FileSet
 	importContext *ImportContext
 	Arch          *Archive
+
+	// for :export; see export.go
+	session *replSession
 }
 
 func newIncrPkg(key string,
@@ -96,7 +160,40 @@ func newIncrPkg(key string,
 		fileSet:       fileSet,
 		importContext: importContext,
 		Arch:          archive,
+		session:       newReplSession(),
+	}
+}
+
+// envPrefix is the Lua that starts off every chunk
+// translated for pk, so that its top-level names land
+// in its package table. main uses the globals.
+func (pk *IncrPkg) envPrefix() string {
+	if pk.key == "main" {
+		return ""
+	}
+	// see __gi_PackageEnv in prelude.lua
+	return fmt.Sprintf("setfenv(1, __gi_PackageEnv(%q, %q));\n", pk.key, pkgTableVar(pk.key))
+}
+
+// pkgTableVar is the Lua global that holds the table
+// of the package at path, typed in at the REPL or
+// imported from source, as code in other packages
+// sees it. It is spelled out from the whole path, so
+// it can't be the name of a Lua library, or of a
+// global in main.
+func pkgTableVar(path string) string {
+	var b bytes.Buffer
+	b.WriteString("__gi_pkg_")
+	for i := 0; i < len(path); i++ {
+		ch := path[i]
+		switch {
+		case 'a' <= ch && ch <= 'z', 'A' <= ch && ch <= 'Z', '0' <= ch && ch <= '9':
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "_%02x", ch)
+		}
 	}
+	return b.String()
 }
 
 type UniqPkgPath string
@@ -115,6 +212,18 @@ type IncrState struct {
 
 	vmCfg *VmConfig
 
+	// BuildContext locates the source of packages
+	// that have no shadow; see ImportSourcePackage.
+	// nil means build.Default.
+	BuildContext *build.Context
+
+	// one per translated chunk of Lua; see srcmap.go.
+	luaMaps []*LuaLineMap
+
+	// the types of package lua, once imported;
+	// see importLuaPackage.
+	luaPkg *types.Package
+
 	minify   bool
 	PrintAST bool
 }
@@ -125,7 +234,9 @@ func (tr *IncrState) Tr(src []byte) []byte {
 
 	// detect the leading '=' and turn it into
 	// __gijit_ans :=
+	orig := src
 	src = prependAns(src)
+	tr.CurPkg.session.staged = nil
 
 	pp("after prependAns, src = '%s'", src)
 
@@ -133,8 +244,8 @@ func (tr *IncrState) Tr(src []byte) []byte {
 	file, err := parser.ParseFile(tr.CurPkg.fileSet, "", src, 0)
 	if err != nil {
 		pp("we got an error on the ParseFile: '%v'", err)
+		panic(tr.compileError(orig, src, err))
 	}
-	panicOn(err)
 	pp("we got past the ParseFile !")
 
 	// Print the AST.
@@ -158,22 +269,37 @@ func (tr *IncrState) Tr(src []byte) []byte {
 		return nil
 	}
 
-	tr.CurPkg.Arch, err = IncrementallyCompile(tr.CurPkg.Arch, tr.CurPkg.pack.ImportPath, files, tr.CurPkg.fileSet, tr.CurPkg.importContext, tr.minify)
-	panicOn(err)
+	arch, err := IncrementallyCompile(tr.CurPkg.Arch, tr.CurPkg.pack.ImportPath, files, tr.CurPkg.fileSet, tr.CurPkg.importContext, tr.minify)
+	if err != nil {
+		panic(tr.compileError(orig, src, err))
+	}
+	tr.CurPkg.Arch = arch
+	tr.CurPkg.session.stage(tr.CurPkg.fileSet, src, file, arch.TypesInfo, arch.Pkg)
 	//pp("archive = '%#v'", tr.CurPkg.Arch)
 	//pp("len(tr.CurPkg.Arch.Declarations)= '%v'", len(tr.CurPkg.Arch.Declarations))
 	//pp("len(tr.CurPkg.Arch.NewCode)= '%v'", len(tr.CurPkg.Arch.NewCodeText))
 
 	pp("got past config.Check")
 
-	var res bytes.Buffer
-	for i, d := range tr.CurPkg.Arch.NewCodeText {
-		pp("writing tr.CurPkg.Arch.NewCode[i=%v].Code = '%v'", i, string(tr.CurPkg.Arch.NewCodeText[i]))
-		res.Write(d)
-	}
+	lua, m := tr.filterLua(tr.CurPkg.envPrefix(), tr.CurPkg.Arch.NewCodeText, tr.CurPkg.fileSet)
 	tr.CurPkg.Arch.NewCodeText = nil
+	m.nameFuncs(tr.CurPkg.fileSet, files)
+	m.src = src
+
+	return lua
+}
 
-	return res.Bytes()
+// compileError wraps err, from translating src, for
+// the user who typed orig.
+func (tr *IncrState) compileError(orig, src []byte, err error) *CompileError {
+	ce := newCompileError(orig, tr.CurPkg.fileSet, err)
+
+	// prependAns rewrote the start of the first line.
+	if !bytes.Equal(orig, src) {
+		lead := len(orig) - len(bytes.TrimLeftFunc(orig, unicode.IsSpace))
+		ce.colShift = len(gijitAnsPrefix) - (lead + 1)
+	}
+	return ce
 }
 
 type ImportCError struct {
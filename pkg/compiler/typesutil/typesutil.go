@@ -36,3 +36,16 @@ func IsLuarObject(t types.Type) bool {
 	}
 	return isNamed && IsLuarPackage(named.Obj().Pkg())
 }
+
+func IsLuaPackage(pkg *types.Package) bool {
+	return pkg != nil && pkg.Path() == "github.com/gijit/gi/lua"
+}
+
+func IsLuaObject(t types.Type) bool {
+	ptr, isPtr := t.(*types.Pointer)
+	if !isPtr {
+		return false
+	}
+	named, isNamed := ptr.Elem().(*types.Named)
+	return isNamed && IsLuaPackage(named.Obj().Pkg()) && named.Obj().Name() == "Object"
+}
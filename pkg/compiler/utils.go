@@ -51,8 +51,6 @@ func (c *funcContext) SetPos(pos token.Pos) {
 }
 
 func (c *funcContext) writePos() {
-	// jea debug: turn off writePos() for now
-	return
 	if c.posAvailable {
 		c.posAvailable = false
 		c.Write([]byte{'\b'})
@@ -274,8 +272,8 @@ func (c *funcContext) newVariableWithLevel(name string, pkgLevel bool) string {
 	if name == "" {
 		panic("newVariable: empty name")
 	}
-	name = encodeIdent(name)
-	pp("newVariableWithLevel begins, after encodeIdent, name='%s'", name)
+	name = luaIdent(name)
+	pp("newVariableWithLevel begins, after luaIdent, name='%s'", name)
 
 	if c.p.minify {
 		i := 0
@@ -565,12 +563,25 @@ func fieldName(t *types.Struct, i int) string {
 	return name
 }
 
+// luaLabelName gives the Lua name for a Go label, which
+// may be a Lua keyword, such as end or then.
+func luaLabelName(name string) string {
+	if reservedKeywords[name] {
+		return name + "_"
+	}
+	return name
+}
+
 func typeKind(ty types.Type) (res string) {
 	defer func() {
 		pp("typeKind called on ty='%#v', returning res='%s'", ty, res)
 	}()
 	switch t := ty.Underlying().(type) {
 	case *types.Basic:
+		if isString(t) {
+			// struct.lua capitalizes this one.
+			return "__gi_kind_String"
+		}
 		return "__gi_kind_" + toJavaScriptType(t)
 	case *types.Array:
 		return "__gi_kind_Array"
@@ -615,6 +626,34 @@ func is64Bit(t *types.Basic) bool {
 	return t.Kind() == types.Int64 || t.Kind() == types.Uint64
 }
 
+// intWidth gives the size in bits of an integer
+// type; int, uint and uintptr are 64 bits wide.
+func intWidth(t *types.Basic) int {
+	switch t.Kind() {
+	case types.Int8, types.Uint8:
+		return 8
+	case types.Int16, types.Uint16:
+		return 16
+	case types.Int32, types.Uint32:
+		return 32
+	}
+	return 64
+}
+
+// intWrapName names the int64.lua function,
+// __gi_int8 through __gi_uint64, that converts
+// a value to the integer type t.
+func intWrapName(t *types.Basic) string {
+	switch t.Kind() {
+	case types.Int, types.Int64:
+		return "int64"
+	case types.Uint, types.Uint64, types.Uintptr:
+		return "uint64"
+	}
+	// types.Typ, so that byte and rune give uint8 and int32.
+	return types.Typ[t.Kind()].Name()
+}
+
 func isBoolean(t *types.Basic) bool {
 	return t.Info()&types.IsBoolean != 0
 }
@@ -858,6 +897,17 @@ func encodeIdent(name string) string {
 	return strings.Replace(url.QueryEscape(name), "%", "$", -1)
 }
 
+// luaIdent gives the Lua variable for the Go
+// identifier name. A Lua keyword, such as end or
+// local, gets an underscore, as labels and methods do.
+func luaIdent(name string) string {
+	name = encodeIdent(name)
+	if luaKeywords[name] {
+		name += "_"
+	}
+	return name
+}
+
 func stripOuterParen(s string) (r string) {
 	r = strings.TrimSpace(s)
 	n := len(r)
@@ -22,7 +22,10 @@ var pp = verb.PP
 // that we can wrap Go slices/arrays with Lua
 // proxies from the very start of their creation.
 
-type Muse struct{}
+type Muse struct {
+	// the named types being punned, to catch recursion.
+	punning map[*types.Named]bool
+}
 
 func NewMuse() *Muse { return &Muse{} }
 
@@ -99,9 +102,15 @@ func (m *Muse) Pun(tt types.Type) (rt reflect.Type, err error) {
 			// id := f.Id() //string; Id(obj.pkg, obj.name)
 
 			tag := x.Tag(i) // string
+			pkgPath := ""
+			if !f.Exported() {
+				// reflect wants a PkgPath for exactly
+				// the unexported fields.
+				pkgPath = pkg.Path()
+			}
 			fields[i] = reflect.StructField{
 				Name:    name,
-				PkgPath: pkg.Path(),
+				PkgPath: pkgPath,
 				Type:    rftyp,
 				Tag:     reflect.StructTag(tag),
 
@@ -177,8 +186,20 @@ func (m *Muse) Pun(tt types.Type) (rt reflect.Type, err error) {
 
 		*/
 
-		panic("not done: *types.Named")
+		// reflect cannot make named types: a struct
+		// type Tree becomes struct{...}, and so on. Nor
+		// recursive ones, such as a Tree with *Tree fields.
+		if m.punning[x] {
+			return nil, fmt.Errorf("cannot pun recursive type '%v'", nm)
+		}
+		if m.punning == nil {
+			m.punning = make(map[*types.Named]bool)
+		}
+		m.punning[x] = true
+		defer delete(m.punning, x)
+		return m.Pun(under)
 	case *types.Interface:
+		return reflect.TypeOf((*interface{})(nil)).Elem(), nil
 	default:
 		panic(fmt.Sprintf("unknown types.Type '%T'", tt))
 	}
@@ -2468,6 +2468,7 @@ func (p *parser) parseDeclOrNode(sync func(*parser)) ast.Node {
 		token.GO,
 		token.GOTO,
 		token.SELECT,
+		token.ARROW,
 		token.MUL:
 		return p.parseStmt()
 	}
@@ -2536,6 +2537,9 @@ func (p *parser) parseFile() *ast.File {
 
 	p.openScope()
 	p.pkgScope = p.topScope
+	// jea: statements at the top level may be labeled,
+	// so the file gets a label scope, as a func body does.
+	p.openLabelScope()
 	// change from []ast.Decl to []ast.Node
 	var nodes []ast.Node
 	if p.mode&PackageClauseOnly == 0 {
@@ -2551,6 +2555,7 @@ func (p *parser) parseFile() *ast.File {
 			}
 		}
 	}
+	p.closeLabelScope()
 	p.closeScope()
 	assert(p.topScope == nil, "unbalanced scopes")
 	assert(p.labelScope == nil, "unbalanced label scopes")
@@ -34,7 +34,7 @@ import (
 // level untyped constants will return an untyped type rather then the
 // respective context-specific type.
 //
-func Eval(fset *token.FileSet, pkg *Package, pos token.Pos, expr string) (TypeAndValue, error) {
+func Eval(fset *token.FileSet, pkg *Package, pos token.Pos, expr string) (_ TypeAndValue, err error) {
 	// determine scope
 	var scope *Scope
 	if pkg == nil {
@@ -65,6 +65,8 @@ func Eval(fset *token.FileSet, pkg *Package, pos token.Pos, expr string) (TypeAn
 	}
 
 	// parse expressions
+	// err is named, so that handleBailout can report
+	// the first error of the check.
 	node, err := parser.ParseExprFrom(fset, "eval", expr, 0)
 	if err != nil {
 		return TypeAndValue{}, err
@@ -530,6 +530,14 @@ func (check *Checker) collectObjects() {
 				check.invalidAST(d.Pos(), "unknown ast.Node node %T", d)
 			}
 		}
+
+		// jea: labels on top level statements are checked
+		// as those in a function body are, with the
+		// statements of the file as the body.
+		if check.hasLabel {
+			check.hasLabel = false
+			check.labels(topLevelStmts(file))
+		}
 	}
 
 	// jea, don't think we want this at the repl
@@ -553,6 +561,18 @@ func (check *Checker) collectObjects() {
 	*/
 }
 
+// topLevelStmts gathers the statements among the
+// nodes of file into a block.
+func topLevelStmts(file *ast.File) *ast.BlockStmt {
+	body := &ast.BlockStmt{Lbrace: file.Pos(), Rbrace: file.End()}
+	for _, n := range file.Nodes {
+		if s, ok := n.(ast.Stmt); ok {
+			body.List = append(body.List, s)
+		}
+	}
+	return body
+}
+
 // packageObjects typechecks all package objects in objList, but not function bodies.
 func (check *Checker) packageObjects(objList []Object) {
 	// add new methods to already type-checked types (from a prior Checker.Files call)
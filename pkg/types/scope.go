@@ -55,7 +55,8 @@ func NewScope(parent *Scope, pos, end token.Pos, comment, methodName string) (sc
 	}
 	pp("NewScope() is returning %p with parent %p. comment '%s'", s, parent, comment)
 	if Universe != nil {
-		if parent == nil {
+		// the label scope, made in labels.go, stands alone.
+		if parent == nil && comment != "label" {
 			panic("where is nil scope parent coming from???? bad!")
 		}
 	}
@@ -440,6 +440,13 @@ func (t *Named) AddMethod(m *Func) {
 	}
 }
 
+// SetMethods replaces the method list of t with a copy
+// of methods. The REPL uses it to take back the methods
+// declared by a chunk that failed to type check.
+func (t *Named) SetMethods(methods []*Func) {
+	t.methods = append([]*Func(nil), methods...)
+}
+
 // Implementations for Type methods.
 
 func (t *Basic) Underlying() Type     { return t }